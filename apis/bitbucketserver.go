@@ -21,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	projectv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/project/v1alpha1"
+	projectpermissionv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/projectpermission/v1alpha1"
 	repositoryv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
 	bitbucketserverv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
 )
@@ -30,6 +31,7 @@ func init() {
 	AddToSchemes = append(AddToSchemes,
 		bitbucketserverv1alpha1.SchemeBuilder.AddToScheme,
 		projectv1alpha1.SchemeBuilder.AddToScheme,
+		projectpermissionv1alpha1.SchemeBuilder.AddToScheme,
 		repositoryv1alpha1.SchemeBuilder.AddToScheme,
 	)
 }
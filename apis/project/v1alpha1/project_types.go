@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"reflect"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
@@ -32,6 +33,11 @@ type ProjectParameters struct {
 	Public bool `json:"public,omitempty"`
 	// +kubebuilder:validation:Optional
 	Description string `json:"description,omitempty"`
+	// DefaultPermission is the access every logged in user has to the
+	// project's repositories. Leave unset to not manage it.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=PROJECT_READ;PROJECT_WRITE;NONE
+	DefaultPermission *string `json:"defaultPermission,omitempty"`
 }
 
 type ProjectInitParameters struct {
@@ -41,6 +47,9 @@ type ProjectInitParameters struct {
 	Public bool `json:"public,omitempty"`
 	// +kubebuilder:validation:Optional
 	Description string `json:"description,omitempty"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=PROJECT_READ;PROJECT_WRITE;NONE
+	DefaultPermission *string `json:"defaultPermission,omitempty"`
 }
 
 // ProjectObservation are the observable fields of a Project.
@@ -78,6 +87,52 @@ type Project struct {
 	Status ProjectStatus `json:"status,omitempty"`
 }
 
+// TypePending is the condition reporting that the ProviderConfig or the
+// credentials of the project are not available yet.
+const TypePending xpv1.ConditionType = "Pending"
+
+// Reasons the project is or is not pending.
+const (
+	ReasonProviderConfigMissing xpv1.ConditionReason = "ProviderConfigMissing"
+	ReasonCredentialsMissing    xpv1.ConditionReason = "CredentialsMissing"
+	ReasonConnected             xpv1.ConditionReason = "Connected"
+)
+
+// ProviderConfigPending returns a condition indicating the referenced
+// ProviderConfig does not exist yet.
+func ProviderConfigPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonProviderConfigMissing,
+		Message:            "waiting for the ProviderConfig to be created",
+	}
+}
+
+// CredentialsPending returns a condition indicating the secret holding the
+// credentials does not exist yet.
+func CredentialsPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCredentialsMissing,
+		Message:            "waiting for the credentials secret to be created",
+	}
+}
+
+// NotPending returns a condition indicating the ProviderConfig and the
+// credentials are available.
+func NotPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonConnected,
+	}
+}
+
 // +kubebuilder:object:root=true
 
 // ProjectList contains a list of Project
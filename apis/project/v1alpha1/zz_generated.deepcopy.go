@@ -54,6 +54,11 @@ func (in *Project) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectInitParameters) DeepCopyInto(out *ProjectInitParameters) {
 	*out = *in
+	if in.DefaultPermission != nil {
+		in, out := &in.DefaultPermission, &out.DefaultPermission
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectInitParameters.
@@ -116,6 +121,11 @@ func (in *ProjectObservation) DeepCopy() *ProjectObservation {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectParameters) DeepCopyInto(out *ProjectParameters) {
 	*out = *in
+	if in.DefaultPermission != nil {
+		in, out := &in.DefaultPermission, &out.DefaultPermission
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectParameters.
@@ -132,8 +142,8 @@ func (in *ProjectParameters) DeepCopy() *ProjectParameters {
 func (in *ProjectSpec) DeepCopyInto(out *ProjectSpec) {
 	*out = *in
 	in.ResourceSpec.DeepCopyInto(&out.ResourceSpec)
-	out.ForProvider = in.ForProvider
-	out.InitProvider = in.InitProvider
+	in.ForProvider.DeepCopyInto(&out.ForProvider)
+	in.InitProvider.DeepCopyInto(&out.InitProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectSpec.
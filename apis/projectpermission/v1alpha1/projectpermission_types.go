@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// Kinds of subjects a project permission is granted to.
+const (
+	SubjectTypeGroup = "Group"
+	SubjectTypeUser  = "User"
+)
+
+// ProjectPermissionParameters are the configurable fields of a ProjectPermission.
+type ProjectPermissionParameters struct {
+	// ProjectKey is the key of the project the permission is granted on
+	ProjectKey string `json:"projectKey"`
+	// SubjectType is the kind of subject the permission is granted to
+	// +kubebuilder:validation:Enum=Group;User
+	SubjectType string `json:"subjectType"`
+	// Subject is the name of the group or user the permission is granted to
+	Subject string `json:"subject"`
+	// +kubebuilder:validation:Enum=PROJECT_READ;PROJECT_WRITE;PROJECT_ADMIN
+	Permission string `json:"permission"`
+}
+
+type ProjectPermissionInitParameters struct {
+	// +kubebuilder:validation:Optional
+	ProjectKey string `json:"projectKey"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Group;User
+	SubjectType string `json:"subjectType"`
+	// +kubebuilder:validation:Optional
+	Subject string `json:"subject"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=PROJECT_READ;PROJECT_WRITE;PROJECT_ADMIN
+	Permission string `json:"permission"`
+}
+
+// ProjectPermissionObservation are the observable fields of a ProjectPermission.
+type ProjectPermissionObservation struct {
+	// Permission is the permission currently granted to the subject
+	Permission string `json:"permission,omitempty"`
+}
+
+// A ProjectPermissionSpec defines the desired state of a ProjectPermission.
+type ProjectPermissionSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ProjectPermissionParameters     `json:"forProvider"`
+	InitProvider      ProjectPermissionInitParameters `json:"initProvider,omitempty"`
+}
+
+// A ProjectPermissionStatus represents the observed state of a ProjectPermission.
+type ProjectPermissionStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ProjectPermissionObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A ProjectPermission grants a group or user a permission on a project.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="PROJECT",type="string",JSONPath=".spec.forProvider.projectKey"
+// +kubebuilder:printcolumn:name="SUBJECT",type="string",JSONPath=".spec.forProvider.subject"
+// +kubebuilder:printcolumn:name="PERMISSION",type="string",JSONPath=".status.atProvider.permission"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,bitbucketserver}
+type ProjectPermission struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProjectPermissionSpec   `json:"spec"`
+	Status ProjectPermissionStatus `json:"status,omitempty"`
+}
+
+// TypePending is the condition reporting that the ProviderConfig or the
+// credentials of the project permission are not available yet.
+const TypePending xpv1.ConditionType = "Pending"
+
+// Reasons the project permission is or is not pending.
+const (
+	ReasonProviderConfigMissing xpv1.ConditionReason = "ProviderConfigMissing"
+	ReasonCredentialsMissing    xpv1.ConditionReason = "CredentialsMissing"
+	ReasonConnected             xpv1.ConditionReason = "Connected"
+)
+
+// ProviderConfigPending returns a condition indicating the referenced
+// ProviderConfig does not exist yet.
+func ProviderConfigPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonProviderConfigMissing,
+		Message:            "waiting for the ProviderConfig to be created",
+	}
+}
+
+// CredentialsPending returns a condition indicating the secret holding the
+// credentials does not exist yet.
+func CredentialsPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCredentialsMissing,
+		Message:            "waiting for the credentials secret to be created",
+	}
+}
+
+// NotPending returns a condition indicating the ProviderConfig and the
+// credentials are available.
+func NotPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonConnected,
+	}
+}
+
+// +kubebuilder:object:root=true
+
+// ProjectPermissionList contains a list of ProjectPermission
+type ProjectPermissionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProjectPermission `json:"items"`
+}
+
+// ProjectPermission type metadata.
+var (
+	ProjectPermissionKind             = reflect.TypeOf(ProjectPermission{}).Name()
+	ProjectPermissionGroupKind        = schema.GroupKind{Group: Group, Kind: ProjectPermissionKind}.String()
+	ProjectPermissionKindAPIVersion   = ProjectPermissionKind + "." + SchemeGroupVersion.String()
+	ProjectPermissionGroupVersionKind = SchemeGroupVersion.WithKind(ProjectPermissionKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&ProjectPermission{}, &ProjectPermissionList{})
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
 	"reflect"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
@@ -27,13 +29,102 @@ import (
 
 // RepositoryParameters are the configurable fields of a Repository.
 type RepositoryParameters struct {
-	Name    string `json:"name"`
+	Name string `json:"name"`
+	// Project is the key of the project of the repository. Defaults to the
+	// default-project of the ProviderConfig.
+	// +kubebuilder:validation:Optional
 	Project string `json:"project"`
 	Public  bool   `json:"public"`
+	// Description of the repository. It may be a go template rendered against
+	// the labels and annotations of the repository, e.g.
+	// "owned by {{ .Labels.team }}".
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=255
 	Description string `json:"description,omitempty"`
 	// +kubebuilder:validation:Optional
 	Groups []AdGroup `json:"groups,omitempty"`
+	// PruneUnknownGroups revokes group permissions on the repository that are
+	// not listed in groups. Set to false to leave permissions managed outside
+	// crossplane in place. Defaults to true.
+	// +kubebuilder:validation:Optional
+	PruneUnknownGroups *bool `json:"pruneUnknownGroups,omitempty"`
+	// +kubebuilder:validation:Optional
+	Mirroring *Mirroring `json:"mirroring,omitempty"`
+	// PullRequestTemplate is the default description of new pull requests
+	// +kubebuilder:validation:Optional
+	PullRequestTemplate *string `json:"pullRequestTemplate,omitempty"`
+	// DefaultMergeStrategy is the merge strategy selected by default when
+	// merging pull requests. It is enabled if it is not already, the other
+	// enabled strategies are left as they are.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=no-ff;ff;ff-only;rebase-no-ff;rebase-ff-only;squash;squash-ff-only
+	DefaultMergeStrategy *string `json:"defaultMergeStrategy,omitempty"`
+	// DeleteConfirmation requires the repository to be annotated with
+	// bitbucketserver.crossplane.io/confirm-delete: "true" before it is
+	// deleted when it holds data. Without the annotation deletion is retried
+	// until the annotation is set.
+	// +kubebuilder:validation:Optional
+	DeleteConfirmation *DeleteConfirmation `json:"deleteConfirmation,omitempty"`
+	// Archived archives or unarchives the repository. Archived repositories
+	// are read-only, so while archived the rest of the spec is not applied.
+	// Leave unset to not manage the archive state. Requires bitbucket 8.0.
+	// +kubebuilder:validation:Optional
+	Archived *bool `json:"archived,omitempty"`
+	// Branches are created in the repository when missing. Branches not
+	// listed are kept unless PruneUnknownBranches is set.
+	// +kubebuilder:validation:Optional
+	Branches []BranchRef `json:"branches,omitempty"`
+	// PruneUnknownBranches deletes branches not listed in branches, except
+	// the default branch. Defaults to false.
+	// +kubebuilder:validation:Optional
+	PruneUnknownBranches *bool `json:"pruneUnknownBranches,omitempty"`
+	// DefaultBranch is the name of the default branch of the repository, e.g.
+	// main. The default branch is only switched to an existing branch, until
+	// the branch exists the DefaultBranchPending condition is set.
+	// +kubebuilder:validation:Optional
+	DefaultBranch *string `json:"defaultBranch,omitempty"`
+	// ProtectDefaultBranch restricts the default branch so it cannot be
+	// force-pushed or deleted and only changes through pull requests. The
+	// restrictions are added once the repository has a default branch and are
+	// not removed when set to false.
+	// +kubebuilder:validation:Optional
+	ProtectDefaultBranch *bool `json:"protectDefaultBranch,omitempty"`
+	// DefaultBranchProtectionExemptGroups are the names of the groups exempted
+	// from the restrictions protecting the default branch. Exactly these
+	// groups are exempted, compared case-insensitively. Exemptions are not
+	// managed when unset.
+	// +kubebuilder:validation:Optional
+	DefaultBranchProtectionExemptGroups []string `json:"defaultBranchProtectionExemptGroups,omitempty"`
+	// EnabledHooks are the keys of the hooks enabled for the repository with
+	// their current settings, e.g.
+	// com.atlassian.bitbucket.server.bitbucket-bundled-hooks:force-push-hook.
+	// Hooks not listed are disabled. Leave unset to not manage hooks.
+	// +kubebuilder:validation:Optional
+	EnabledHooks []string `json:"enabledHooks,omitempty"`
+	// RequiredBuilds are merge checks requiring named builds to pass before
+	// pull requests can be merged. Required builds of branches not listed are
+	// removed. Leave unset to not manage required builds. Requires Bitbucket
+	// Data Center 7.14 or later.
+	// +kubebuilder:validation:Optional
+	RequiredBuilds []RequiredBuild `json:"requiredBuilds,omitempty"`
+	// LFSEnabled enables or disables git LFS for the repository. Leave unset
+	// to not manage LFS. Requires git LFS to be enabled on the server.
+	// +kubebuilder:validation:Optional
+	LFSEnabled *bool `json:"lfsEnabled,omitempty"`
+	// ForkSync configures synchronizing a fork with its upstream repository.
+	// Only applicable to forks. Leave unset to not manage synchronization.
+	// +kubebuilder:validation:Optional
+	ForkSync *ForkSync `json:"forkSync,omitempty"`
+	// TemplateFrom is a repository whose branch restrictions, hooks and pull
+	// request settings are copied to the repository when it is created.
+	// Settings in the spec take precedence over those of the template.
+	// +kubebuilder:validation:Optional
+	TemplateFrom *RepositoryRef `json:"templateFrom,omitempty"`
+	// OrphanStuckDeletionAfter removes the finalizer of a repository that
+	// bitbucket has refused to delete for this long, leaving the repository in
+	// bitbucket. Leave unset to keep retrying the deletion.
+	// +kubebuilder:validation:Optional
+	OrphanStuckDeletionAfter *metav1.Duration `json:"orphanStuckDeletionAfter,omitempty"`
 }
 
 type RepositoryInitParameters struct {
@@ -44,9 +135,71 @@ type RepositoryInitParameters struct {
 	// +kubebuilder:validation:Optional
 	Public bool `json:"public"`
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=255
 	Description string `json:"description,omitempty"`
 	// +kubebuilder:validation:Optional
 	Groups []AdGroup `json:"groups,omitempty"`
+	// +kubebuilder:validation:Optional
+	Mirroring *Mirroring `json:"mirroring,omitempty"`
+	// PullRequestTemplate is the default description of new pull requests
+	// +kubebuilder:validation:Optional
+	PullRequestTemplate *string `json:"pullRequestTemplate,omitempty"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=no-ff;ff;ff-only;rebase-no-ff;rebase-ff-only;squash;squash-ff-only
+	DefaultMergeStrategy *string `json:"defaultMergeStrategy,omitempty"`
+	// +kubebuilder:validation:Optional
+	Archived *bool `json:"archived,omitempty"`
+	// +kubebuilder:validation:Optional
+	Branches []BranchRef `json:"branches,omitempty"`
+	// +kubebuilder:validation:Optional
+	DefaultBranch *string `json:"defaultBranch,omitempty"`
+	// +kubebuilder:validation:Optional
+	ProtectDefaultBranch *bool `json:"protectDefaultBranch,omitempty"`
+	// +kubebuilder:validation:Optional
+	DefaultBranchProtectionExemptGroups []string `json:"defaultBranchProtectionExemptGroups,omitempty"`
+	// +kubebuilder:validation:Optional
+	EnabledHooks []string `json:"enabledHooks,omitempty"`
+	// +kubebuilder:validation:Optional
+	RequiredBuilds []RequiredBuild `json:"requiredBuilds,omitempty"`
+	// +kubebuilder:validation:Optional
+	LFSEnabled *bool `json:"lfsEnabled,omitempty"`
+	// +kubebuilder:validation:Optional
+	ForkSync *ForkSync `json:"forkSync,omitempty"`
+	// +kubebuilder:validation:Optional
+	TemplateFrom *RepositoryRef `json:"templateFrom,omitempty"`
+}
+
+// UserPermission is a permission granted to an individual user
+type UserPermission struct {
+	User       string `json:"user"`
+	Permission string `json:"permission"`
+}
+
+// BranchRef is a branch created in the repository
+type BranchRef struct {
+	// Name of the branch, e.g. develop
+	Name string `json:"name"`
+	// StartPoint is the branch, tag or commit the branch is created from, e.g. main
+	StartPoint string `json:"startPoint"`
+}
+
+// RequiredBuild requires builds to pass before pull requests to a branch can
+// be merged
+type RequiredBuild struct {
+	// Branch is the ref of the branch pull requests target, e.g. refs/heads/main
+	Branch string `json:"branch"`
+	// BuildKeys are the keys of the builds that must pass, e.g. the key of a
+	// Bamboo plan or a Jenkins job
+	// +kubebuilder:validation:MinItems=1
+	BuildKeys []string `json:"buildKeys"`
+}
+
+// RepositoryRef references a repository in bitbucket
+type RepositoryRef struct {
+	// Project is the key of the project of the repository
+	Project string `json:"project"`
+	// Slug of the repository
+	Slug string `json:"slug"`
 }
 
 type AdGroup struct {
@@ -54,9 +207,265 @@ type AdGroup struct {
 	Permission string `json:"permission"`
 }
 
+// AnnotationConfirmDelete confirms the deletion of a repository that requires
+// confirmation
+const AnnotationConfirmDelete = "bitbucketserver.crossplane.io/confirm-delete"
+
+// AnnotationForceSync makes the next observation of the repository bypass the
+// cached state of bitbucket. The annotation is removed once observed.
+const AnnotationForceSync = "bitbucketserver.crossplane.io/force-sync"
+
+// DeleteConfirmation configures when deleting the repository requires confirmation.
+type DeleteConfirmation struct {
+	// MaxSize is the size in bytes above which deleting the repository
+	// requires confirmation
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	MaxSize *int64 `json:"maxSize,omitempty"`
+	// IfCommits requires confirmation to delete a repository containing commits
+	// +kubebuilder:validation:Optional
+	IfCommits bool `json:"ifCommits,omitempty"`
+}
+
+// Mirroring configures which smart mirrors the repository is mirrored to.
+// Requires mirroring to be set up on the bitbucket server.
+type Mirroring struct {
+	// MirrorServers are the ids of the mirror servers the repository is
+	// mirrored to. Mirror servers not listed stop mirroring the repository.
+	MirrorServers []string `json:"mirrorServers"`
+}
+
+// ForkSync configures synchronizing a fork with its upstream repository.
+// Bitbucket fast-forwards the refs that have not diverged from upstream, refs
+// that have diverged are left for their owners to reconcile.
+type ForkSync struct {
+	// Enabled synchronizes the fork automatically
+	Enabled bool `json:"enabled"`
+}
+
 // RepositoryObservation are the observable fields of a Repository.
 type RepositoryObservation struct {
 	ID int `json:"id"`
+	// Project is the key of the project the repository was last observed in.
+	// It differs from the spec until a repository whose project changed is
+	// moved.
+	Project string `json:"project,omitempty"`
+	// DriftReason lists the fields that differed from the spec when the
+	// repository was last observed, empty when up to date.
+	DriftReason string `json:"driftReason,omitempty"`
+	// Archived is true when the repository is archived in bitbucket
+	Archived bool `json:"archived,omitempty"`
+	// IsFork is true when the repository is a fork of another repository
+	IsFork bool `json:"isFork,omitempty"`
+	// AnonymousAccess is true when anonymous users can read the repository,
+	// either because it is public or because its project is public. A
+	// private repository in a public project is reported with a warning event
+	// rather than as drift: only making the project private revokes the
+	// access, which the repository cannot do, so the drift would never
+	// resolve. It is kept from the previous observation when the project
+	// cannot be read.
+	AnonymousAccess bool `json:"anonymousAccess,omitempty"`
+	// Origin is the project/slug of the repository this repository is forked
+	// from
+	Origin string `json:"origin,omitempty"`
+	// CreatedAt is when the repository was created in bitbucket, empty when
+	// bitbucket does not report it
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+	// Users are the permissions granted to individual users on the
+	// repository, kept from the previous observation when they cannot be listed
+	Users []UserPermission `json:"users,omitempty"`
+	// OpenPullRequests is the number of open pull requests targeting the
+	// repository. At most 500 are counted, so a busy repository reports 500.
+	OpenPullRequests int `json:"openPullRequests,omitempty"`
+	// RequiredApprovals is the highest number of approvals from default
+	// reviewers required by the default reviewer conditions of the
+	// repository, kept from the previous observation when they cannot be read
+	RequiredApprovals int `json:"requiredApprovals,omitempty"`
+	// LastSyncTime is when the repository was last successfully observed or
+	// updated in bitbucket
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// TypeArchived is the condition reporting that the repository is archived in
+// bitbucket and the spec is not applied to it.
+const TypeArchived xpv1.ConditionType = "Archived"
+
+// Reasons the repository is or is not archived.
+const (
+	ReasonArchived    xpv1.ConditionReason = "RepositoryArchived"
+	ReasonNotArchived xpv1.ConditionReason = "RepositoryNotArchived"
+)
+
+// Archived returns a condition indicating the repository is archived and the
+// spec is not applied to it.
+func Archived() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeArchived,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonArchived,
+		Message:            "repository is archived in bitbucket, set archived to false to unarchive it and apply the spec",
+	}
+}
+
+// NotArchived returns a condition indicating the repository is not archived.
+func NotArchived() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeArchived,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNotArchived,
+	}
+}
+
+// TypeDeletionBlocked is the condition reporting that bitbucket refuses to
+// delete the repository.
+const TypeDeletionBlocked xpv1.ConditionType = "DeletionBlocked"
+
+// Reasons the deletion of the repository is blocked.
+const (
+	ReasonDeletionFailed   xpv1.ConditionReason = "DeletionFailed"
+	ReasonDeletionOrphaned xpv1.ConditionReason = "DeletionOrphaned"
+)
+
+// DeletionBlocked returns a condition indicating bitbucket refuses to delete
+// the repository.
+func DeletionBlocked() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDeletionBlocked,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDeletionFailed,
+		Message:            "bitbucket refuses to delete the repository, see the Synced condition for the error",
+	}
+}
+
+// DeletionOrphaned returns a condition indicating the repository could not be
+// deleted and is left in bitbucket.
+func DeletionOrphaned() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDeletionBlocked,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDeletionOrphaned,
+		Message:            "bitbucket refused to delete the repository for longer than orphanStuckDeletionAfter, the repository is left in bitbucket",
+	}
+}
+
+// TypePending is the condition reporting that the ProviderConfig or the
+// credentials of the repository are not available yet.
+const TypePending xpv1.ConditionType = "Pending"
+
+// Reasons the repository is or is not pending.
+const (
+	ReasonProviderConfigMissing xpv1.ConditionReason = "ProviderConfigMissing"
+	ReasonCredentialsMissing    xpv1.ConditionReason = "CredentialsMissing"
+	ReasonConnected             xpv1.ConditionReason = "Connected"
+)
+
+// ProviderConfigPending returns a condition indicating the referenced
+// ProviderConfig does not exist yet.
+func ProviderConfigPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonProviderConfigMissing,
+		Message:            "waiting for the ProviderConfig to be created",
+	}
+}
+
+// CredentialsPending returns a condition indicating the secret holding the
+// credentials does not exist yet.
+func CredentialsPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCredentialsMissing,
+		Message:            "waiting for the credentials secret to be created",
+	}
+}
+
+// NotPending returns a condition indicating the ProviderConfig and the
+// credentials are available.
+func NotPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePending,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonConnected,
+	}
+}
+
+// TypeDefaultBranchPending is the condition reporting that the default branch
+// in the spec does not exist yet, so the default branch is not switched.
+const TypeDefaultBranchPending xpv1.ConditionType = "DefaultBranchPending"
+
+// Reasons the default branch is or is not pending.
+const (
+	ReasonDefaultBranchMissing xpv1.ConditionReason = "DefaultBranchMissing"
+	ReasonDefaultBranchSet     xpv1.ConditionReason = "DefaultBranchSet"
+)
+
+// DefaultBranchPending returns a condition indicating the default branch in
+// the spec does not exist yet.
+func DefaultBranchPending(branch string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDefaultBranchPending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDefaultBranchMissing,
+		Message:            fmt.Sprintf("branch %s does not exist, the default branch is switched once it is pushed or listed in branches", branch),
+	}
+}
+
+// DefaultBranchSet returns a condition indicating the default branch is the
+// one in the spec.
+func DefaultBranchSet() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDefaultBranchPending,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDefaultBranchSet,
+	}
+}
+
+// TypeBitbucketError is the condition reporting the kind of error bitbucket
+// returned when the repository was last reconciled.
+const TypeBitbucketError xpv1.ConditionType = "BitbucketError"
+
+// Reasons of the errors returned by bitbucket.
+const (
+	ReasonAuthenticationFailed xpv1.ConditionReason = "AuthenticationFailed"
+	ReasonConflict             xpv1.ConditionReason = "Conflict"
+	ReasonUnsupported          xpv1.ConditionReason = "Unsupported"
+	ReasonMaintenance          xpv1.ConditionReason = "Maintenance"
+	ReasonInvalidResponse      xpv1.ConditionReason = "InvalidResponse"
+	ReasonNoError              xpv1.ConditionReason = "NoError"
+)
+
+// BitbucketError returns a condition indicating bitbucket returned an error of
+// the kind given by the reason.
+func BitbucketError(reason xpv1.ConditionReason, err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeBitbucketError,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            err.Error(),
+	}
+}
+
+// NoBitbucketError returns a condition indicating the repository was
+// reconciled without bitbucket returning an error.
+func NoBitbucketError() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeBitbucketError,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoError,
+	}
 }
 
 // A RepositorySpec defines the desired state of a Repository.
@@ -64,6 +473,10 @@ type RepositorySpec struct {
 	xpv1.ResourceSpec `json:",inline"`
 	ForProvider       RepositoryParameters     `json:"forProvider"`
 	InitProvider      RepositoryInitParameters `json:"initProvider,omitempty"`
+	// CredentialsSecretRef references credentials used for this repository
+	// instead of those of the ProviderConfig, e.g. a token with a narrower scope.
+	// +optional
+	CredentialsSecretRef *xpv1.SecretKeySelector `json:"credentialsSecretRef,omitempty"`
 }
 
 // A RepositoryStatus represents the observed state of a Repository.
@@ -21,6 +21,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	commonv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -39,6 +41,76 @@ func (in *AdGroup) DeepCopy() *AdGroup {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BranchRef) DeepCopyInto(out *BranchRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BranchRef.
+func (in *BranchRef) DeepCopy() *BranchRef {
+	if in == nil {
+		return nil
+	}
+	out := new(BranchRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeleteConfirmation) DeepCopyInto(out *DeleteConfirmation) {
+	*out = *in
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeleteConfirmation.
+func (in *DeleteConfirmation) DeepCopy() *DeleteConfirmation {
+	if in == nil {
+		return nil
+	}
+	out := new(DeleteConfirmation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForkSync) DeepCopyInto(out *ForkSync) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForkSync.
+func (in *ForkSync) DeepCopy() *ForkSync {
+	if in == nil {
+		return nil
+	}
+	out := new(ForkSync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Mirroring) DeepCopyInto(out *Mirroring) {
+	*out = *in
+	if in.MirrorServers != nil {
+		in, out := &in.MirrorServers, &out.MirrorServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Mirroring.
+func (in *Mirroring) DeepCopy() *Mirroring {
+	if in == nil {
+		return nil
+	}
+	out := new(Mirroring)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Repository) DeepCopyInto(out *Repository) {
 	*out = *in
@@ -74,6 +146,73 @@ func (in *RepositoryInitParameters) DeepCopyInto(out *RepositoryInitParameters)
 		*out = make([]AdGroup, len(*in))
 		copy(*out, *in)
 	}
+	if in.Mirroring != nil {
+		in, out := &in.Mirroring, &out.Mirroring
+		*out = new(Mirroring)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PullRequestTemplate != nil {
+		in, out := &in.PullRequestTemplate, &out.PullRequestTemplate
+		*out = new(string)
+		**out = **in
+	}
+	if in.DefaultMergeStrategy != nil {
+		in, out := &in.DefaultMergeStrategy, &out.DefaultMergeStrategy
+		*out = new(string)
+		**out = **in
+	}
+	if in.Archived != nil {
+		in, out := &in.Archived, &out.Archived
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]BranchRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultBranch != nil {
+		in, out := &in.DefaultBranch, &out.DefaultBranch
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProtectDefaultBranch != nil {
+		in, out := &in.ProtectDefaultBranch, &out.ProtectDefaultBranch
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DefaultBranchProtectionExemptGroups != nil {
+		in, out := &in.DefaultBranchProtectionExemptGroups, &out.DefaultBranchProtectionExemptGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnabledHooks != nil {
+		in, out := &in.EnabledHooks, &out.EnabledHooks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredBuilds != nil {
+		in, out := &in.RequiredBuilds, &out.RequiredBuilds
+		*out = make([]RequiredBuild, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LFSEnabled != nil {
+		in, out := &in.LFSEnabled, &out.LFSEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ForkSync != nil {
+		in, out := &in.ForkSync, &out.ForkSync
+		*out = new(ForkSync)
+		**out = **in
+	}
+	if in.TemplateFrom != nil {
+		in, out := &in.TemplateFrom, &out.TemplateFrom
+		*out = new(RepositoryRef)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryInitParameters.
@@ -121,6 +260,19 @@ func (in *RepositoryList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepositoryObservation) DeepCopyInto(out *RepositoryObservation) {
 	*out = *in
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]UserPermission, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryObservation.
@@ -141,6 +293,93 @@ func (in *RepositoryParameters) DeepCopyInto(out *RepositoryParameters) {
 		*out = make([]AdGroup, len(*in))
 		copy(*out, *in)
 	}
+	if in.PruneUnknownGroups != nil {
+		in, out := &in.PruneUnknownGroups, &out.PruneUnknownGroups
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Mirroring != nil {
+		in, out := &in.Mirroring, &out.Mirroring
+		*out = new(Mirroring)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PullRequestTemplate != nil {
+		in, out := &in.PullRequestTemplate, &out.PullRequestTemplate
+		*out = new(string)
+		**out = **in
+	}
+	if in.DefaultMergeStrategy != nil {
+		in, out := &in.DefaultMergeStrategy, &out.DefaultMergeStrategy
+		*out = new(string)
+		**out = **in
+	}
+	if in.DeleteConfirmation != nil {
+		in, out := &in.DeleteConfirmation, &out.DeleteConfirmation
+		*out = new(DeleteConfirmation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Archived != nil {
+		in, out := &in.Archived, &out.Archived
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]BranchRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.PruneUnknownBranches != nil {
+		in, out := &in.PruneUnknownBranches, &out.PruneUnknownBranches
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DefaultBranch != nil {
+		in, out := &in.DefaultBranch, &out.DefaultBranch
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProtectDefaultBranch != nil {
+		in, out := &in.ProtectDefaultBranch, &out.ProtectDefaultBranch
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DefaultBranchProtectionExemptGroups != nil {
+		in, out := &in.DefaultBranchProtectionExemptGroups, &out.DefaultBranchProtectionExemptGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnabledHooks != nil {
+		in, out := &in.EnabledHooks, &out.EnabledHooks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredBuilds != nil {
+		in, out := &in.RequiredBuilds, &out.RequiredBuilds
+		*out = make([]RequiredBuild, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LFSEnabled != nil {
+		in, out := &in.LFSEnabled, &out.LFSEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ForkSync != nil {
+		in, out := &in.ForkSync, &out.ForkSync
+		*out = new(ForkSync)
+		**out = **in
+	}
+	if in.TemplateFrom != nil {
+		in, out := &in.TemplateFrom, &out.TemplateFrom
+		*out = new(RepositoryRef)
+		**out = **in
+	}
+	if in.OrphanStuckDeletionAfter != nil {
+		in, out := &in.OrphanStuckDeletionAfter, &out.OrphanStuckDeletionAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryParameters.
@@ -153,12 +392,32 @@ func (in *RepositoryParameters) DeepCopy() *RepositoryParameters {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryRef) DeepCopyInto(out *RepositoryRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryRef.
+func (in *RepositoryRef) DeepCopy() *RepositoryRef {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepositorySpec) DeepCopyInto(out *RepositorySpec) {
 	*out = *in
 	in.ResourceSpec.DeepCopyInto(&out.ResourceSpec)
 	in.ForProvider.DeepCopyInto(&out.ForProvider)
 	in.InitProvider.DeepCopyInto(&out.InitProvider)
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(commonv1.SecretKeySelector)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySpec.
@@ -175,7 +434,7 @@ func (in *RepositorySpec) DeepCopy() *RepositorySpec {
 func (in *RepositoryStatus) DeepCopyInto(out *RepositoryStatus) {
 	*out = *in
 	in.ResourceStatus.DeepCopyInto(&out.ResourceStatus)
-	out.AtProvider = in.AtProvider
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryStatus.
@@ -187,3 +446,38 @@ func (in *RepositoryStatus) DeepCopy() *RepositoryStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequiredBuild) DeepCopyInto(out *RequiredBuild) {
+	*out = *in
+	if in.BuildKeys != nil {
+		in, out := &in.BuildKeys, &out.BuildKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequiredBuild.
+func (in *RequiredBuild) DeepCopy() *RequiredBuild {
+	if in == nil {
+		return nil
+	}
+	out := new(RequiredBuild)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserPermission) DeepCopyInto(out *UserPermission) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserPermission.
+func (in *UserPermission) DeepCopy() *UserPermission {
+	if in == nil {
+		return nil
+	}
+	out := new(UserPermission)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -21,6 +21,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -87,11 +89,130 @@ func (in *ProviderConfigList) DeepCopyObject() runtime.Object {
 func (in *ProviderConfigSpec) DeepCopyInto(out *ProviderConfigSpec) {
 	*out = *in
 	in.Credentials.DeepCopyInto(&out.Credentials)
+	if in.FailoverURLs != nil {
+		in, out := &in.FailoverURLs, &out.FailoverURLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.CaCertPath != nil {
 		in, out := &in.CaCertPath, &out.CaCertPath
 		*out = new(string)
 		**out = **in
 	}
+	if in.DefaultProject != nil {
+		in, out := &in.DefaultProject, &out.DefaultProject
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxResponseBodySize != nil {
+		in, out := &in.MaxResponseBodySize, &out.MaxResponseBodySize
+		*out = new(int64)
+		**out = **in
+	}
+	if in.AcceptLanguage != nil {
+		in, out := &in.AcceptLanguage, &out.AcceptLanguage
+		*out = new(string)
+		**out = **in
+	}
+	if in.TLSMinVersion != nil {
+		in, out := &in.TLSMinVersion, &out.TLSMinVersion
+		*out = new(string)
+		**out = **in
+	}
+	if in.TLSCipherSuites != nil {
+		in, out := &in.TLSCipherSuites, &out.TLSCipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClientCertificateSecretRef != nil {
+		in, out := &in.ClientCertificateSecretRef, &out.ClientCertificateSecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.GroupConcurrency != nil {
+		in, out := &in.GroupConcurrency, &out.GroupConcurrency
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxGroupsPerRepository != nil {
+		in, out := &in.MaxGroupsPerRepository, &out.MaxGroupsPerRepository
+		*out = new(int)
+		**out = **in
+	}
+	if in.DialTimeout != nil {
+		in, out := &in.DialTimeout, &out.DialTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ResponseHeaderTimeout != nil {
+		in, out := &in.ResponseHeaderTimeout, &out.ResponseHeaderTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RepositoryReadyTimeout != nil {
+		in, out := &in.RepositoryReadyTimeout, &out.RepositoryReadyTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.OperationTimeouts != nil {
+		in, out := &in.OperationTimeouts, &out.OperationTimeouts
+		*out = make(map[string]metav1.Duration, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ConnectionDetailKeys != nil {
+		in, out := &in.ConnectionDetailKeys, &out.ConnectionDetailKeys
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PreferredCloneProtocol != nil {
+		in, out := &in.PreferredCloneProtocol, &out.PreferredCloneProtocol
+		*out = new(string)
+		**out = **in
+	}
+	if in.RepositoryNamePattern != nil {
+		in, out := &in.RepositoryNamePattern, &out.RepositoryNamePattern
+		*out = new(string)
+		**out = **in
+	}
+	if in.SOCKS5Proxy != nil {
+		in, out := &in.SOCKS5Proxy, &out.SOCKS5Proxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.DisablePing != nil {
+		in, out := &in.DisablePing, &out.DisablePing
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PingEndpoint != nil {
+		in, out := &in.PingEndpoint, &out.PingEndpoint
+		*out = new(string)
+		**out = **in
+	}
+	if in.DisableRedirects != nil {
+		in, out := &in.DisableRedirects, &out.DisableRedirects
+		*out = new(bool)
+		**out = **in
+	}
+	if in.VerifyAccess != nil {
+		in, out := &in.VerifyAccess, &out.VerifyAccess
+		*out = new(bool)
+		**out = **in
+	}
+	if in.VerifyTokenScopes != nil {
+		in, out := &in.VerifyTokenScopes, &out.VerifyTokenScopes
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RetryBudget != nil {
+		in, out := &in.RetryBudget, &out.RetryBudget
+		*out = new(RetryBudget)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConfigSpec.
@@ -194,6 +315,22 @@ func (in *ProviderCredentials) DeepCopy() *ProviderCredentials {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryBudget) DeepCopyInto(out *RetryBudget) {
+	*out = *in
+	out.Window = in.Window
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryBudget.
+func (in *RetryBudget) DeepCopy() *RetryBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StoreConfig) DeepCopyInto(out *StoreConfig) {
 	*out = *in
@@ -0,0 +1,51 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command import prints Repository manifests for the existing repositories
+// of a Bitbucket project, to adopt them with the provider.
+//
+//	BITBUCKET_TOKEN=... go run ./cmd/import --base-url https://bitbucket.example.com --project PRJ > repositories.yaml
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/importer"
+)
+
+func main() {
+	var (
+		app            = kingpin.New(filepath.Base(os.Args[0]), "Print Repository manifests for the repositories of a Bitbucket project.").DefaultEnvars()
+		baseURL        = app.Flag("base-url", "Base URL of the Bitbucket server.").Required().String()
+		token          = app.Flag("token", "Bitbucket HTTP access token.").Envar("BITBUCKET_TOKEN").Required().String()
+		caCertPath     = app.Flag("ca-cert-path", "CA certificate to trust in addition to the system certificates.").String()
+		project        = app.Flag("project", "Key of the project whose repositories are imported.").Required().String()
+		providerConfig = app.Flag("provider-config", "Name of the ProviderConfig the manifests reference.").Default("default").String()
+	)
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	client, err := bitbucket.NewClient(*baseURL, *token, caCertPath)
+	kingpin.FatalIfError(err, "Cannot create Bitbucket client")
+	svc, err := bitbucket.NewService(client)
+	kingpin.FatalIfError(err, "Cannot create Bitbucket service")
+
+	kingpin.FatalIfError(importer.WriteRepositories(context.Background(), svc.Repositories, *project, *providerConfig, os.Stdout), "Cannot import repositories")
+}
@@ -40,6 +40,9 @@ import (
 	"github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
 	bitbucketserver "github.com/MrVinkel/provider-bitbucketserver/internal/controller"
 	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/features"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/graceful"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/jitter"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/version"
 )
 
 func main() {
@@ -50,7 +53,9 @@ func main() {
 
 		syncInterval     = app.Flag("sync", "How often all resources will be double-checked for drift from the desired state.").Short('s').Default("1h").Duration()
 		pollInterval     = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("1m").Duration()
+		pollJitter       = app.Flag("poll-jitter", "Fraction of the poll interval by which individual resources are randomly checked earlier or later, to spread out the load on Bitbucket.").Default("0.1").Float64()
 		maxReconcileRate = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("10").Int()
+		shutdownGrace    = app.Flag("shutdown-grace-period", "How long in-flight Bitbucket operations may run to completion after the provider is asked to stop.").Default("20s").Duration()
 
 		namespace                  = app.Flag("namespace", "Namespace used to set as default scope in default secret store config.").Default("crossplane-system").Envar("POD_NAMESPACE").String()
 		enableExternalSecretStores = app.Flag("enable-external-secret-stores", "Enable support for ExternalSecretStores.").Default("false").Envar("ENABLE_EXTERNAL_SECRET_STORES").Bool()
@@ -60,6 +65,7 @@ func main() {
 
 	zl := zap.New(zap.UseDevMode(*debug))
 	log := logging.NewLogrLogger(zl.WithName("provider-bitbucketserver"))
+	log.Info("Starting provider", "version", version.Version)
 	if *debug {
 		// The controller-runtime runs with a no-op logger by default. It is
 		// *very* verbose even at info level, so we only provide it a real
@@ -85,6 +91,17 @@ func main() {
 		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
 		LeaseDuration:              func() *time.Duration { d := 60 * time.Second; return &d }(),
 		RenewDeadline:              func() *time.Duration { d := 50 * time.Second; return &d }(),
+
+		// Reconciles are cancelled as soon as the manager is stopped. Carry
+		// the grace period in their context so in-flight Bitbucket
+		// operations can finish instead of leaving a half-applied
+		// repository behind, and have the manager wait for them. The poll
+		// jitter is carried the same way.
+		BaseContext: func() context.Context {
+			ctx := jitter.WithFraction(context.Background(), *pollJitter)
+			return graceful.WithPeriod(ctx, *shutdownGrace)
+		},
+		GracefulShutdownTimeout: shutdownGrace,
 	})
 	kingpin.FatalIfError(err, "Cannot create controller manager")
 	kingpin.FatalIfError(apis.AddToScheme(mgr.GetScheme()), "Cannot add BitbucketServer APIs to scheme")
@@ -0,0 +1,101 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const branchUtilsAPI = "branch-utils/1.0"
+
+// Branch is a branch of a repository
+type Branch struct {
+	// ID is the ref of the branch, e.g. refs/heads/main
+	ID string
+	// Name is the display name of the branch, e.g. main
+	Name      string
+	IsDefault bool
+}
+
+// GetBranches returns the branches of the repository
+func (service *repositoryService) GetBranches(ctx context.Context, repository *Repository) ([]Branch, error) {
+	url := fmt.Sprintf("projects/%s/repos/%s/branches", repository.Project, repository.Name)
+
+	branches := []Branch{}
+	err := service.client.getPaged(ctx, url, func(values json.RawMessage) error {
+		var entries []struct {
+			ID        string `json:"id"`
+			DisplayID string `json:"displayId"`
+			IsDefault bool   `json:"isDefault"`
+		}
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			branches = append(branches, Branch{ID: entry.ID, Name: entry.DisplayID, IsDefault: entry.IsDefault})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting repository branches: %w", err)
+	}
+	return branches, nil
+}
+
+// CreateBranch creates the branch from the start point, a branch, tag or commit
+func (service *repositoryService) CreateBranch(ctx context.Context, repository *Repository, name string, startPoint string) error {
+	url := restAPIPath(branchUtilsAPI, fmt.Sprintf("projects/%s/repos/%s/branches", repository.Project, repository.Name))
+	body := struct {
+		Name       string `json:"name"`
+		StartPoint string `json:"startPoint"`
+	}{Name: name, StartPoint: startPoint}
+	req, err := service.client.newRequest(http.MethodPost, url, &body)
+	if err != nil {
+		return fmt.Errorf("error creating request for creating repository branch: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error creating repository branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteBranch deletes the branch with the ref, e.g. refs/heads/feature
+func (service *repositoryService) DeleteBranch(ctx context.Context, repository *Repository, ref string) error {
+	url := restAPIPath(branchUtilsAPI, fmt.Sprintf("projects/%s/repos/%s/branches", repository.Project, repository.Name))
+	body := struct {
+		Name   string `json:"name"`
+		DryRun bool   `json:"dryRun"`
+	}{Name: ref}
+	req, err := service.client.newRequest(http.MethodDelete, url, &body)
+	if err != nil {
+		return fmt.Errorf("error creating request for deleting repository branch: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error deleting repository branch %s: %w", ref, err)
+	}
+	return nil
+}
+
+// SetDefaultBranch makes the branch with the ref, e.g. refs/heads/main, the
+// default branch of the repository. Only the default branch setting changes,
+// no commits are pushed.
+func (service *repositoryService) SetDefaultBranch(ctx context.Context, repository *Repository, ref string) error {
+	body := struct {
+		ID string `json:"id"`
+	}{ID: ref}
+	req, err := service.client.newRequest(http.MethodPut, fmt.Sprintf("projects/%s/repos/%s/branches/default", repository.Project, repository.Name), &body)
+	if err != nil {
+		return fmt.Errorf("error creating request for setting default branch: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error setting default branch %s: %w", ref, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package bitbucket
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBranches(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		body   string
+	}
+	requests := []request{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{method: r.Method, path: r.URL.Path, body: strings.TrimSpace(string(b))})
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"values":[{"id":"refs/heads/main","displayId":"main","isDefault":true},{"id":"refs/heads/develop","displayId":"develop"}],"isLastPage":true}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	service := &repositoryService{client: c}
+	repo := &Repository{Name: "repo", Project: "PRJ"}
+
+	branches, err := service.GetBranches(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("GetBranches(...): %v", err)
+	}
+	wantBranches := []Branch{
+		{ID: "refs/heads/main", Name: "main", IsDefault: true},
+		{ID: "refs/heads/develop", Name: "develop"},
+	}
+	if diff := cmp.Diff(wantBranches, branches); diff != "" {
+		t.Errorf("GetBranches(...): -want, +got:\n%s\n", diff)
+	}
+
+	if err := service.CreateBranch(context.Background(), repo, "feature", "main"); err != nil {
+		t.Fatalf("CreateBranch(...): %v", err)
+	}
+	if err := service.DeleteBranch(context.Background(), repo, "refs/heads/feature"); err != nil {
+		t.Fatalf("DeleteBranch(...): %v", err)
+	}
+	if err := service.SetDefaultBranch(context.Background(), repo, "refs/heads/develop"); err != nil {
+		t.Fatalf("SetDefaultBranch(...): %v", err)
+	}
+
+	branchUtils := "/rest/branch-utils/1.0/projects/PRJ/repos/repo/branches"
+	want := []request{
+		{method: http.MethodGet, path: apiPath + "projects/PRJ/repos/repo/branches"},
+		{method: http.MethodPost, path: branchUtils, body: `{"name":"feature","startPoint":"main"}`},
+		{method: http.MethodDelete, path: branchUtils, body: `{"name":"refs/heads/feature","dryRun":false}`},
+		{method: http.MethodPut, path: apiPath + "projects/PRJ/repos/repo/branches/default", body: `{"id":"refs/heads/develop"}`},
+	}
+	if diff := cmp.Diff(want, requests, cmp.AllowUnexported(request{})); diff != "" {
+		t.Errorf("branch requests: -want, +got:\n%s\n", diff)
+	}
+}
@@ -2,6 +2,7 @@ package bitbucket
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -9,16 +10,51 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/MrVinkel/provider-bitbucketserver/internal/version"
 )
 
 const (
 	apiPath       = "/rest/api/1.0/"
 	jsonMediaType = "application/json"
+
+	// defaultMaxResponseSize is the default upper bound of bytes read from a response body
+	defaultMaxResponseSize int64 = 4 << 20
+
+	// pageLimit is the number of entries requested per page from paged apis
+	pageLimit = 100
+	// defaultMaxPages is the default upper bound of pages fetched from a paged api
+	defaultMaxPages = 100
+
+	// maxBodySnippet is the number of bytes of a malformed response body included in the error
+	maxBodySnippet = 200
+
+	// defaultConnectRetries is the number of times a request failing to
+	// connect is retried, e.g. while bitbucket is starting
+	defaultConnectRetries = 3
+	// defaultConnectBackoff is the wait before the first retry, doubled for
+	// each following retry
+	defaultConnectBackoff = 500 * time.Millisecond
+
+	// defaultPingTimeout bounds the request NewClient sends to check
+	// connectivity, which has no reconcile deadline of its own
+	defaultPingTimeout = 10 * time.Second
+
+	// maxRedirects is the number of redirects followed per request
+	maxRedirects = 10
 )
 
 // Client encapsulates a client that talks to the bitbucket server api
@@ -32,6 +68,169 @@ type Client struct {
 
 	// base URL for the bitbucket server + apiPath
 	baseURL *url.URL
+
+	// maxResponseSize is the maximum number of bytes read from a response body
+	maxResponseSize int64
+
+	// maxPages is the maximum number of pages fetched from a paged api
+	maxPages int
+
+	// serverInfo caches the information about the server once fetched
+	serverInfo   *ServerInfo
+	serverInfoMu sync.Mutex
+
+	// etags caches responses of conditional requests, nil when not caching
+	etags *ETagCache
+
+	// skipPing leaves validating connectivity to the first real request
+	skipPing bool
+	// pingEndpoint is the endpoint requested to check connectivity
+	pingEndpoint string
+	// pingTimeout bounds the whole request checking connectivity, including
+	// reading the response, whatever the timeouts of the http client
+	pingTimeout time.Duration
+
+	// connectRetries is the number of times a request failing to connect is
+	// retried, waiting connectBackoff doubled for each retry in between
+	connectRetries int
+	connectBackoff time.Duration
+	// retryBudget limits the retries of all clients of the server, nil
+	// when not limited
+	retryBudget        *retryBudget
+	retryBudgetRetries int
+	retryBudgetWindow  time.Duration
+
+	// failoverURLs are the base URLs of further endpoints serving the same
+	// bitbucket + apiPath, tried in order when baseURL cannot be connected to
+	failoverURLs    []*url.URL
+	rawFailoverURLs []string
+	// endpoint is the index of the endpoint requests are sent to, 0 for
+	// baseURL and i for failoverURLs[i-1]
+	endpoint int32
+}
+
+// ClientOption configures optional behaviour of the Client
+type ClientOption func(*Client)
+
+// WithMaxResponseSize limits the number of bytes read from a response body.
+// Non-positive values keep the default limit.
+func WithMaxResponseSize(size int64) ClientOption {
+	return func(c *Client) {
+		if size > 0 {
+			c.maxResponseSize = size
+		}
+	}
+}
+
+// WithDialTimeout limits the time spent establishing a connection to
+// bitbucket, so an unreachable server fails fast
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.transport().DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	}
+}
+
+// WithResponseHeaderTimeout limits the time waited for bitbucket to respond
+// once a request is sent. It replaces the overall request timeout, so slow
+// responses are bounded by it and the reconcile deadline instead. The request
+// NewClient sends to check connectivity stays bounded by its own timeout.
+func WithResponseHeaderTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.transport().ResponseHeaderTimeout = timeout
+		c.client.Timeout = 0
+	}
+}
+
+// WithProxyDialer establishes connections to bitbucket through the dialer of
+// a proxy, e.g. a SOCKS5 dialer from golang.org/x/net/proxy. It replaces the
+// dialer set by WithDialTimeout, so the timeout belongs on the proxy dialer.
+func WithProxyDialer(dialer proxy.ContextDialer) ClientOption {
+	return func(c *Client) {
+		c.transport().DialContext = dialer.DialContext
+	}
+}
+
+// WithTLSMinVersion sets the minimum TLS version accepted from the server, e.g. tls.VersionTLS13
+func WithTLSMinVersion(version uint16) ClientOption {
+	return func(c *Client) {
+		c.transport().TLSClientConfig.MinVersion = version
+	}
+}
+
+// WithTLSCipherSuites restricts the cipher suites offered for TLS 1.2 connections.
+// TLS 1.3 cipher suites are not configurable.
+func WithTLSCipherSuites(suites []uint16) ClientOption {
+	return func(c *Client) {
+		c.transport().TLSClientConfig.CipherSuites = suites
+	}
+}
+
+// WithClientCertificate presents the certificate to the server for mutual TLS
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *Client) {
+		c.transport().TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+}
+
+// WithAcceptLanguage sets the Accept-Language header of every request so error
+// messages from the server are in a predictable language
+func WithAcceptLanguage(language string) ClientOption {
+	return func(c *Client) {
+		if language != "" {
+			c.headers["Accept-Language"] = language
+		}
+	}
+}
+
+// WithFailoverURLs adds the base URLs of further endpoints of the same
+// bitbucket, e.g. the nodes behind a load balancer. A request that cannot
+// connect to its endpoint is sent to the next one, and requests stay on the
+// endpoint that last connected.
+func WithFailoverURLs(urls ...string) ClientOption {
+	return func(c *Client) {
+		c.rawFailoverURLs = append(c.rawFailoverURLs, urls...)
+	}
+}
+
+// WithoutRedirects fails requests bitbucket redirects rather than following
+// the redirect, e.g. to notice a base URL that no longer matches the server.
+func WithoutRedirects() ClientOption {
+	return func(c *Client) {
+		c.client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+}
+
+// WithoutPing skips the request NewClient sends to check connectivity, for
+// credentials that are not allowed to list projects. The first real request
+// then reports connectivity problems instead.
+func WithoutPing() ClientOption {
+	return func(c *Client) {
+		c.skipPing = true
+	}
+}
+
+// Endpoints NewClient can request to check connectivity and the credentials
+const (
+	// PingProjects lists projects, which requires permission to browse a project
+	PingProjects = "projects"
+	// PingUsers lists users, which any authenticated user may
+	PingUsers = "users"
+	// PingApplicationProperties gets the version of the server, which only
+	// fails for invalid credentials
+	PingApplicationProperties = "application-properties"
+)
+
+// WithPingEndpoint sets the endpoint NewClient requests to check connectivity,
+// e.g. PingUsers for credentials that may not list projects. Empty keeps
+// PingProjects.
+func WithPingEndpoint(endpoint string) ClientOption {
+	return func(c *Client) {
+		if endpoint != "" {
+			c.pingEndpoint = endpoint
+		}
+	}
 }
 
 var (
@@ -43,12 +242,20 @@ var (
 	ErrResponseMalformed = errors.New("response_malformed")
 	// ErrConflict is used when a duplicate resource is trying to be created
 	ErrConflict = errors.New("conflict")
+	// ErrResponseTooLarge is used when a response body exceeds the configured maximum size
+	ErrResponseTooLarge = errors.New("response_too_large")
+	// ErrTooManyPages is used when a paged api returns more pages than the client is willing to fetch
+	ErrTooManyPages = errors.New("too_many_pages")
+	// ErrUnsupported is used when the bitbucket server does not provide the requested feature
+	ErrUnsupported = errors.New("unsupported")
+	// ErrMaintenance is used when the bitbucket server is in maintenance mode. The request is
+	// expected to succeed once the maintenance is over and should be retried with a backoff.
+	ErrMaintenance = errors.New("maintenance")
 )
 
 // NewClient creates a new instance of the bitbucket client
-func NewClient(baseURL string, base64creds string, caCertPath *string) (*Client, error) {
-	baseURL = strings.TrimRight(baseURL, "/")
-	pBaseURL, err := url.Parse(fmt.Sprintf("%s%s", baseURL, apiPath))
+func NewClient(baseURL string, base64creds string, caCertPath *string, opts ...ClientOption) (*Client, error) {
+	pBaseURL, err := apiURL(baseURL)
 	if err != nil {
 		return nil, err
 	}
@@ -57,8 +264,35 @@ func NewClient(baseURL string, base64creds string, caCertPath *string) (*Client,
 
 	c := &Client{
 		baseURL: pBaseURL,
-		client:  &http.Client{Timeout: time.Second * 10, Transport: transport},
-		headers: map[string]string{"Authorization": fmt.Sprintf("Bearer %s", base64creds)},
+		client:  &http.Client{Timeout: time.Second * 10, Transport: transport, CheckRedirect: keepMethodOnRedirect},
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", base64creds),
+			"User-Agent":    version.UserAgent(),
+		},
+
+		maxResponseSize:    defaultMaxResponseSize,
+		maxPages:           defaultMaxPages,
+		connectRetries:     defaultConnectRetries,
+		connectBackoff:     defaultConnectBackoff,
+		retryBudgetRetries: defaultRetryBudget,
+		retryBudgetWindow:  defaultRetryBudgetWindow,
+		pingEndpoint:       PingProjects,
+		pingTimeout:        defaultPingTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.retryBudget = sharedRetryBudget(c.baseURL.Host, c.retryBudgetRetries, c.retryBudgetWindow)
+	for _, raw := range c.rawFailoverURLs {
+		u, err := apiURL(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid failover url %q: %w", raw, err)
+		}
+		c.failoverURLs = append(c.failoverURLs, u)
+	}
+	if c.skipPing {
+		return c, nil
 	}
 
 	err = c.ping()
@@ -69,6 +303,41 @@ func NewClient(baseURL string, base64creds string, caCertPath *string) (*Client,
 	return c, nil
 }
 
+// keepMethodOnRedirect follows redirects keeping the method and body of the
+// request. The http client otherwise turns e.g. a POST redirected with 301,
+// as some setups do to add a trailing slash, into a GET without a body. A 303
+// See Other asks for the result to be fetched with a GET, so it is followed
+// as one.
+func keepMethodOnRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.Response != nil && req.Response.StatusCode == http.StatusSeeOther {
+		return nil
+	}
+	prev := via[len(via)-1]
+	if req.Method == prev.Method {
+		return nil
+	}
+	req.Method = prev.Method
+	if prev.GetBody != nil {
+		body, err := prev.GetBody()
+		if err != nil {
+			return err
+		}
+		req.Body, req.GetBody, req.ContentLength = body, prev.GetBody, prev.ContentLength
+	}
+	if contentType := prev.Header.Get("Content-Type"); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return nil
+}
+
+// apiURL returns the url of the core api of the bitbucket at baseURL
+func apiURL(baseURL string) (*url.URL, error) {
+	return url.Parse(fmt.Sprintf("%s%s", strings.TrimRight(baseURL, "/"), apiPath))
+}
+
 func createTransport(caCertPath *string) *http.Transport {
 	rootCAs, _ := x509.SystemCertPool()
 	if rootCAs == nil {
@@ -76,7 +345,7 @@ func createTransport(caCertPath *string) *http.Transport {
 	}
 
 	if caCertPath == nil || *caCertPath == "" {
-		return &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}
+		return &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}}
 	}
 
 	_, err := os.Stat(*caCertPath)
@@ -92,32 +361,142 @@ func createTransport(caCertPath *string) *http.Transport {
 		fmt.Printf("'%s' does not exist\n", *caCertPath)
 	}
 
-	return &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}
+	return &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}}
+}
+
+// transport returns the transport of the underlying http client
+func (c *Client) transport() *http.Transport {
+	return c.client.Transport.(*http.Transport)
 }
 
 // ping is used to check that the client can correctly communicate with the bitbucket api
 func (c *Client) ping() error {
-	req, err := c.newRequest("GET", "projects", nil)
+	req, err := c.newRequest("GET", pathWithQuery(c.pingEndpoint, url.Values{"limit": {"1"}}), nil)
 	if err != nil {
-		return fmt.Errorf("error creating request for getting projects: %w", err)
+		return fmt.Errorf("error creating request for getting %s: %w", c.pingEndpoint, err)
 	}
 
-	err = c.do(context.Background(), req, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), c.pingTimeout)
+	defer cancel()
+	err = c.do(ctx, req, nil)
 	if err != nil {
-		return fmt.Errorf("error fetching projects at %s: %w", req.URL.String(), err)
+		return fmt.Errorf("error fetching %s at %s: %w", c.pingEndpoint, req.URL.String(), err)
 	}
 	return nil
 }
 
+// VerifyAccess checks that the credentials can administer the repositories of
+// the project, which managing their group permissions requires. Listing the
+// group permissions of the project requires project admin permission.
+func (c *Client) VerifyAccess(ctx context.Context, projectKey string) error {
+	req, err := c.newRequest(http.MethodGet, pathWithQuery(fmt.Sprintf("projects/%s/permissions/groups", projectKey), url.Values{"limit": {"1"}}), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for verifying access: %w", err)
+	}
+	err = c.do(ctx, req, nil)
+	if errors.Is(err, ErrPermission) {
+		return fmt.Errorf("the credentials cannot administer repositories of project %s, grant them admin permission on the project: %w", projectKey, err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("project %s does not exist or is not visible to the credentials: %w", projectKey, err)
+	}
+	if err != nil {
+		return fmt.Errorf("error verifying access to project %s: %w", projectKey, err)
+	}
+	return nil
+}
+
+// VerifyScopes checks that the effective permissions of the credentials, the
+// permissions of the user limited by the scopes of an access token, allow
+// administering the repository: REPO_ADMIN on the repository or PROJECT_ADMIN
+// on its project, as creating it requires. ErrUnsupported is returned for a
+// server without access tokens, whose listings may ignore the permission
+// filters and so cannot verify the scopes.
+func (c *Client) VerifyScopes(ctx context.Context, projectKey string, repository string) error {
+	if err := c.requireFeature(ctx, "verifying the scopes of the credentials", minVersionTokenScopes); err != nil {
+		return fmt.Errorf("error verifying the scopes of the credentials: %w", err)
+	}
+	admin, err := c.hasPermission(ctx, pathWithQuery("repos", url.Values{
+		"projectkey": {projectKey},
+		"name":       {repository},
+		"permission": {"REPO_ADMIN"},
+	}), func(entry permissionEntry) bool {
+		return strings.EqualFold(entry.Project.Key, projectKey) && strings.EqualFold(entry.Name, repository)
+	})
+	if err == nil && !admin {
+		admin, err = c.hasPermission(ctx, pathWithQuery("projects", url.Values{
+			"permission": {"PROJECT_ADMIN"},
+		}), func(entry permissionEntry) bool {
+			return strings.EqualFold(entry.Key, projectKey)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("error verifying the scopes of the credentials: %w", err)
+	}
+	if !admin {
+		return fmt.Errorf("the credentials or the scopes of their access token grant neither REPO_ADMIN on repository %s/%s nor PROJECT_ADMIN on project %s: %w", projectKey, repository, projectKey, ErrPermission)
+	}
+	return nil
+}
+
+// permissionEntry is a repository or project of a listing filtered by permission
+type permissionEntry struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
+
+// hasPermission reports whether any entry of the paged listing matches
+func (c *Client) hasPermission(ctx context.Context, path string, match func(permissionEntry) bool) (bool, error) {
+	found := false
+	err := c.getPaged(ctx, path, func(values json.RawMessage) error {
+		var entries []permissionEntry
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			found = found || match(entry)
+		}
+		return nil
+	})
+	return found, err
+}
+
+// restAPIPath returns the path of a resource in one of the bitbucket rest apis
+// other than the core api, e.g. "mirroring/1.0". The path is relative to the
+// core api so servers running under a context path are supported.
+func restAPIPath(api string, path string) string {
+	return fmt.Sprintf("../../%s/%s", api, path)
+}
+
+// webPath returns the path of a resource served outside the rest apis, e.g.
+// the repository sizes, relative to the core api
+func webPath(path string) string {
+	return "../../../" + path
+}
+
+// pathWithQuery appends the encoded query parameters to path
+func pathWithQuery(path string, query url.Values) string {
+	return path + "?" + query.Encode()
+}
+
 func (c *Client) newRequest(method string, path string, body interface{}) (*http.Request, error) {
 	u, err := c.baseURL.Parse(path)
 	if err != nil {
 		return nil, err
 	}
+	// empty path segments, e.g. of a base URL ending in a slash, are
+	// redirected by some setups
+	u.Path = collapseSlashes(u.Path)
+	if u.RawPath != "" {
+		u.RawPath = collapseSlashes(u.RawPath)
+	}
 
 	var req *http.Request
 	switch method {
-	case http.MethodGet:
+	case http.MethodGet, http.MethodHead:
 		req, err = http.NewRequest(method, u.String(), nil)
 		if err != nil {
 			return nil, err
@@ -125,7 +504,11 @@ func (c *Client) newRequest(method string, path string, body interface{}) (*http
 	default:
 		buf := new(bytes.Buffer)
 		if body != nil {
-			err = json.NewEncoder(buf).Encode(body)
+			// send e.g. markdown in descriptions as written rather than
+			// escaping <, > and &
+			enc := json.NewEncoder(buf)
+			enc.SetEscapeHTML(false)
+			err = enc.Encode(body)
 			if err != nil {
 				return nil, err
 			}
@@ -148,52 +531,311 @@ func (c *Client) newRequest(method string, path string, body interface{}) (*http
 	return req, nil
 }
 
+// collapseSlashes replaces every run of slashes in the path by a single one
+func collapseSlashes(path string) string {
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	return path
+}
+
 // do makes an HTTP request and populates the given struct v from the response.
 func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) error {
 	req = req.WithContext(ctx)
-	res, err := c.client.Do(req)
+	res, err := c.send(req)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
+	if err := decompress(res); err != nil {
+		return err
+	}
 	return c.handleResponse(res, v)
 }
 
+// send sends the request, retrying with backoff while it fails to connect to
+// bitbucket and the retry budget lasts. Requests that reached bitbucket are
+// not retried, whatever their response status.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	backoff := c.connectBackoff
+	for retry := 0; ; retry++ {
+		res, err := c.sendFailover(req)
+		if err == nil {
+			observeRateLimit(res)
+			return res, nil
+		}
+		if retry >= c.connectRetries || !connectionError(req, err) {
+			return res, err
+		}
+		if !c.retryBudget.take() {
+			return nil, fmt.Errorf("retry budget exhausted, not retrying: %w", err)
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// sendFailover sends the request to the current endpoint, moving on to the
+// next endpoint while the request fails to connect. Once every endpoint has
+// been tried the last error is returned.
+func (c *Client) sendFailover(req *http.Request) (*http.Response, error) {
+	endpoints := len(c.failoverURLs) + 1
+	start := int(atomic.LoadInt32(&c.endpoint))
+	var err error
+	for i := 0; i < endpoints; i++ {
+		endpoint := (start + i) % endpoints
+		attempt, aerr := c.onEndpoint(req, endpoint)
+		if aerr != nil {
+			return nil, aerr
+		}
+		var res *http.Response
+		res, err = c.client.Do(attempt)
+		if err == nil {
+			atomic.StoreInt32(&c.endpoint, int32(endpoint))
+			return res, nil
+		}
+		if !connectionError(req, err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// onEndpoint returns a copy of the request, built against baseURL, addressed
+// to the endpoint and with a fresh body so it can be sent again
+func (c *Client) onEndpoint(req *http.Request, endpoint int) (*http.Request, error) {
+	attempt := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attempt.Body = body
+	}
+	if endpoint == 0 {
+		return attempt, nil
+	}
+
+	// the path below the context path of baseURL is kept, e.g. for other rest apis
+	to := c.failoverURLs[endpoint-1]
+	u := *req.URL
+	u.Scheme, u.Host, u.User = to.Scheme, to.Host, to.User
+	u.Path = contextPath(to) + strings.TrimPrefix(u.Path, contextPath(c.baseURL))
+	if u.RawPath != "" {
+		u.RawPath = contextPath(to) + strings.TrimPrefix(u.RawPath, contextPath(c.baseURL))
+	}
+	attempt.URL = &u
+	attempt.Host = u.Host
+	return attempt, nil
+}
+
+// contextPath returns the path bitbucket is served under, "" at the root
+func contextPath(api *url.URL) string {
+	return strings.TrimSuffix(api.Path, apiPath)
+}
+
+// connectionError reports whether the request failed to connect to bitbucket
+// rather than after bitbucket received it. A reset connection may have
+// delivered the request, so only idempotent requests are retried for it.
+func connectionError(req *http.Request, err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+			return true
+		}
+	}
+	return false
+}
+
+// gzipBody closes both the gzip reader and the response body it reads
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (b gzipBody) Close() error {
+	_ = b.Reader.Close()
+	return b.body.Close()
+}
+
+// decompress replaces a gzip encoded response body by its decoded content.
+// The transport only does so itself when it asked for gzip, not when the
+// Accept-Encoding header is set explicitly.
+func decompress(res *http.Response) error {
+	if !strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+	gz, err := gzip.NewReader(res.Body)
+	if errors.Is(err, io.EOF) {
+		// an empty body is not a gzip stream
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s returned a malformed gzip body: %w", res.Request.URL, ErrResponseMalformed)
+	}
+	res.Body = gzipBody{Reader: gz, body: res.Body}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	return nil
+}
+
+// page is a single page of a paged api response
+type page struct {
+	Values        json.RawMessage `json:"values"`
+	IsLastPage    bool            `json:"isLastPage"`
+	NextPageStart int             `json:"nextPageStart"`
+}
+
+// getPaged fetches all pages of the paged api at path and calls fn with the
+// values of each page. Paging stops at the last page, when ctx is cancelled or
+// when more than maxPages pages have been fetched.
+func (c *Client) getPaged(ctx context.Context, path string, fn func(values json.RawMessage) error) error {
+	u, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	query.Set("limit", strconv.Itoa(pageLimit))
+
+	start := 0
+	for i := 0; i < c.maxPages; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		query.Set("start", strconv.Itoa(start))
+		u.RawQuery = query.Encode()
+		req, err := c.newRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		var p page
+		if err := c.do(ctx, req, &p); err != nil {
+			return err
+		}
+		// the page of an empty listing, e.g. of a project without
+		// repositories, may omit the values
+		if len(p.Values) > 0 {
+			if err := fn(p.Values); err != nil {
+				return err
+			}
+		}
+		if p.IsLastPage {
+			return nil
+		}
+		start = p.NextPageStart
+	}
+
+	return fmt.Errorf("%s has more than %d pages: %w", path, c.maxPages, ErrTooManyPages)
+}
+
 // handleResponse makes an HTTP request and populates the given struct v from
 // the response.  This is meant for internal testing and shouldn't be used
 // directly. Instead please use `Client.do`.
 func (c *Client) handleResponse(res *http.Response, v interface{}) error {
-	out, err := io.ReadAll(res.Body)
+	// read one byte past the limit so an oversized body can be told apart from one of exactly the limit
+	out, err := io.ReadAll(io.LimitReader(res.Body, c.maxResponseSize+1))
 	if err != nil {
 		return err
 	}
+	if int64(len(out)) > c.maxResponseSize {
+		return fmt.Errorf("%s exceeded %d bytes: %w", res.Request.URL, c.maxResponseSize, ErrResponseTooLarge)
+	}
 
 	switch res.StatusCode {
 	case 404:
 		return ErrNotFound
-	case 401:
+	case 401, 403:
 		return ErrPermission
 	case 409:
 		return ErrConflict
+	case 503:
+		if isMaintenance(out) {
+			return fmt.Errorf("%s: %w", res.Request.URL, ErrMaintenance)
+		}
 	}
 
 	if res.StatusCode >= http.StatusBadRequest {
 		return fmt.Errorf("%s returned %d", res.Request.URL, res.StatusCode)
 	}
 
+	// a redirect that was not followed, see WithoutRedirects
+	if location := res.Header.Get("Location"); location != "" && res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%s redirected to %s with %d, update the base URL", res.Request.URL, location, res.StatusCode)
+	}
+
 	// this means we don't care about unmarshaling the response body into v
 	if v == nil || res.StatusCode == http.StatusNoContent {
 		return nil
 	}
 
+	// some endpoints answer a success without a body, which leaves v as is
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil
+	}
+
+	// e.g. the login page of a proxy in front of bitbucket
+	if contentType := res.Header.Get("Content-Type"); !isJSON(contentType) {
+		return fmt.Errorf("%s returned non-json %s response %q: %w", res.Request.URL, contentType, bodySnippet(out), ErrResponseMalformed)
+	}
+
 	err = json.Unmarshal(out, &v)
 	if err != nil {
 		var jsonErr *json.SyntaxError
 		if errors.As(err, &jsonErr) {
-			return ErrResponseMalformed
+			// e.g. an html error page of a proxy in front of bitbucket
+			return fmt.Errorf("%s returned %s body %q: %w", res.Request.URL, res.Header.Get("Content-Type"), bodySnippet(out), ErrResponseMalformed)
 		}
 		return err
 	}
 
 	return nil
 }
+
+// isJSON reports whether a Content-Type header is a json media type
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == jsonMediaType || strings.HasSuffix(mediaType, "+json"))
+}
+
+// bodySnippet returns the start of a response body for error messages
+func bodySnippet(body []byte) string {
+	if len(body) <= maxBodySnippet {
+		return string(body)
+	}
+	return string(body[:maxBodySnippet]) + "..."
+}
+
+// errorResponse is the body of a bitbucket error response
+type errorResponse struct {
+	Errors []struct {
+		Message       string `json:"message"`
+		ExceptionName string `json:"exceptionName"`
+	} `json:"errors"`
+}
+
+// isMaintenance reports whether the body of a 503 response is bitbucket
+// reporting that it is in maintenance mode, e.g. during a backup or migration,
+// rather than the server being unavailable for other reasons
+func isMaintenance(body []byte) bool {
+	var res errorResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return false
+	}
+	for _, e := range res.Errors {
+		if strings.Contains(strings.ToLower(e.ExceptionName+" "+e.Message), "maintenance") {
+			return true
+		}
+	}
+	return false
+}
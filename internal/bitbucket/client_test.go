@@ -0,0 +1,1277 @@
+package bitbucket
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/MrVinkel/provider-bitbucketserver/internal/version"
+)
+
+// newTestClient returns a client talking to a test server serving handler.
+// The ping is skipped so tests only see the requests they make themselves.
+func newTestClient(t *testing.T, handler http.Handler, opts ...ClientOption) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL + apiPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{
+		baseURL:         u,
+		client:          srv.Client(),
+		headers:         map[string]string{},
+		maxResponseSize: defaultMaxResponseSize,
+		maxPages:        defaultMaxPages,
+	}
+	c.client.CheckRedirect = keepMethodOnRedirect
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	type args struct {
+		body string
+		opts []ClientOption
+	}
+
+	type want struct {
+		name string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"BelowLimit": {
+			reason: "A body smaller than the limit should be decoded",
+			args: args{
+				body: `{"name":"repo"}`,
+				opts: []ClientOption{WithMaxResponseSize(64)},
+			},
+			want: want{name: "repo"},
+		},
+		"ExactlyLimit": {
+			reason: "A body of exactly the limit should be decoded",
+			args: args{
+				body: `{"name":"repo"}`,
+				opts: []ClientOption{WithMaxResponseSize(int64(len(`{"name":"repo"}`)))},
+			},
+			want: want{name: "repo"},
+		},
+		"AboveLimit": {
+			reason: "A body larger than the limit should be rejected",
+			args: args{
+				body: `{"name":"` + strings.Repeat("a", 128) + `"}`,
+				opts: []ClientOption{WithMaxResponseSize(64)},
+			},
+			want: want{err: ErrResponseTooLarge},
+		},
+		"DefaultLimit": {
+			reason: "Without an option the default limit should be enforced",
+			args: args{
+				body: `{"name":"` + strings.Repeat("a", int(defaultMaxResponseSize)) + `"}`,
+			},
+			want: want{err: ErrResponseTooLarge},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(tc.args.body))
+			}), tc.args.opts...)
+
+			req, err := c.newRequest(http.MethodGet, "projects", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := struct {
+				Name string `json:"name"`
+			}{}
+			err = c.do(context.Background(), req, &got)
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.name, got.Name); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// pagedHandler serves values as a paged api, pageSize values per page
+func pagedHandler(values []string, pageSize int, requests *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		start := 0
+		_, _ = fmt.Sscan(r.URL.Query().Get("start"), &start)
+		end := start + pageSize
+		if end > len(values) {
+			end = len(values)
+		}
+		w.Header().Set("Content-Type", jsonMediaType)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"values":        values[start:end],
+			"isLastPage":    end == len(values),
+			"nextPageStart": end,
+		})
+	}
+}
+
+// maintenanceBody is the response bitbucket serves while in maintenance mode
+const maintenanceBody = `{"errors":[{"context":null,"message":"Bitbucket is currently unavailable because it is in maintenance mode. Please try again later.","exceptionName":"com.atlassian.bitbucket.maintenance.MaintenanceModeException"}]}`
+
+func TestMaintenance(t *testing.T) {
+	type want struct {
+		failed      bool
+		maintenance bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		status int
+		body   string
+		want   want
+	}{
+		"Maintenance": {
+			reason: "A 503 with a maintenance payload should be reported as maintenance",
+			status: http.StatusServiceUnavailable,
+			body:   maintenanceBody,
+			want:   want{failed: true, maintenance: true},
+		},
+		"Unavailable": {
+			reason: "A 503 without a maintenance payload should be reported as a plain error",
+			status: http.StatusServiceUnavailable,
+			body:   `<html>Service Unavailable</html>`,
+			want:   want{failed: true},
+		},
+		"MaintenanceMessageOtherStatus": {
+			reason: "A maintenance payload with any other status should not be reported as maintenance",
+			status: http.StatusInternalServerError,
+			body:   maintenanceBody,
+			want:   want{failed: true},
+		},
+		"OK": {
+			reason: "A successful response should not be reported as maintenance",
+			status: http.StatusOK,
+			body:   `{}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			req, err := c.newRequest(http.MethodGet, "projects", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.do(context.Background(), req, nil)
+			got := want{failed: err != nil, maintenance: errors.Is(err, ErrMaintenance)}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want, +got:\n%s\n%v", tc.reason, diff, err)
+			}
+		})
+	}
+}
+
+func TestResponseMalformed(t *testing.T) {
+	body := `<html><head><title>502 Bad Gateway</title></head><body>` + strings.Repeat("proxy error ", 50) + `</body></html>`
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonMediaType)
+		_, _ = w.Write([]byte(body))
+	}))
+
+	req, err := c.newRequest(http.MethodGet, "projects", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v map[string]interface{}
+	err = c.do(context.Background(), req, &v)
+	if !errors.Is(err, ErrResponseMalformed) {
+		t.Fatalf("c.do(...): want ErrResponseMalformed, got %v", err)
+	}
+	for _, want := range []string{jsonMediaType, "<title>502 Bad Gateway</title>", "..."} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("c.do(...): want error containing %q, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "</html>") {
+		t.Errorf("c.do(...): want body truncated, got %v", err)
+	}
+}
+
+func TestEmptyBody(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		header string
+		body   string
+	}{
+		"Empty": {
+			reason: "A success without a body should leave the target as is",
+		},
+		"EmptyJSON": {
+			reason: "A json success without a body should leave the target as is",
+			header: jsonMediaType,
+		},
+		"Whitespace": {
+			reason: "A success with only whitespace should leave the target as is",
+			header: jsonMediaType,
+			body:   "\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.header != "" {
+					w.Header().Set("Content-Type", tc.header)
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			req, err := c.newRequest(http.MethodGet, "projects/PRJ", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := Project{}
+			if err := c.do(context.Background(), req, &got); err != nil {
+				t.Fatalf("\n%s\nc.do(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(Project{}, got); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGzip(t *testing.T) {
+	type want struct {
+		project Project
+		err     error
+	}
+	gzipped := func(body string) []byte {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		_, _ = gz.Write([]byte(body))
+		_ = gz.Close()
+		return buf.Bytes()
+	}
+
+	cases := map[string]struct {
+		reason string
+		body   []byte
+		want   want
+	}{
+		"Gzip": {
+			reason: "A gzip encoded json response should be decompressed before decoding",
+			body:   gzipped(`{"key":"PRJ","name":"Project"}`),
+			want:   want{project: Project{Key: "PRJ", Name: "Project"}},
+		},
+		"Malformed": {
+			reason: "A response that claims to be gzip encoded but is not should be malformed",
+			body:   []byte(`{"key":"PRJ"}`),
+			want:   want{err: ErrResponseMalformed},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(tc.body)
+			}))
+			// the transport leaves the body encoded when gzip is asked for explicitly
+			c.headers["Accept-Encoding"] = "gzip"
+			req, err := c.newRequest(http.MethodGet, "projects/PRJ", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := want{}
+			got.err = c.do(context.Background(), req, &got.project)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	type want struct {
+		malformed bool
+		contains  string
+	}
+
+	cases := map[string]struct {
+		reason      string
+		contentType string
+		body        string
+		want        want
+	}{
+		"JSON": {
+			reason:      "A json response should be decoded",
+			contentType: jsonMediaType,
+			body:        `{}`,
+		},
+		"JSONWithCharset": {
+			reason:      "A json response with parameters should be decoded",
+			contentType: "application/json;charset=UTF-8",
+			body:        `{}`,
+		},
+		"HTML": {
+			reason:      "An html success response should be reported as non-json with the url",
+			contentType: "text/html; charset=utf-8",
+			body:        `<html><body>Log in</body></html>`,
+			want:        want{malformed: true, contains: "/rest/api/1.0/projects returned non-json text/html; charset=utf-8 response"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			req, err := c.newRequest(http.MethodGet, "projects", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var v map[string]interface{}
+			err = c.do(context.Background(), req, &v)
+			if diff := cmp.Diff(tc.want.malformed, errors.Is(err, ErrResponseMalformed)); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want malformed, +got malformed:\n%s\n%v", tc.reason, diff, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), tc.want.contains) {
+				t.Errorf("\n%s\nc.do(...): want error containing %q, got %v", tc.reason, tc.want.contains, err)
+			}
+		})
+	}
+}
+
+func TestGetPaged(t *testing.T) {
+	type args struct {
+		values   []string
+		pageSize int
+		maxPages int
+		cancel   bool
+	}
+
+	type want struct {
+		values   []string
+		requests int
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"SinglePage": {
+			reason: "All values of a single page should be returned",
+			args:   args{values: []string{"a", "b"}, pageSize: 10, maxPages: 10},
+			want:   want{values: []string{"a", "b"}, requests: 1},
+		},
+		"MultiplePages": {
+			reason: "Values of all pages should be returned in order",
+			args:   args{values: []string{"a", "b", "c", "d", "e"}, pageSize: 2, maxPages: 10},
+			want:   want{values: []string{"a", "b", "c", "d", "e"}, requests: 3},
+		},
+		"Cancelled": {
+			reason: "Paging should stop when the context is cancelled",
+			args:   args{values: []string{"a", "b", "c", "d"}, pageSize: 2, maxPages: 10, cancel: true},
+			want:   want{values: []string{"a", "b"}, requests: 1, err: context.Canceled},
+		},
+		"TooManyPages": {
+			reason: "Paging should stop after the maximum number of pages",
+			args:   args{values: []string{"a", "b", "c", "d", "e"}, pageSize: 1, maxPages: 3},
+			want:   want{values: []string{"a", "b", "c"}, requests: 3, err: ErrTooManyPages},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			requests := 0
+			c := newTestClient(t, pagedHandler(tc.args.values, tc.args.pageSize, &requests))
+			c.maxPages = tc.args.maxPages
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			got := []string{}
+			err := c.getPaged(ctx, "projects", func(values json.RawMessage) error {
+				var page []string
+				if err := json.Unmarshal(values, &page); err != nil {
+					return err
+				}
+				got = append(got, page...)
+				if tc.args.cancel {
+					cancel()
+				}
+				return nil
+			})
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.getPaged(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.values, got); diff != "" {
+				t.Errorf("\n%s\nc.getPaged(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.requests, requests); diff != "" {
+				t.Errorf("\n%s\nc.getPaged(...): -want requests, +got requests:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []ClientOption
+		want   string
+	}{
+		"Configured": {
+			reason: "The configured language should be sent",
+			opts:   []ClientOption{WithAcceptLanguage("en-US")},
+			want:   "en-US",
+		},
+		"Unset": {
+			reason: "No language should be sent when unset so the server default applies",
+		},
+		"Empty": {
+			reason: "No language should be sent when configured empty",
+			opts:   []ClientOption{WithAcceptLanguage("")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Accept-Language")
+				w.WriteHeader(http.StatusNoContent)
+			}), tc.opts...)
+
+			req, err := c.newRequest(http.MethodGet, "projects", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.do(context.Background(), req, nil); err != nil {
+				t.Fatalf("c.do(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nAccept-Language: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	got := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	// NewClient pings the server, which is enough to observe the header
+	if _, err := NewClient(srv.URL, "creds", nil); err != nil {
+		t.Fatalf("NewClient(...): %v", err)
+	}
+	if diff := cmp.Diff(version.UserAgent(), got); diff != "" {
+		t.Errorf("User-Agent: -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestWithoutPing(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []ClientOption
+		want   []string
+	}{
+		"Ping": {
+			reason: "NewClient should ping the server by default",
+			want:   []string{"GET " + apiPath + "projects"},
+		},
+		"WithoutPing": {
+			reason: "NewClient should not send any request when the ping is disabled",
+			opts:   []ClientOption{WithoutPing()},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = append(got, r.Method+" "+r.URL.Path)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(srv.Close)
+
+			if _, err := NewClient(srv.URL, "creds", nil, tc.opts...); err != nil {
+				t.Fatalf("NewClient(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nNewClient(...): -want requests, +got requests:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithPingEndpoint(t *testing.T) {
+	type want struct {
+		requests []string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []ClientOption
+		want   want
+	}{
+		"Projects": {
+			reason: "Credentials that may not list projects should fail the default ping",
+			want: want{
+				requests: []string{"GET " + apiPath + "projects?limit=1"},
+				err:      ErrPermission,
+			},
+		},
+		"Users": {
+			reason: "The ping should list users when configured to",
+			opts:   []ClientOption{WithPingEndpoint(PingUsers)},
+			want:   want{requests: []string{"GET " + apiPath + "users?limit=1"}},
+		},
+		"ApplicationProperties": {
+			reason: "The ping should get the application properties when configured to",
+			opts:   []ClientOption{WithPingEndpoint(PingApplicationProperties)},
+			want:   want{requests: []string{"GET " + apiPath + "application-properties?limit=1"}},
+		},
+		"Default": {
+			reason: "An empty endpoint should keep listing projects",
+			opts:   []ClientOption{WithPingEndpoint("")},
+			want: want{
+				requests: []string{"GET " + apiPath + "projects?limit=1"},
+				err:      ErrPermission,
+			},
+		},
+		"WithoutPing": {
+			reason: "Disabling the ping should win over the endpoint",
+			opts:   []ClientOption{WithPingEndpoint(PingUsers), WithoutPing()},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.requests = append(got.requests, r.Method+" "+r.URL.RequestURI())
+				if r.URL.Path == apiPath+"projects" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			t.Cleanup(srv.Close)
+
+			_, got.err = NewClient(srv.URL, "creds", nil, tc.opts...)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewClient(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// stubDialer records the addresses it is asked to dial and connects to them directly
+type stubDialer struct {
+	addrs []string
+}
+
+func (d *stubDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.addrs = append(d.addrs, addr)
+	return (&net.Dialer{}).DialContext(ctx, network, addr)
+}
+
+func TestWithProxyDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	dialer := &stubDialer{}
+	if _, err := NewClient(srv.URL, "creds", nil, WithProxyDialer(dialer)); err != nil {
+		t.Fatalf("NewClient(...): %v", err)
+	}
+	if diff := cmp.Diff([]string{srv.Listener.Addr().String()}, dialer.addrs); diff != "" {
+		t.Errorf("WithProxyDialer(...): -want dialed, +got dialed:\n%s\n", diff)
+	}
+}
+
+func TestTLSOptions(t *testing.T) {
+	type want struct {
+		minVersion   uint16
+		cipherSuites []uint16
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []ClientOption
+		want   want
+	}{
+		"Default": {
+			reason: "TLS 1.2 should be the default minimum version",
+			want:   want{minVersion: tls.VersionTLS12},
+		},
+		"MinVersion": {
+			reason: "The configured minimum version should be applied to the transport",
+			opts:   []ClientOption{WithTLSMinVersion(tls.VersionTLS13)},
+			want:   want{minVersion: tls.VersionTLS13},
+		},
+		"CipherSuites": {
+			reason: "The configured cipher suites should be applied to the transport",
+			opts:   []ClientOption{WithTLSCipherSuites([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384})},
+			want: want{
+				minVersion:   tls.VersionTLS12,
+				cipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &Client{client: &http.Client{Transport: createTransport(nil)}}
+			for _, opt := range tc.opts {
+				opt(c)
+			}
+			cfg := c.transport().TLSClientConfig
+			got := want{minVersion: cfg.MinVersion, cipherSuites: cfg.CipherSuites}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ntls config: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPingTimeout(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// send the headers in time, then stall sending the body
+		w.Header().Set("Content-Type", jsonMediaType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"values":[`))
+		w.(http.Flusher).Flush()
+		<-release
+	}), WithResponseHeaderTimeout(time.Second))
+	t.Cleanup(func() { close(release) })
+	c.pingEndpoint = PingProjects
+	c.pingTimeout = 100 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() { done <- c.ping() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("c.ping(): want a deadline exceeded error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("c.ping(): still reading a stalled response without an overall request timeout")
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	type want struct {
+		failed  bool
+		timeout bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []ClientOption
+		delay  time.Duration
+		want   want
+	}{
+		"Default": {
+			reason: "A request should succeed without timeouts configured",
+			delay:  50 * time.Millisecond,
+		},
+		"DialTimeout": {
+			reason: "A connection that cannot be established in time should fail",
+			opts:   []ClientOption{WithDialTimeout(time.Nanosecond)},
+			want:   want{failed: true, timeout: true},
+		},
+		"ResponseHeaderTimeout": {
+			reason: "A response that does not arrive in time should fail",
+			opts:   []ClientOption{WithResponseHeaderTimeout(10 * time.Millisecond)},
+			delay:  200 * time.Millisecond,
+			want:   want{failed: true, timeout: true},
+		},
+		"SlowResponseWithinTimeout": {
+			reason: "A slow response within the response header timeout should succeed",
+			opts:   []ClientOption{WithDialTimeout(time.Second), WithResponseHeaderTimeout(time.Second)},
+			delay:  50 * time.Millisecond,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(tc.delay)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(srv.Close)
+
+			u, err := url.Parse(srv.URL + apiPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Client{
+				baseURL:         u,
+				client:          &http.Client{Timeout: 10 * time.Second, Transport: createTransport(nil)},
+				headers:         map[string]string{},
+				maxResponseSize: defaultMaxResponseSize,
+			}
+			for _, opt := range tc.opts {
+				opt(c)
+			}
+
+			req, err := c.newRequest(http.MethodGet, "projects", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.do(context.Background(), req, nil)
+
+			var netErr net.Error
+			got := want{failed: err != nil, timeout: errors.As(err, &netErr) && netErr.Timeout()}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want, +got:\n%s\n%v", tc.reason, diff, err)
+			}
+		})
+	}
+}
+
+// selfSignedCertificate returns a self signed client certificate and key
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "provider-bitbucketserver"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestClientCertificate(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		withCert bool
+		wantErr  bool
+	}{
+		"WithCertificate": {
+			reason:   "The client certificate should be presented to a server requiring one",
+			withCert: true,
+		},
+		"WithoutCertificate": {
+			reason:  "A server requiring a client certificate should refuse the connection without one",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			presented := 0
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				presented = len(r.TLS.PeerCertificates)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+			srv.StartTLS()
+			t.Cleanup(srv.Close)
+
+			u, err := url.Parse(srv.URL + apiPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Client{baseURL: u, client: srv.Client(), headers: map[string]string{}, maxResponseSize: defaultMaxResponseSize}
+			if tc.withCert {
+				WithClientCertificate(selfSignedCertificate(t))(c)
+			}
+
+			req, err := c.newRequest(http.MethodGet, "projects", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.do(context.Background(), req, nil)
+			if diff := cmp.Diff(tc.wantErr, err != nil); diff != "" {
+				t.Errorf("\n%s\nc.do(...): -want error, +got error:\n%s\n%v", tc.reason, diff, err)
+			}
+			if tc.withCert && presented != 1 {
+				t.Errorf("\n%s\nc.do(...): want 1 presented certificate, got %d", tc.reason, presented)
+			}
+		})
+	}
+}
+
+func TestVerifyAccess(t *testing.T) {
+	type want struct {
+		err  error
+		path string
+	}
+
+	cases := map[string]struct {
+		reason string
+		status int
+		want   want
+	}{
+		"ProjectAdmin": {
+			reason: "Credentials that may list the project permissions should have sufficient access",
+			status: http.StatusOK,
+			want:   want{path: apiPath + "projects/PRJ/permissions/groups"},
+		},
+		"Forbidden": {
+			reason: "Credentials that may not list the project permissions should lack access",
+			status: http.StatusForbidden,
+			want:   want{err: ErrPermission, path: apiPath + "projects/PRJ/permissions/groups"},
+		},
+		"ProjectNotFound": {
+			reason: "A project the credentials cannot see should be reported as not found",
+			status: http.StatusNotFound,
+			want:   want{err: ErrNotFound, path: apiPath + "projects/PRJ/permissions/groups"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.path = r.URL.Path
+				w.Header().Set("Content-Type", jsonMediaType)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(`{"values":[],"isLastPage":true}`))
+			}))
+
+			got.err = c.VerifyAccess(context.Background(), "PRJ")
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nVerifyAccess(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestVerifyScopes(t *testing.T) {
+	type want struct {
+		err      error
+		requests []string
+	}
+	properties := apiPath + "application-properties"
+	repos := apiPath + "repos"
+	projects := apiPath + "projects"
+
+	cases := map[string]struct {
+		reason   string
+		version  string
+		repos    string
+		projects string
+		status   int
+		want     want
+	}{
+		"RepoAdmin": {
+			reason:  "Credentials administering the repository should have sufficient scopes",
+			version: "8.9.2",
+			repos:   `{"values":[{"name":"repo","project":{"key":"PRJ"}}],"isLastPage":true}`,
+			want:    want{requests: []string{properties, repos}},
+		},
+		"ProjectAdmin": {
+			reason:   "Credentials administering the project should have sufficient scopes for a repository yet to be created",
+			version:  "8.9.2",
+			repos:    `{"values":[],"isLastPage":true}`,
+			projects: `{"values":[{"key":"OTHER"},{"key":"PRJ"}],"isLastPage":true}`,
+			want:     want{requests: []string{properties, repos, projects}},
+		},
+		"Insufficient": {
+			reason:   "Credentials administering neither the repository nor the project should lack scopes",
+			version:  "8.9.2",
+			repos:    `{"values":[{"name":"repo-2","project":{"key":"PRJ"}}],"isLastPage":true}`,
+			projects: `{"values":[{"key":"OTHER"}],"isLastPage":true}`,
+			want:     want{err: ErrPermission, requests: []string{properties, repos, projects}},
+		},
+		"Unsupported": {
+			reason:  "A server without access tokens may ignore the permission filters, so the scopes should not be taken as verified",
+			version: "5.4.0",
+			want:    want{err: ErrUnsupported, requests: []string{properties}},
+		},
+		"Error": {
+			reason:  "Errors listing the repositories should be returned rather than taken as verified",
+			version: "8.9.2",
+			status:  http.StatusNotFound,
+			want:    want{err: ErrNotFound, requests: []string{properties, repos}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.requests = append(got.requests, r.URL.Path)
+				w.Header().Set("Content-Type", jsonMediaType)
+				if r.URL.Path == properties {
+					_, _ = fmt.Fprintf(w, `{"version":%q}`, tc.version)
+					return
+				}
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+					return
+				}
+				if r.URL.Path == repos {
+					if r.URL.Query().Get("permission") != "REPO_ADMIN" || r.URL.Query().Get("projectkey") != "PRJ" {
+						t.Errorf("unexpected repository filter %s", r.URL.RawQuery)
+					}
+					_, _ = w.Write([]byte(tc.repos))
+					return
+				}
+				if r.URL.Query().Get("permission") != "PROJECT_ADMIN" {
+					t.Errorf("unexpected project filter %s", r.URL.RawQuery)
+				}
+				_, _ = w.Write([]byte(tc.projects))
+			}))
+
+			got.err = c.VerifyScopes(context.Background(), "PRJ", "repo")
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nVerifyScopes(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectRetry(t *testing.T) {
+	type want struct {
+		failed   bool
+		dials    int
+		requests int
+	}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	cases := map[string]struct {
+		reason   string
+		method   string
+		failures int
+		dialErr  error
+		status   int
+		want     want
+	}{
+		"RefusedThenUp": {
+			reason:   "A refused connection should be retried until bitbucket accepts it",
+			method:   http.MethodPost,
+			failures: 2,
+			dialErr:  refused,
+			status:   http.StatusOK,
+			want:     want{dials: 3, requests: 1},
+		},
+		"DNSThenUp": {
+			reason:   "A failed name resolution should be retried",
+			method:   http.MethodGet,
+			failures: 1,
+			dialErr:  &net.DNSError{Err: "no such host", Name: "bitbucket", IsNotFound: true},
+			status:   http.StatusOK,
+			want:     want{dials: 2, requests: 1},
+		},
+		"StillDown": {
+			reason:   "Retrying should give up after the configured number of retries",
+			method:   http.MethodGet,
+			failures: 10,
+			dialErr:  refused,
+			want:     want{failed: true, dials: 3},
+		},
+		"HTTPError": {
+			reason: "A request bitbucket answered with an error status should not be retried",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			want:   want{failed: true, dials: 1, requests: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.requests++
+				w.WriteHeader(tc.status)
+			}))
+			c.connectRetries = 2
+			c.connectBackoff = time.Millisecond
+			// every request dials as keep alive is disabled
+			c.transport().DisableKeepAlives = true
+			dial := (&net.Dialer{}).DialContext
+			c.transport().DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				got.dials++
+				if got.dials <= tc.failures {
+					return nil, tc.dialErr
+				}
+				return dial(ctx, network, addr)
+			}
+
+			req, err := c.newRequest(tc.method, "projects", map[string]string{"name": "repo"})
+			if err != nil {
+				t.Fatalf("\n%s\nnewRequest(...): %v", tc.reason, err)
+			}
+			got.failed = c.do(context.Background(), req, nil) != nil
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ndo(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	type want struct {
+		err         bool
+		method      string
+		body        string
+		contentType string
+	}
+
+	cases := map[string]struct {
+		reason string
+		method string
+		status int
+		opts   []ClientOption
+		want   want
+	}{
+		"Get": {
+			reason: "A redirected GET should be followed",
+			method: http.MethodGet,
+			status: http.StatusMovedPermanently,
+			want:   want{method: http.MethodGet},
+		},
+		"Post": {
+			reason: "A POST redirected with 301 should keep its method and body",
+			method: http.MethodPost,
+			status: http.StatusMovedPermanently,
+			want:   want{method: http.MethodPost, body: `{"name":"repo"}`, contentType: jsonMediaType},
+		},
+		"PostFound": {
+			reason: "A POST redirected with 302 should keep its method and body",
+			method: http.MethodPost,
+			status: http.StatusFound,
+			want:   want{method: http.MethodPost, body: `{"name":"repo"}`, contentType: jsonMediaType},
+		},
+		"Put": {
+			reason: "A PUT redirected with 308 should keep its method and body",
+			method: http.MethodPut,
+			status: http.StatusPermanentRedirect,
+			want:   want{method: http.MethodPut, body: `{"name":"repo"}`, contentType: jsonMediaType},
+		},
+		"SeeOther": {
+			reason: "A POST redirected with 303 should be followed as a GET without a body",
+			method: http.MethodPost,
+			status: http.StatusSeeOther,
+			want:   want{method: http.MethodGet},
+		},
+		"Disabled": {
+			reason: "A redirect should fail the request when redirects are disabled",
+			method: http.MethodPost,
+			status: http.StatusMovedPermanently,
+			opts:   []ClientOption{WithoutRedirects()},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// redirect to the path with a trailing slash, as some setups do
+				if !strings.HasSuffix(r.URL.Path, "/") {
+					http.Redirect(w, r, r.URL.Path+"/", tc.status)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				got.method, got.body, got.contentType = r.Method, strings.TrimSpace(string(body)), r.Header.Get("Content-Type")
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(`{}`))
+			}), tc.opts...)
+
+			var body interface{}
+			if tc.method != http.MethodGet {
+				body = map[string]string{"name": "repo"}
+			}
+			req, err := c.newRequest(tc.method, "projects/PRJ/repos", body)
+			if err != nil {
+				t.Fatalf("newRequest(...): %v", err)
+			}
+			got.err = c.do(context.Background(), req, nil) != nil
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ndo(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewRequestPath(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		path   string
+		want   string
+	}{
+		"Plain": {
+			reason: "A path below the api should be kept",
+			path:   "projects/PRJ/repos",
+			want:   apiPath + "projects/PRJ/repos",
+		},
+		"EmptySegments": {
+			reason: "Empty path segments should be collapsed to avoid redirects",
+			path:   "projects//PRJ///repos",
+			want:   apiPath + "projects/PRJ/repos",
+		},
+	}
+
+	c := newTestClient(t, http.NotFoundHandler())
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, err := c.newRequest(http.MethodGet, tc.path, nil)
+			if err != nil {
+				t.Fatalf("newRequest(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, req.URL.Path); diff != "" {
+				t.Errorf("\n%s\nnewRequest(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFailover(t *testing.T) {
+	type want struct {
+		failed bool
+		paths  []string
+		bodies []string
+	}
+
+	cases := map[string]struct {
+		reason        string
+		secondaryDown bool
+		requests      []string
+		want          want
+	}{
+		"PrimaryDown": {
+			reason:   "Requests should be sent to the secondary while the primary refuses connections",
+			requests: []string{"projects", restAPIPath("mirroring/1.0", "mirrorServers")},
+			want: want{
+				paths:  []string{"/bitbucket/rest/api/1.0/projects", "/bitbucket/rest/mirroring/1.0/mirrorServers"},
+				bodies: []string{`{"name":"repo"}`, `{"name":"repo"}`},
+			},
+		},
+		"AllDown": {
+			reason:        "The request should fail once no endpoint can be connected to",
+			secondaryDown: true,
+			requests:      []string{"projects"},
+			want:          want{failed: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				got.paths = append(got.paths, r.URL.Path)
+				got.bodies = append(got.bodies, strings.TrimSpace(string(body)))
+			}))
+			secondary, err := apiURL(strings.TrimSuffix(c.baseURL.String(), apiPath) + "/bitbucket")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.secondaryDown {
+				secondary, _ = apiURL(closedServerURL(t))
+			}
+			c.baseURL, _ = apiURL(closedServerURL(t))
+			c.failoverURLs = []*url.URL{secondary}
+
+			for _, path := range tc.requests {
+				req, err := c.newRequest(http.MethodPost, path, map[string]string{"name": "repo"})
+				if err != nil {
+					t.Fatalf("\n%s\nnewRequest(...): %v", tc.reason, err)
+				}
+				if err := c.do(context.Background(), req, nil); err != nil {
+					got.failed = true
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ndo(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// closedServerURL returns the url of a server no longer accepting connections
+func closedServerURL(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
@@ -0,0 +1,42 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const defaultReviewersAPI = "default-reviewers/1.0"
+
+// GetRequiredApprovals returns the highest number of approvals from default
+// reviewers any default reviewer condition of the repository requires, 0
+// without conditions. The server answers not found when default reviewers
+// are disabled, which is reported as no conditions.
+func (service *repositoryService) GetRequiredApprovals(ctx context.Context, repository *Repository) (int, error) {
+	url := restAPIPath(defaultReviewersAPI, fmt.Sprintf("projects/%s/repos/%s/conditions", repository.Project, repository.Name))
+	req, err := service.client.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request for getting repository default reviewers: %w", err)
+	}
+
+	// the conditions are not paged
+	var conditions []struct {
+		RequiredApprovals int `json:"requiredApprovals"`
+	}
+	err = service.client.do(ctx, req, &conditions)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error getting repository default reviewers: %w", err)
+	}
+
+	required := 0
+	for _, condition := range conditions {
+		if condition.RequiredApprovals > required {
+			required = condition.RequiredApprovals
+		}
+	}
+	return required, nil
+}
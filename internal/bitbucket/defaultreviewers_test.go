@@ -0,0 +1,66 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestGetRequiredApprovals(t *testing.T) {
+	type want struct {
+		required int
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		status int
+		body   string
+		want   want
+	}{
+		"Conditions": {
+			reason: "The highest number of required approvals of the conditions should be returned",
+			status: http.StatusOK,
+			body:   `[{"id":1,"requiredApprovals":1},{"id":2,"requiredApprovals":2}]`,
+			want:   want{required: 2},
+		},
+		"NoConditions": {
+			reason: "A repository without conditions should require no approvals",
+			status: http.StatusOK,
+			body:   `[]`,
+		},
+		"Disabled": {
+			reason: "Default reviewers being unavailable should be reported as no approvals",
+			status: http.StatusNotFound,
+		},
+		"Forbidden": {
+			reason: "Other errors should be returned",
+			status: http.StatusForbidden,
+			want:   want{err: ErrPermission},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/rest/default-reviewers/1.0/projects/PRJ/repos/repo/conditions" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", jsonMediaType)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			service := &repositoryService{client: c}
+			required, err := service.GetRequiredApprovals(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+			got := want{required: required, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nGetRequiredApprovals(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ETagCache caches GET responses together with their etag, so resources that
+// did not change are not transferred again. A cache outlives the clients
+// using it and is safe for concurrent use.
+type ETagCache struct {
+	mu      sync.Mutex
+	entries map[string]etagEntry
+}
+
+type etagEntry struct {
+	etag string
+	body []byte
+}
+
+// NewETagCache returns an empty ETagCache
+func NewETagCache() *ETagCache {
+	return &ETagCache{entries: map[string]etagEntry{}}
+}
+
+func (e *ETagCache) get(key string) (etagEntry, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entry, ok := e.entries[key]
+	return entry, ok
+}
+
+func (e *ETagCache) put(key string, entry etagEntry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries[key] = entry
+}
+
+func (e *ETagCache) forget(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.entries, key)
+}
+
+// WithETagCache sends conditional requests for the responses cached in cache
+// and reuses the cached response when bitbucket reports it unchanged
+func WithETagCache(cache *ETagCache) ClientOption {
+	return func(c *Client) {
+		c.etags = cache
+	}
+}
+
+type uncachedKey struct{}
+
+// WithoutCache returns a context whose requests bypass the etag cache. The
+// cached responses are dropped and replaced by the fresh ones.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uncachedKey{}, true)
+}
+
+// doCached is do for GET requests of resources worth caching. Without an
+// etag cache it is the same as do.
+func (c *Client) doCached(ctx context.Context, req *http.Request, v interface{}) error {
+	if c.etags == nil {
+		return c.do(ctx, req, v)
+	}
+
+	key := req.URL.String()
+	if uncached, _ := ctx.Value(uncachedKey{}).(bool); uncached {
+		c.etags.forget(key)
+	}
+	entry, cached := c.etags.get(key)
+	if cached {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+
+	req = req.WithContext(ctx)
+	res, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := decompress(res); err != nil {
+		c.etags.forget(key)
+		return err
+	}
+
+	if res.StatusCode == http.StatusNotModified && cached {
+		return json.Unmarshal(entry.body, v)
+	}
+
+	// keep the body to cache it, reading as much as handleResponse would
+	out, err := io.ReadAll(io.LimitReader(res.Body, c.maxResponseSize+1))
+	if err != nil {
+		return err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(out))
+	if err := c.handleResponse(res, v); err != nil {
+		c.etags.forget(key)
+		return err
+	}
+
+	if etag := res.Header.Get("ETag"); etag != "" {
+		c.etags.put(key, etagEntry{etag: etag, body: out})
+	} else {
+		c.etags.forget(key)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestETagCache(t *testing.T) {
+	type want struct {
+		repos    []string
+		notMod   int
+		requests int
+	}
+
+	// the description of the repository changes between the third and fourth get
+	description := "v1"
+	got := want{}
+	cache := NewETagCache()
+	srv := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.requests++
+		etag := `"` + description + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			got.notMod++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", jsonMediaType)
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(`{"id":1,"name":"repo","slug":"repo","project":{"key":"PRJ"},"description":"` + description + `"}`))
+	}))
+	// a new client per get sharing the cache, as the controller connects on every reconcile
+	newClient := func() *Client {
+		c := &Client{baseURL: srv.baseURL, client: srv.client, headers: map[string]string{}, maxResponseSize: defaultMaxResponseSize, maxPages: defaultMaxPages}
+		WithETagCache(cache)(c)
+		return c
+	}
+
+	for i := 0; i < 4; i++ {
+		if i == 3 {
+			description = "v2"
+		}
+		service := &repositoryService{client: newClient()}
+		repo, err := service.Get(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+		if err != nil {
+			t.Fatalf("Get(...): %v", err)
+		}
+		got.repos = append(got.repos, repo.Description)
+	}
+
+	// the second and third get reuse the cached repository
+	if diff := cmp.Diff(want{repos: []string{"v1", "v1", "v1", "v2"}, notMod: 2, requests: 4}, got, cmp.AllowUnexported(want{})); diff != "" {
+		t.Errorf("Get(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestWithoutCache(t *testing.T) {
+	var conditional []bool
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match") != "")
+		w.Header().Set("Content-Type", jsonMediaType)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"id":1,"name":"repo","slug":"repo","project":{"key":"PRJ"}}`))
+	}), WithETagCache(NewETagCache()))
+	service := &repositoryService{client: c}
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		WithoutCache(context.Background()),
+		context.Background(),
+	} {
+		if _, err := service.Get(ctx, &Repository{Name: "repo", Project: "PRJ"}); err != nil {
+			t.Fatalf("Get(...): %v", err)
+		}
+	}
+
+	// the forced get is not conditional, the response is cached again after it
+	if diff := cmp.Diff([]bool{false, false, true}, conditional); diff != "" {
+		t.Errorf("Get(...): -want conditional requests, +got conditional requests:\n%s\n", diff)
+	}
+}
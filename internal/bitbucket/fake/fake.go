@@ -0,0 +1,361 @@
+// Package fake contains fake implementations of the bitbucket services for
+// use in tests.
+package fake
+
+import (
+	"context"
+
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+var _ bitbucket.RepositoryService = &MockRepositoryService{}
+
+// MockRepositoryService is a fake bitbucket.RepositoryService whose behaviour
+// is controlled by its Mock functions.
+type MockRepositoryService struct {
+	MockGet                func(ctx context.Context, repository *bitbucket.Repository) (*bitbucket.Repository, error)
+	MockExists             func(ctx context.Context, repository *bitbucket.Repository) (bool, error)
+	MockList               func(ctx context.Context, project string) ([]bitbucket.Repository, error)
+	MockCreate             func(ctx context.Context, repository *bitbucket.Repository) (*bitbucket.Repository, error)
+	MockUpdate             func(ctx context.Context, repository *bitbucket.Repository) (*bitbucket.Repository, error)
+	MockDelete             func(ctx context.Context, repository *bitbucket.Repository) error
+	MockMove               func(ctx context.Context, repository *bitbucket.Repository, project string) (*bitbucket.Repository, error)
+	MockGetGroups          func(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.Group, error)
+	MockGetGroupPermission func(ctx context.Context, repository *bitbucket.Repository, group string) (string, error)
+	MockAddGroup           func(ctx context.Context, repository *bitbucket.Repository, group *bitbucket.Group) error
+	MockRevokeGroup        func(ctx context.Context, repository *bitbucket.Repository, group *bitbucket.Group) error
+
+	MockGetUsers func(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.UserPermission, error)
+
+	MockGetMirrorServers   func(ctx context.Context, repository *bitbucket.Repository) ([]string, error)
+	MockAddMirrorServer    func(ctx context.Context, repository *bitbucket.Repository, mirrorID string) error
+	MockRemoveMirrorServer func(ctx context.Context, repository *bitbucket.Repository, mirrorID string) error
+
+	MockGetPullRequestTemplate func(ctx context.Context, repository *bitbucket.Repository) (string, error)
+	MockSetPullRequestTemplate func(ctx context.Context, repository *bitbucket.Repository, template string) error
+
+	MockGetDefaultMergeStrategy func(ctx context.Context, repository *bitbucket.Repository) (string, error)
+	MockSetDefaultMergeStrategy func(ctx context.Context, repository *bitbucket.Repository, strategy string) error
+
+	MockGetDefaultBranch func(ctx context.Context, repository *bitbucket.Repository) (string, error)
+	MockSetDefaultBranch func(ctx context.Context, repository *bitbucket.Repository, ref string) error
+	MockGetBranches      func(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.Branch, error)
+	MockCreateBranch     func(ctx context.Context, repository *bitbucket.Repository, name string, startPoint string) error
+	MockDeleteBranch     func(ctx context.Context, repository *bitbucket.Repository, ref string) error
+	MockGetRestrictions  func(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.Restriction, error)
+	MockAddRestriction   func(ctx context.Context, repository *bitbucket.Repository, restriction *bitbucket.Restriction) error
+
+	MockGetEnabledHooks func(ctx context.Context, repository *bitbucket.Repository) ([]string, error)
+	MockEnableHook      func(ctx context.Context, repository *bitbucket.Repository, key string) error
+	MockDisableHook     func(ctx context.Context, repository *bitbucket.Repository, key string) error
+
+	MockGetRequiredBuilds   func(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.RequiredBuild, error)
+	MockAddRequiredBuild    func(ctx context.Context, repository *bitbucket.Repository, build *bitbucket.RequiredBuild) error
+	MockUpdateRequiredBuild func(ctx context.Context, repository *bitbucket.Repository, build *bitbucket.RequiredBuild) error
+	MockDeleteRequiredBuild func(ctx context.Context, repository *bitbucket.Repository, id int) error
+
+	MockCountOpenPullRequests func(ctx context.Context, repository *bitbucket.Repository) (int, error)
+	MockGetRequiredApprovals  func(ctx context.Context, repository *bitbucket.Repository) (int, error)
+
+	MockGetForkSyncEnabled func(ctx context.Context, repository *bitbucket.Repository) (bool, error)
+	MockSetForkSyncEnabled func(ctx context.Context, repository *bitbucket.Repository, enabled bool) error
+
+	MockGetLFSEnabled func(ctx context.Context, repository *bitbucket.Repository) (bool, error)
+	MockSetLFSEnabled func(ctx context.Context, repository *bitbucket.Repository, enabled bool) error
+
+	MockGetSize    func(ctx context.Context, repository *bitbucket.Repository) (int64, error)
+	MockHasCommits func(ctx context.Context, repository *bitbucket.Repository) (bool, error)
+}
+
+// Get calls MockGet
+func (m *MockRepositoryService) Get(ctx context.Context, repository *bitbucket.Repository) (*bitbucket.Repository, error) {
+	return m.MockGet(ctx, repository)
+}
+
+// Exists calls MockExists
+func (m *MockRepositoryService) Exists(ctx context.Context, repository *bitbucket.Repository) (bool, error) {
+	return m.MockExists(ctx, repository)
+}
+
+// List calls MockList
+func (m *MockRepositoryService) List(ctx context.Context, project string) ([]bitbucket.Repository, error) {
+	return m.MockList(ctx, project)
+}
+
+// Create calls MockCreate
+func (m *MockRepositoryService) Create(ctx context.Context, repository *bitbucket.Repository) (*bitbucket.Repository, error) {
+	return m.MockCreate(ctx, repository)
+}
+
+// Update calls MockUpdate
+func (m *MockRepositoryService) Update(ctx context.Context, repository *bitbucket.Repository) (*bitbucket.Repository, error) {
+	return m.MockUpdate(ctx, repository)
+}
+
+// Delete calls MockDelete
+func (m *MockRepositoryService) Delete(ctx context.Context, repository *bitbucket.Repository) error {
+	return m.MockDelete(ctx, repository)
+}
+
+// Move calls MockMove
+func (m *MockRepositoryService) Move(ctx context.Context, repository *bitbucket.Repository, project string) (*bitbucket.Repository, error) {
+	return m.MockMove(ctx, repository, project)
+}
+
+// GetGroups calls MockGetGroups
+func (m *MockRepositoryService) GetGroups(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.Group, error) {
+	return m.MockGetGroups(ctx, repository)
+}
+
+// GetGroupPermission calls MockGetGroupPermission
+func (m *MockRepositoryService) GetGroupPermission(ctx context.Context, repository *bitbucket.Repository, group string) (string, error) {
+	return m.MockGetGroupPermission(ctx, repository, group)
+}
+
+// GetUsers calls MockGetUsers
+func (m *MockRepositoryService) GetUsers(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.UserPermission, error) {
+	return m.MockGetUsers(ctx, repository)
+}
+
+// AddGroup calls MockAddGroup
+func (m *MockRepositoryService) AddGroup(ctx context.Context, repository *bitbucket.Repository, group *bitbucket.Group) error {
+	return m.MockAddGroup(ctx, repository, group)
+}
+
+// RevokeGroup calls MockRevokeGroup
+func (m *MockRepositoryService) RevokeGroup(ctx context.Context, repository *bitbucket.Repository, group *bitbucket.Group) error {
+	return m.MockRevokeGroup(ctx, repository, group)
+}
+
+// GetMirrorServers calls MockGetMirrorServers
+func (m *MockRepositoryService) GetMirrorServers(ctx context.Context, repository *bitbucket.Repository) ([]string, error) {
+	return m.MockGetMirrorServers(ctx, repository)
+}
+
+// AddMirrorServer calls MockAddMirrorServer
+func (m *MockRepositoryService) AddMirrorServer(ctx context.Context, repository *bitbucket.Repository, mirrorID string) error {
+	return m.MockAddMirrorServer(ctx, repository, mirrorID)
+}
+
+// RemoveMirrorServer calls MockRemoveMirrorServer
+func (m *MockRepositoryService) RemoveMirrorServer(ctx context.Context, repository *bitbucket.Repository, mirrorID string) error {
+	return m.MockRemoveMirrorServer(ctx, repository, mirrorID)
+}
+
+// GetPullRequestTemplate calls MockGetPullRequestTemplate
+func (m *MockRepositoryService) GetPullRequestTemplate(ctx context.Context, repository *bitbucket.Repository) (string, error) {
+	return m.MockGetPullRequestTemplate(ctx, repository)
+}
+
+// SetPullRequestTemplate calls MockSetPullRequestTemplate
+func (m *MockRepositoryService) SetPullRequestTemplate(ctx context.Context, repository *bitbucket.Repository, template string) error {
+	return m.MockSetPullRequestTemplate(ctx, repository, template)
+}
+
+// GetDefaultMergeStrategy calls MockGetDefaultMergeStrategy
+func (m *MockRepositoryService) GetDefaultMergeStrategy(ctx context.Context, repository *bitbucket.Repository) (string, error) {
+	return m.MockGetDefaultMergeStrategy(ctx, repository)
+}
+
+// SetDefaultMergeStrategy calls MockSetDefaultMergeStrategy
+func (m *MockRepositoryService) SetDefaultMergeStrategy(ctx context.Context, repository *bitbucket.Repository, strategy string) error {
+	return m.MockSetDefaultMergeStrategy(ctx, repository, strategy)
+}
+
+// GetDefaultBranch calls MockGetDefaultBranch
+func (m *MockRepositoryService) GetDefaultBranch(ctx context.Context, repository *bitbucket.Repository) (string, error) {
+	return m.MockGetDefaultBranch(ctx, repository)
+}
+
+// SetDefaultBranch calls MockSetDefaultBranch
+func (m *MockRepositoryService) SetDefaultBranch(ctx context.Context, repository *bitbucket.Repository, ref string) error {
+	return m.MockSetDefaultBranch(ctx, repository, ref)
+}
+
+// GetBranches calls MockGetBranches
+func (m *MockRepositoryService) GetBranches(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.Branch, error) {
+	return m.MockGetBranches(ctx, repository)
+}
+
+// CreateBranch calls MockCreateBranch
+func (m *MockRepositoryService) CreateBranch(ctx context.Context, repository *bitbucket.Repository, name string, startPoint string) error {
+	return m.MockCreateBranch(ctx, repository, name, startPoint)
+}
+
+// DeleteBranch calls MockDeleteBranch
+func (m *MockRepositoryService) DeleteBranch(ctx context.Context, repository *bitbucket.Repository, ref string) error {
+	return m.MockDeleteBranch(ctx, repository, ref)
+}
+
+// GetRestrictions calls MockGetRestrictions
+func (m *MockRepositoryService) GetRestrictions(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.Restriction, error) {
+	return m.MockGetRestrictions(ctx, repository)
+}
+
+// AddRestriction calls MockAddRestriction
+func (m *MockRepositoryService) AddRestriction(ctx context.Context, repository *bitbucket.Repository, restriction *bitbucket.Restriction) error {
+	return m.MockAddRestriction(ctx, repository, restriction)
+}
+
+// GetEnabledHooks calls MockGetEnabledHooks
+func (m *MockRepositoryService) GetEnabledHooks(ctx context.Context, repository *bitbucket.Repository) ([]string, error) {
+	return m.MockGetEnabledHooks(ctx, repository)
+}
+
+// EnableHook calls MockEnableHook
+func (m *MockRepositoryService) EnableHook(ctx context.Context, repository *bitbucket.Repository, key string) error {
+	return m.MockEnableHook(ctx, repository, key)
+}
+
+// DisableHook calls MockDisableHook
+func (m *MockRepositoryService) DisableHook(ctx context.Context, repository *bitbucket.Repository, key string) error {
+	return m.MockDisableHook(ctx, repository, key)
+}
+
+// GetRequiredBuilds calls MockGetRequiredBuilds
+func (m *MockRepositoryService) GetRequiredBuilds(ctx context.Context, repository *bitbucket.Repository) ([]bitbucket.RequiredBuild, error) {
+	return m.MockGetRequiredBuilds(ctx, repository)
+}
+
+// AddRequiredBuild calls MockAddRequiredBuild
+func (m *MockRepositoryService) AddRequiredBuild(ctx context.Context, repository *bitbucket.Repository, build *bitbucket.RequiredBuild) error {
+	return m.MockAddRequiredBuild(ctx, repository, build)
+}
+
+// UpdateRequiredBuild calls MockUpdateRequiredBuild
+func (m *MockRepositoryService) UpdateRequiredBuild(ctx context.Context, repository *bitbucket.Repository, build *bitbucket.RequiredBuild) error {
+	return m.MockUpdateRequiredBuild(ctx, repository, build)
+}
+
+// DeleteRequiredBuild calls MockDeleteRequiredBuild
+func (m *MockRepositoryService) DeleteRequiredBuild(ctx context.Context, repository *bitbucket.Repository, id int) error {
+	return m.MockDeleteRequiredBuild(ctx, repository, id)
+}
+
+// CountOpenPullRequests calls MockCountOpenPullRequests
+func (m *MockRepositoryService) CountOpenPullRequests(ctx context.Context, repository *bitbucket.Repository) (int, error) {
+	return m.MockCountOpenPullRequests(ctx, repository)
+}
+
+// GetRequiredApprovals calls MockGetRequiredApprovals
+func (m *MockRepositoryService) GetRequiredApprovals(ctx context.Context, repository *bitbucket.Repository) (int, error) {
+	return m.MockGetRequiredApprovals(ctx, repository)
+}
+
+// GetForkSyncEnabled calls MockGetForkSyncEnabled
+func (m *MockRepositoryService) GetForkSyncEnabled(ctx context.Context, repository *bitbucket.Repository) (bool, error) {
+	return m.MockGetForkSyncEnabled(ctx, repository)
+}
+
+// SetForkSyncEnabled calls MockSetForkSyncEnabled
+func (m *MockRepositoryService) SetForkSyncEnabled(ctx context.Context, repository *bitbucket.Repository, enabled bool) error {
+	return m.MockSetForkSyncEnabled(ctx, repository, enabled)
+}
+
+// GetLFSEnabled calls MockGetLFSEnabled
+func (m *MockRepositoryService) GetLFSEnabled(ctx context.Context, repository *bitbucket.Repository) (bool, error) {
+	return m.MockGetLFSEnabled(ctx, repository)
+}
+
+// SetLFSEnabled calls MockSetLFSEnabled
+func (m *MockRepositoryService) SetLFSEnabled(ctx context.Context, repository *bitbucket.Repository, enabled bool) error {
+	return m.MockSetLFSEnabled(ctx, repository, enabled)
+}
+
+// GetSize calls MockGetSize
+func (m *MockRepositoryService) GetSize(ctx context.Context, repository *bitbucket.Repository) (int64, error) {
+	return m.MockGetSize(ctx, repository)
+}
+
+// HasCommits calls MockHasCommits
+func (m *MockRepositoryService) HasCommits(ctx context.Context, repository *bitbucket.Repository) (bool, error) {
+	return m.MockHasCommits(ctx, repository)
+}
+
+var _ bitbucket.ServerService = &MockServerService{}
+
+// MockServerService is a fake bitbucket.ServerService whose behaviour is
+// controlled by its Mock functions.
+type MockServerService struct {
+	MockGetServerInfo func(ctx context.Context) (*bitbucket.ServerInfo, error)
+	MockVerifyAccess  func(ctx context.Context, projectKey string) error
+	MockVerifyScopes  func(ctx context.Context, projectKey string, repository string) error
+}
+
+// GetServerInfo calls MockGetServerInfo
+func (m *MockServerService) GetServerInfo(ctx context.Context) (*bitbucket.ServerInfo, error) {
+	return m.MockGetServerInfo(ctx)
+}
+
+var _ bitbucket.ProjectService = &MockProjectService{}
+
+// MockProjectService is a fake bitbucket.ProjectService whose behaviour is
+// controlled by its Mock functions.
+type MockProjectService struct {
+	MockGet    func(ctx context.Context, req *bitbucket.GetProjectRequest) (*bitbucket.Project, error)
+	MockCreate func(ctx context.Context, req *bitbucket.CreateProjectRequest) (*bitbucket.Project, error)
+	MockUpdate func(ctx context.Context, req *bitbucket.UpdateProjectRequest) (*bitbucket.Project, error)
+	MockDelete func(ctx context.Context, req *bitbucket.DeleteProjectRequest) error
+
+	MockGetDefaultPermission func(ctx context.Context, key string) (string, error)
+	MockSetDefaultPermission func(ctx context.Context, key string, permission string) error
+
+	MockGetPermission    func(ctx context.Context, key string, subject bitbucket.PermissionSubject) (string, error)
+	MockGrantPermission  func(ctx context.Context, key string, subject bitbucket.PermissionSubject, permission string) error
+	MockRevokePermission func(ctx context.Context, key string, subject bitbucket.PermissionSubject) error
+}
+
+// Get calls MockGet
+func (m *MockProjectService) Get(ctx context.Context, req *bitbucket.GetProjectRequest) (*bitbucket.Project, error) {
+	return m.MockGet(ctx, req)
+}
+
+// Create calls MockCreate
+func (m *MockProjectService) Create(ctx context.Context, req *bitbucket.CreateProjectRequest) (*bitbucket.Project, error) {
+	return m.MockCreate(ctx, req)
+}
+
+// Update calls MockUpdate
+func (m *MockProjectService) Update(ctx context.Context, req *bitbucket.UpdateProjectRequest) (*bitbucket.Project, error) {
+	return m.MockUpdate(ctx, req)
+}
+
+// Delete calls MockDelete
+func (m *MockProjectService) Delete(ctx context.Context, req *bitbucket.DeleteProjectRequest) error {
+	return m.MockDelete(ctx, req)
+}
+
+// GetDefaultPermission calls MockGetDefaultPermission
+func (m *MockProjectService) GetDefaultPermission(ctx context.Context, key string) (string, error) {
+	return m.MockGetDefaultPermission(ctx, key)
+}
+
+// SetDefaultPermission calls MockSetDefaultPermission
+func (m *MockProjectService) SetDefaultPermission(ctx context.Context, key string, permission string) error {
+	return m.MockSetDefaultPermission(ctx, key, permission)
+}
+
+// GetPermission calls MockGetPermission
+func (m *MockProjectService) GetPermission(ctx context.Context, key string, subject bitbucket.PermissionSubject) (string, error) {
+	return m.MockGetPermission(ctx, key, subject)
+}
+
+// GrantPermission calls MockGrantPermission
+func (m *MockProjectService) GrantPermission(ctx context.Context, key string, subject bitbucket.PermissionSubject, permission string) error {
+	return m.MockGrantPermission(ctx, key, subject, permission)
+}
+
+// RevokePermission calls MockRevokePermission
+func (m *MockProjectService) RevokePermission(ctx context.Context, key string, subject bitbucket.PermissionSubject) error {
+	return m.MockRevokePermission(ctx, key, subject)
+}
+
+// VerifyAccess calls MockVerifyAccess
+func (m *MockServerService) VerifyAccess(ctx context.Context, projectKey string) error {
+	return m.MockVerifyAccess(ctx, projectKey)
+}
+
+// VerifyScopes calls MockVerifyScopes
+func (m *MockServerService) VerifyScopes(ctx context.Context, projectKey string, repository string) error {
+	return m.MockVerifyScopes(ctx, projectKey, repository)
+}
@@ -0,0 +1,73 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// minimum server versions of features not available in every bitbucket
+// version the provider supports
+const (
+	minVersionArchive = "8.0"
+	// access tokens, whose scopes limit the permission filters of the
+	// repository and project listings
+	minVersionTokenScopes = "5.5"
+)
+
+// supportsFeature reports whether the server is at least minVersion, using the
+// cached server version
+func (c *Client) supportsFeature(ctx context.Context, minVersion string) (bool, error) {
+	info, err := c.getServerInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+	return compareVersions(info.Version, minVersion) >= 0, nil
+}
+
+// requireFeature returns an error wrapping ErrUnsupported naming the feature
+// and the version it requires if the server is older than minVersion. This
+// avoids confusing 404s from endpoints the server does not know.
+func (c *Client) requireFeature(ctx context.Context, feature string, minVersion string) error {
+	ok, err := c.supportsFeature(ctx, minVersion)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// the server info is cached by supportsFeature
+		info, _ := c.getServerInfo(ctx)
+		return fmt.Errorf("%s requires bitbucket %s or later, server is %s: %w", feature, minVersion, info.Version, ErrUnsupported)
+	}
+	return nil
+}
+
+// compareVersions compares two dotted versions like 8.9.2 numerically and
+// returns -1, 0 or 1. Missing parts count as 0 and any suffix of a part, e.g.
+// the -rc1 of 8.0.0-rc1, is ignored.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := versionPart(as, i), versionPart(bs, i)
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionPart returns the leading number of the i'th part of a version
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	digits := strings.IndexFunc(parts[i], func(r rune) bool { return r < '0' || r > '9' })
+	if digits == -1 {
+		digits = len(parts[i])
+	}
+	n, _ := strconv.Atoi(parts[i][:digits])
+	return n
+}
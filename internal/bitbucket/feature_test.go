@@ -0,0 +1,68 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := map[string]struct {
+		a, b string
+		want int
+	}{
+		"Equal":          {a: "8.9.2", b: "8.9.2", want: 0},
+		"MissingPart":    {a: "8.0", b: "8.0.0", want: 0},
+		"OlderMinor":     {a: "7.21.10", b: "8.0", want: -1},
+		"NumericNotText": {a: "8.10.0", b: "8.9.0", want: 1},
+		"Suffix":         {a: "8.0.0-rc1", b: "8.0", want: 0},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, compareVersions(tc.a, tc.b)); diff != "" {
+				t.Errorf("compareVersions(%q, %q): -want, +got:\n%s\n", tc.a, tc.b, diff)
+			}
+		})
+	}
+}
+
+func TestRequireFeature(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		version string
+		want    error
+	}{
+		"Supported": {
+			reason:  "A server at the minimum version should support the feature",
+			version: "8.0.0",
+		},
+		"Newer": {
+			reason:  "A newer server should support the feature",
+			version: "8.19.1",
+		},
+		"Unsupported": {
+			reason:  "An older server should not support the feature",
+			version: "7.21.16",
+			want:    ErrUnsupported,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = fmt.Fprintf(w, `{"version":%q,"displayName":"Bitbucket"}`, tc.version)
+			}))
+
+			err := c.requireFeature(context.Background(), "archiving", minVersionArchive)
+			if diff := cmp.Diff(tc.want, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.requireFeature(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const refSyncAPI = "sync/latest"
+
+// forkSyncJson is the ref synchronization state of a fork
+type forkSyncJson struct {
+	Available bool `json:"available,omitempty"`
+	Enabled   bool `json:"enabled"`
+}
+
+// GetForkSyncEnabled reports whether the fork is automatically synchronized
+// with its upstream repository. ErrUnsupported is returned when the server
+// does not offer synchronization for the repository, e.g. as it is no fork.
+func (service *repositoryService) GetForkSyncEnabled(ctx context.Context, repository *Repository) (bool, error) {
+	url := restAPIPath(refSyncAPI, fmt.Sprintf("projects/%s/repos/%s", repository.Project, repository.Name))
+	req, err := service.client.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request for getting repository fork sync: %w", err)
+	}
+
+	var sync forkSyncJson
+	err = service.client.do(ctx, req, &sync)
+	if err != nil {
+		return false, fmt.Errorf("error getting repository fork sync: %w", err)
+	}
+	if !sync.Available {
+		return false, fmt.Errorf("error getting repository fork sync: synchronization is not available for %s/%s: %w", repository.Project, repository.Name, ErrUnsupported)
+	}
+	return sync.Enabled, nil
+}
+
+// SetForkSyncEnabled enables or disables synchronizing the fork with its
+// upstream repository
+func (service *repositoryService) SetForkSyncEnabled(ctx context.Context, repository *Repository, enabled bool) error {
+	url := restAPIPath(refSyncAPI, fmt.Sprintf("projects/%s/repos/%s", repository.Project, repository.Name))
+	req, err := service.client.newRequest(http.MethodPost, url, &forkSyncJson{Enabled: enabled})
+	if err != nil {
+		return fmt.Errorf("error creating request for setting repository fork sync: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error setting repository fork sync: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package bitbucket
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestForkSync(t *testing.T) {
+	type want struct {
+		enabled  bool
+		err      error
+		requests []string
+	}
+	path := "/rest/sync/latest/projects/PRJ/repos/repo"
+
+	cases := map[string]struct {
+		reason string
+		body   string
+		enable bool
+		want   want
+	}{
+		"Enabled": {
+			reason: "The sync state of a fork should be returned",
+			body:   `{"available":true,"enabled":true}`,
+			want:   want{enabled: true, requests: []string{"GET " + path}},
+		},
+		"NotAvailable": {
+			reason: "A repository without synchronization should be unsupported",
+			body:   `{"available":false,"enabled":false}`,
+			want:   want{err: ErrUnsupported, requests: []string{"GET " + path}},
+		},
+		"Set": {
+			reason: "Enabling synchronization should post the enabled state",
+			enable: true,
+			want:   want{requests: []string{`POST ` + path + ` {"enabled":true}`}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				got.requests = append(got.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+strings.TrimSpace(string(b))))
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			service := &repositoryService{client: c}
+			repo := &Repository{Name: "repo", Project: "PRJ"}
+			if tc.enable {
+				got.err = service.SetForkSyncEnabled(context.Background(), repo, true)
+			} else {
+				got.enabled, got.err = service.GetForkSyncEnabled(context.Background(), repo)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nfork sync: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
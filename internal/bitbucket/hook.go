@@ -0,0 +1,62 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GetEnabledHooks returns the keys of the hooks enabled for the repository
+func (service *repositoryService) GetEnabledHooks(ctx context.Context, repository *Repository) ([]string, error) {
+	url := fmt.Sprintf("projects/%s/repos/%s/settings/hooks", repository.Project, repository.Name)
+
+	hooks := []string{}
+	err := service.client.getPaged(ctx, url, func(values json.RawMessage) error {
+		var entries []struct {
+			Details struct {
+				Key string `json:"key"`
+			} `json:"details"`
+			Enabled bool `json:"enabled"`
+		}
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Enabled {
+				hooks = append(hooks, entry.Details.Key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting repository hooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// EnableHook enables the hook with the key, e.g.
+// com.atlassian.bitbucket.server.bitbucket-bundled-hooks:force-push-hook, for the
+// repository with its current settings
+func (service *repositoryService) EnableHook(ctx context.Context, repository *Repository, key string) error {
+	return service.setHookEnabled(ctx, repository, key, http.MethodPut)
+}
+
+// DisableHook disables the hook with the key for the repository
+func (service *repositoryService) DisableHook(ctx context.Context, repository *Repository, key string) error {
+	return service.setHookEnabled(ctx, repository, key, http.MethodDelete)
+}
+
+func (service *repositoryService) setHookEnabled(ctx context.Context, repository *Repository, key string, method string) error {
+	url := fmt.Sprintf("projects/%s/repos/%s/settings/hooks/%s/enabled", repository.Project, repository.Name, key)
+	req, err := service.client.newRequest(method, url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for setting repository hook %s: %w", key, err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error setting repository hook %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHooks(t *testing.T) {
+	hooksPath := apiPath + "projects/PRJ/repos/repo/settings/hooks"
+	// an in memory repository holding the enabled state of its hooks
+	enabled := map[string]bool{"jira": true, "force-push": false}
+	requests := []string{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == hooksPath:
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"values":[` +
+				`{"details":{"key":"force-push"},"enabled":` + strconv.FormatBool(enabled["force-push"]) + `},` +
+				`{"details":{"key":"jira"},"enabled":` + strconv.FormatBool(enabled["jira"]) + `}` +
+				`],"isLastPage":true}`))
+		case r.URL.Path == hooksPath+"/force-push/enabled" || r.URL.Path == hooksPath+"/jira/enabled":
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, hooksPath+"/"), "/enabled")
+			enabled[key] = r.Method == http.MethodPut
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	service := &repositoryService{client: c}
+	repo := &Repository{Name: "repo", Project: "PRJ"}
+
+	get := func() []string {
+		t.Helper()
+		got, err := service.GetEnabledHooks(context.Background(), repo)
+		if err != nil {
+			t.Fatalf("GetEnabledHooks(...): %v", err)
+		}
+		return got
+	}
+
+	if diff := cmp.Diff([]string{"jira"}, get()); diff != "" {
+		t.Errorf("GetEnabledHooks(...): -want, +got:\n%s\n", diff)
+	}
+	if err := service.EnableHook(context.Background(), repo, "force-push"); err != nil {
+		t.Fatalf("EnableHook(...): %v", err)
+	}
+	if err := service.DisableHook(context.Background(), repo, "jira"); err != nil {
+		t.Fatalf("DisableHook(...): %v", err)
+	}
+	if diff := cmp.Diff([]string{"force-push"}, get()); diff != "" {
+		t.Errorf("GetEnabledHooks(...) after enabling and disabling: -want, +got:\n%s\n", diff)
+	}
+	want := []string{"PUT " + hooksPath + "/force-push/enabled", "DELETE " + hooksPath + "/jira/enabled"}
+	if diff := cmp.Diff(want, requests); diff != "" {
+		t.Errorf("hook requests: -want, +got:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,53 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const lfsAPI = "git-lfs/admin"
+
+// GetLFSEnabled reports whether git LFS is enabled for the repository. The
+// server answers not found for a repository without LFS, so a server without
+// LFS reports it as disabled.
+func (service *repositoryService) GetLFSEnabled(ctx context.Context, repository *Repository) (bool, error) {
+	url := restAPIPath(lfsAPI, fmt.Sprintf("projects/%s/repos/%s/enabled", repository.Project, repository.Name))
+	req, err := service.client.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request for getting repository lfs: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting repository lfs: %w", err)
+	}
+	return true, nil
+}
+
+// SetLFSEnabled enables or disables git LFS for the repository.
+// ErrUnsupported is returned if LFS is not available on the server.
+func (service *repositoryService) SetLFSEnabled(ctx context.Context, repository *Repository, enabled bool) error {
+	method := http.MethodPut
+	if !enabled {
+		method = http.MethodDelete
+	}
+	url := restAPIPath(lfsAPI, fmt.Sprintf("projects/%s/repos/%s/enabled", repository.Project, repository.Name))
+	req, err := service.client.newRequest(method, url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for setting repository lfs: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("error setting repository lfs: git lfs %w", ErrUnsupported)
+	}
+	if err != nil {
+		return fmt.Errorf("error setting repository lfs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLFSEnabled(t *testing.T) {
+	type want struct {
+		enabled  bool
+		requests []string
+		err      error
+	}
+	path := "/rest/git-lfs/admin/projects/PRJ/repos/repo/enabled"
+
+	cases := map[string]struct {
+		reason    string
+		installed bool
+		set       bool
+		want      want
+	}{
+		"Enable": {
+			reason:    "Enabling LFS should PUT the enabled resource",
+			installed: true,
+			set:       true,
+			want: want{
+				enabled:  true,
+				requests: []string{"PUT " + path, "GET " + path},
+			},
+		},
+		"Disable": {
+			reason:    "Disabling LFS should DELETE the enabled resource",
+			installed: true,
+			want: want{
+				requests: []string{"DELETE " + path, "GET " + path},
+			},
+		},
+		"Unsupported": {
+			reason: "Enabling LFS on a server without LFS should report LFS as unsupported",
+			set:    true,
+			want: want{
+				requests: []string{"PUT " + path},
+				err:      ErrUnsupported,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// an in memory repository answering not found while LFS is disabled
+			enabled := false
+			requests := []string{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				if !tc.installed || r.URL.Path != path {
+					http.NotFound(w, r)
+					return
+				}
+				switch r.Method {
+				case http.MethodGet:
+					if !enabled {
+						http.NotFound(w, r)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				case http.MethodPut:
+					enabled = true
+					w.WriteHeader(http.StatusNoContent)
+				case http.MethodDelete:
+					enabled = false
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			service := &repositoryService{client: c}
+			repo := &Repository{Name: "repo", Project: "PRJ"}
+
+			got := want{}
+			got.err = service.SetLFSEnabled(context.Background(), repo, tc.set)
+			if got.err == nil {
+				got.enabled, got.err = service.GetLFSEnabled(context.Background(), repo)
+			}
+			got.requests = requests
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nlfs: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
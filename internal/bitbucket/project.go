@@ -2,7 +2,12 @@ package bitbucket
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 // ProjectService provides operations around bitbucket projects
@@ -11,6 +16,44 @@ type ProjectService interface {
 	Create(context.Context, *CreateProjectRequest) (*Project, error)
 	Update(context.Context, *UpdateProjectRequest) (*Project, error)
 	Delete(context.Context, *DeleteProjectRequest) error
+	// Default permission of all logged in users
+	GetDefaultPermission(ctx context.Context, key string) (string, error)
+	SetDefaultPermission(ctx context.Context, key string, permission string) error
+	// Permissions of individual groups and users
+	GetPermission(ctx context.Context, key string, subject PermissionSubject) (string, error)
+	GrantPermission(ctx context.Context, key string, subject PermissionSubject, permission string) error
+	RevokePermission(ctx context.Context, key string, subject PermissionSubject) error
+}
+
+// Default permissions of a project
+const (
+	PermissionProjectRead  = "PROJECT_READ"
+	PermissionProjectWrite = "PROJECT_WRITE"
+	PermissionNone         = "NONE"
+)
+
+// Kinds of subjects project permissions are granted to
+const (
+	SubjectGroup = "group"
+	SubjectUser  = "user"
+)
+
+// PermissionSubject is the group or user a project permission is granted to
+type PermissionSubject struct {
+	// Type is SubjectGroup or SubjectUser
+	Type string
+	Name string
+}
+
+// path returns the path of the permissions of the subject type of a project
+func (s PermissionSubject) path(key string) (string, error) {
+	switch s.Type {
+	case SubjectGroup:
+		return fmt.Sprintf("projects/%s/permissions/groups", key), nil
+	case SubjectUser:
+		return fmt.Sprintf("projects/%s/permissions/users", key), nil
+	}
+	return "", fmt.Errorf("unknown permission subject type %q", s.Type)
 }
 
 type projectService struct {
@@ -110,3 +153,134 @@ func (ps *projectService) Update(ctx context.Context, updateReq *UpdateProjectRe
 
 	return &p, nil
 }
+
+// GetDefaultPermission returns the permission all logged in users have on the
+// project, PermissionNone if they have none
+func (ps *projectService) GetDefaultPermission(ctx context.Context, key string) (string, error) {
+	for _, permission := range []string{PermissionProjectWrite, PermissionProjectRead} {
+		req, err := ps.client.newRequest(http.MethodGet, fmt.Sprintf("projects/%s/permissions/%s/all", key, permission), nil)
+		if err != nil {
+			return "", fmt.Errorf("error creating request for getting project default permission: %w", err)
+		}
+
+		var res struct {
+			Permitted bool `json:"permitted"`
+		}
+		if err := ps.client.do(ctx, req, &res); err != nil {
+			return "", fmt.Errorf("error getting project default permission: %w", err)
+		}
+		if res.Permitted {
+			return permission, nil
+		}
+	}
+	return PermissionNone, nil
+}
+
+// SetDefaultPermission sets the permission all logged in users have on the
+// project. PermissionNone revokes the default permission.
+func (ps *projectService) SetDefaultPermission(ctx context.Context, key string, permission string) error {
+	// write implies read, so revoke write before granting read only
+	grants := map[string][]struct {
+		permission string
+		allow      bool
+	}{
+		PermissionProjectWrite: {{PermissionProjectWrite, true}},
+		PermissionProjectRead:  {{PermissionProjectWrite, false}, {PermissionProjectRead, true}},
+		PermissionNone:         {{PermissionProjectWrite, false}, {PermissionProjectRead, false}},
+	}[permission]
+	if grants == nil {
+		return fmt.Errorf("unknown project default permission %q", permission)
+	}
+
+	for _, g := range grants {
+		path := pathWithQuery(fmt.Sprintf("projects/%s/permissions/%s/all", key, g.permission), url.Values{
+			"allow": {strconv.FormatBool(g.allow)},
+		})
+		req, err := ps.client.newRequest(http.MethodPost, path, nil)
+		if err != nil {
+			return fmt.Errorf("error creating request for setting project default permission: %w", err)
+		}
+		if err := ps.client.do(ctx, req, nil); err != nil {
+			return fmt.Errorf("error setting project default permission: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetPermission returns the permission granted to the subject on the project,
+// empty if none is granted
+func (ps *projectService) GetPermission(ctx context.Context, key string, subject PermissionSubject) (string, error) {
+	path, err := subject.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	// the filter matches substrings, so the names are compared below
+	permission := ""
+	err = ps.client.getPaged(ctx, pathWithQuery(path, url.Values{"filter": {subject.Name}}), func(values json.RawMessage) error {
+		var entries []struct {
+			Group struct {
+				Name string `json:"name"`
+			} `json:"group"`
+			User struct {
+				Name string `json:"name"`
+			} `json:"user"`
+			Permission string `json:"permission"`
+		}
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name := entry.Group.Name
+			if subject.Type == SubjectUser {
+				name = entry.User.Name
+			}
+			if strings.EqualFold(name, subject.Name) {
+				permission = entry.Permission
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting project %s permission: %w", subject.Type, err)
+	}
+	return permission, nil
+}
+
+// GrantPermission grants the permission to the subject on the project,
+// replacing any permission it had
+func (ps *projectService) GrantPermission(ctx context.Context, key string, subject PermissionSubject, permission string) error {
+	path, err := subject.path(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := ps.client.newRequest(http.MethodPut, pathWithQuery(path, url.Values{
+		"name":       {subject.Name},
+		"permission": {permission},
+	}), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for granting project %s permission: %w", subject.Type, err)
+	}
+	if err := ps.client.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("error granting project %s permission: %w", subject.Type, err)
+	}
+	return nil
+}
+
+// RevokePermission revokes all permissions of the subject on the project
+func (ps *projectService) RevokePermission(ctx context.Context, key string, subject PermissionSubject) error {
+	path, err := subject.path(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := ps.client.newRequest(http.MethodDelete, pathWithQuery(path, url.Values{"name": {subject.Name}}), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for revoking project %s permission: %w", subject.Type, err)
+	}
+	if err := ps.client.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("error revoking project %s permission: %w", subject.Type, err)
+	}
+	return nil
+}
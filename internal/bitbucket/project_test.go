@@ -0,0 +1,241 @@
+package bitbucket
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestProjectService(t *testing.T) {
+	type want struct {
+		project *Project
+		request string
+		body    string
+		err     error
+	}
+	project := `{"id":1,"key":"PRJ","name":"Project","description":"desc","type":"NORMAL","public":true}`
+	prj := &Project{ID: 1, Key: "PRJ", Name: "Project", Description: "desc", Type: "NORMAL", Public: true}
+
+	get := func(s ProjectService) (*Project, error) {
+		return s.Get(context.Background(), &GetProjectRequest{Key: "PRJ"})
+	}
+	create := func(s ProjectService) (*Project, error) {
+		return s.Create(context.Background(), &CreateProjectRequest{Name: "Project", Key: "PRJ", Description: "desc", Public: true})
+	}
+	update := func(s ProjectService) (*Project, error) {
+		return s.Update(context.Background(), &UpdateProjectRequest{Key: "PRJ", Description: "desc", Public: true})
+	}
+	del := func(s ProjectService) (*Project, error) {
+		return nil, s.Delete(context.Background(), &DeleteProjectRequest{Key: "PRJ"})
+	}
+
+	cases := map[string]struct {
+		reason string
+		call   func(ProjectService) (*Project, error)
+		status int
+		body   string
+		want   want
+	}{
+		"Get": {
+			reason: "Get should fetch the project by its key",
+			call:   get,
+			status: http.StatusOK,
+			body:   project,
+			want:   want{project: prj, request: "GET /rest/api/1.0/projects/PRJ"},
+		},
+		"GetNotFound": {
+			reason: "Get should map a missing project to ErrNotFound",
+			call:   get,
+			status: http.StatusNotFound,
+			want:   want{request: "GET /rest/api/1.0/projects/PRJ", err: ErrNotFound},
+		},
+		"Create": {
+			reason: "Create should POST the project",
+			call:   create,
+			status: http.StatusCreated,
+			body:   project,
+			want: want{
+				project: prj,
+				request: "POST /rest/api/1.0/projects",
+				body:    `{"name":"Project","key":"PRJ","description":"desc","public":true}`,
+			},
+		},
+		"CreateConflict": {
+			reason: "Create should map an existing project to ErrConflict",
+			call:   create,
+			status: http.StatusConflict,
+			want: want{
+				request: "POST /rest/api/1.0/projects",
+				body:    `{"name":"Project","key":"PRJ","description":"desc","public":true}`,
+				err:     ErrConflict,
+			},
+		},
+		"Update": {
+			reason: "Update should PUT the project to its key",
+			call:   update,
+			status: http.StatusOK,
+			body:   project,
+			want: want{
+				project: prj,
+				request: "PUT /rest/api/1.0/projects/PRJ",
+				body:    `{"key":"PRJ","description":"desc","public":true}`,
+			},
+		},
+		"UpdateNotFound": {
+			reason: "Update should map a missing project to ErrNotFound",
+			call:   update,
+			status: http.StatusNotFound,
+			want: want{
+				request: "PUT /rest/api/1.0/projects/PRJ",
+				body:    `{"key":"PRJ","description":"desc","public":true}`,
+				err:     ErrNotFound,
+			},
+		},
+		"Delete": {
+			reason: "Delete should DELETE the project by its key",
+			call:   del,
+			status: http.StatusNoContent,
+			want:   want{request: "DELETE /rest/api/1.0/projects/PRJ"},
+		},
+		"DeleteConflict": {
+			reason: "Delete should map a project still holding repositories to ErrConflict",
+			call:   del,
+			status: http.StatusConflict,
+			want:   want{request: "DELETE /rest/api/1.0/projects/PRJ", err: ErrConflict},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.request = r.Method + " " + r.URL.Path
+				b, _ := io.ReadAll(r.Body)
+				got.body = strings.TrimSpace(string(b))
+				if tc.body != "" {
+					w.Header().Set("Content-Type", jsonMediaType)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			got.project, got.err = tc.call(&projectService{client: c})
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nProjectService: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultPermission(t *testing.T) {
+	// an in memory project holding the default permissions granted to all users
+	granted := map[string]bool{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := apiPath + "projects/PRJ/permissions/"
+		if !strings.HasPrefix(r.URL.Path, prefix) || !strings.HasSuffix(r.URL.Path, "/all") {
+			http.NotFound(w, r)
+			return
+		}
+		permission := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), "/all")
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", jsonMediaType)
+			// write implies read
+			permitted := granted[permission] || (permission == PermissionProjectRead && granted[PermissionProjectWrite])
+			if permitted {
+				_, _ = w.Write([]byte(`{"permitted":true}`))
+			} else {
+				_, _ = w.Write([]byte(`{"permitted":false}`))
+			}
+		case http.MethodPost:
+			granted[permission] = r.URL.Query().Get("allow") == "true"
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	service := &projectService{client: c}
+
+	for _, want := range []string{PermissionProjectWrite, PermissionProjectRead, PermissionNone, PermissionProjectRead} {
+		if err := service.SetDefaultPermission(context.Background(), "PRJ", want); err != nil {
+			t.Fatalf("SetDefaultPermission(%s): %v", want, err)
+		}
+		got, err := service.GetDefaultPermission(context.Background(), "PRJ")
+		if err != nil {
+			t.Fatalf("GetDefaultPermission(...): %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetDefaultPermission(...) after SetDefaultPermission(%s): -want, +got:\n%s\n", want, diff)
+		}
+	}
+
+	if err := service.SetDefaultPermission(context.Background(), "PRJ", "REPO_ADMIN"); err == nil {
+		t.Errorf("SetDefaultPermission(REPO_ADMIN): want error for unknown permission")
+	}
+}
+
+func TestProjectPermission(t *testing.T) {
+	// an in memory project holding the permissions granted to users, with a
+	// user whose name contains the name of the managed user
+	granted := map[string]string{"alice.smith": "PROJECT_ADMIN"}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != apiPath+"projects/PRJ/permissions/users" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+
+		switch r.Method {
+		case http.MethodGet:
+			values := []string{}
+			for name, permission := range granted {
+				if strings.Contains(name, query.Get("filter")) {
+					values = append(values, `{"user":{"name":"`+name+`"},"permission":"`+permission+`"}`)
+				}
+			}
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"values":[` + strings.Join(values, ",") + `],"isLastPage":true}`))
+		case http.MethodPut:
+			granted[query.Get("name")] = query.Get("permission")
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			delete(granted, query.Get("name"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	service := &projectService{client: c}
+	alice := PermissionSubject{Type: SubjectUser, Name: "alice"}
+
+	get := func() string {
+		t.Helper()
+		got, err := service.GetPermission(context.Background(), "PRJ", alice)
+		if err != nil {
+			t.Fatalf("GetPermission(...): %v", err)
+		}
+		return got
+	}
+
+	if diff := cmp.Diff("", get()); diff != "" {
+		t.Errorf("GetPermission(...) before granting: -want, +got:\n%s\n", diff)
+	}
+	if err := service.GrantPermission(context.Background(), "PRJ", alice, "PROJECT_WRITE"); err != nil {
+		t.Fatalf("GrantPermission(...): %v", err)
+	}
+	if diff := cmp.Diff("PROJECT_WRITE", get()); diff != "" {
+		t.Errorf("GetPermission(...) after granting: -want, +got:\n%s\n", diff)
+	}
+	if err := service.RevokePermission(context.Background(), "PRJ", alice); err != nil {
+		t.Fatalf("RevokePermission(...): %v", err)
+	}
+	if diff := cmp.Diff("", get()); diff != "" {
+		t.Errorf("GetPermission(...) after revoking: -want, +got:\n%s\n", diff)
+	}
+
+	if _, err := service.GetPermission(context.Background(), "PRJ", PermissionSubject{Type: "team", Name: "alice"}); err == nil {
+		t.Errorf("GetPermission(team): want error for unknown subject type")
+	}
+}
@@ -0,0 +1,68 @@
+package bitbucket
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Headers bitbucket reports the rate limit budget of the credentials in, when
+// sent through a gateway or proxy enforcing one
+const (
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+var (
+	rateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bitbucket_rate_limit_remaining",
+		Help: "Requests left in the rate limit budget last reported by bitbucket.",
+	}, []string{"host"})
+	rateLimitReset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bitbucket_rate_limit_reset_timestamp_seconds",
+		Help: "Unix time the rate limit budget last reported by bitbucket is reset at.",
+	}, []string{"host"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(rateLimitRemaining, rateLimitReset)
+}
+
+// RateLimit is the rate limit budget reported in a response
+type RateLimit struct {
+	// Remaining is the number of requests left until the budget is reset
+	Remaining int
+	// Reset is when the budget is reset, zero when not reported
+	Reset time.Time
+}
+
+// parseRateLimit returns the rate limit budget reported in the headers of a
+// response. It reports false when the headers carry no valid budget.
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	remaining, err := strconv.Atoi(h.Get(headerRateLimitRemaining))
+	if err != nil || remaining < 0 {
+		return RateLimit{}, false
+	}
+	rl := RateLimit{Remaining: remaining}
+	if reset, err := strconv.ParseInt(h.Get(headerRateLimitReset), 10, 64); err == nil && reset > 0 {
+		rl.Reset = time.Unix(reset, 0).UTC()
+	}
+	return rl, true
+}
+
+// observeRateLimit records the rate limit budget reported in the response,
+// if any, in the metrics of the host that sent it
+func observeRateLimit(res *http.Response) {
+	rl, ok := parseRateLimit(res.Header)
+	if !ok || res.Request == nil {
+		return
+	}
+	host := res.Request.URL.Host
+	rateLimitRemaining.WithLabelValues(host).Set(float64(rl.Remaining))
+	if !rl.Reset.IsZero() {
+		rateLimitReset.WithLabelValues(host).Set(float64(rl.Reset.Unix()))
+	}
+}
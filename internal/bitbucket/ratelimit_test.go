@@ -0,0 +1,87 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	type want struct {
+		rl RateLimit
+		ok bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		headers map[string]string
+		want    want
+	}{
+		"Budget": {
+			reason:  "The remaining budget and its reset should be parsed",
+			headers: map[string]string{"X-RateLimit-Remaining": "42", "X-RateLimit-Reset": "1700000000"},
+			want:    want{rl: RateLimit{Remaining: 42, Reset: time.Unix(1700000000, 0).UTC()}, ok: true},
+		},
+		"LowerCase": {
+			reason:  "Header names should be matched regardless of their case",
+			headers: map[string]string{"x-ratelimit-remaining": "0"},
+			want:    want{rl: RateLimit{}, ok: true},
+		},
+		"NoReset": {
+			reason:  "A budget without a reset should be parsed without one",
+			headers: map[string]string{"X-RateLimit-Remaining": "7", "X-RateLimit-Reset": "soon"},
+			want:    want{rl: RateLimit{Remaining: 7}, ok: true},
+		},
+		"Absent": {
+			reason: "Responses without a budget should report none",
+			want:   want{},
+		},
+		"Invalid": {
+			reason:  "A remaining budget that is not a count should be ignored",
+			headers: map[string]string{"X-RateLimit-Remaining": "-1"},
+			want:    want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+			got := want{}
+			got.rl, got.ok = parseRateLimit(h)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nparseRateLimit(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRateLimitMetrics(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", jsonMediaType)
+		w.Header().Set("X-RateLimit-Remaining", "99")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+
+	req, err := c.newRequest(http.MethodGet, "projects", nil)
+	if err != nil {
+		t.Fatalf("newRequest(...): %v", err)
+	}
+	if err := c.do(context.Background(), req, nil); err != nil {
+		t.Fatalf("do(...): %v", err)
+	}
+
+	if got := testutil.ToFloat64(rateLimitRemaining.WithLabelValues(req.URL.Host)); got != 99 {
+		t.Errorf("bitbucket_rate_limit_remaining: want 99, got %v", got)
+	}
+	if got := testutil.ToFloat64(rateLimitReset.WithLabelValues(req.URL.Host)); got != 1700000000 {
+		t.Errorf("bitbucket_rate_limit_reset_timestamp_seconds: want 1700000000, got %v", got)
+	}
+}
@@ -2,21 +2,72 @@ package bitbucket
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 type RepositoryService interface {
 	Get(context.Context, *Repository) (*Repository, error)
+	Exists(context.Context, *Repository) (bool, error)
+	List(ctx context.Context, project string) ([]Repository, error)
 	Create(context.Context, *Repository) (*Repository, error)
 	Update(context.Context, *Repository) (*Repository, error)
 	Delete(context.Context, *Repository) error
+	Move(context.Context, *Repository, string) (*Repository, error)
 	// Groups permissions
 	GetGroups(context.Context, *Repository) ([]Group, error)
+	GetGroupPermission(context.Context, *Repository, string) (string, error)
 	AddGroup(context.Context, *Repository, *Group) error
 	RevokeGroup(context.Context, *Repository, *Group) error
+	// User permissions
+	GetUsers(context.Context, *Repository) ([]UserPermission, error)
+	// Smart mirroring
+	GetMirrorServers(context.Context, *Repository) ([]string, error)
+	AddMirrorServer(context.Context, *Repository, string) error
+	RemoveMirrorServer(context.Context, *Repository, string) error
+	// Pull request settings
+	GetPullRequestTemplate(context.Context, *Repository) (string, error)
+	SetPullRequestTemplate(context.Context, *Repository, string) error
+	GetDefaultMergeStrategy(context.Context, *Repository) (string, error)
+	SetDefaultMergeStrategy(context.Context, *Repository, string) error
+	// Branches
+	GetDefaultBranch(context.Context, *Repository) (string, error)
+	SetDefaultBranch(context.Context, *Repository, string) error
+	GetBranches(context.Context, *Repository) ([]Branch, error)
+	CreateBranch(context.Context, *Repository, string, string) error
+	DeleteBranch(context.Context, *Repository, string) error
+	GetRestrictions(context.Context, *Repository) ([]Restriction, error)
+	AddRestriction(context.Context, *Repository, *Restriction) error
+	// Hooks
+	GetEnabledHooks(context.Context, *Repository) ([]string, error)
+	EnableHook(context.Context, *Repository, string) error
+	DisableHook(context.Context, *Repository, string) error
+	// Merge checks
+	GetRequiredBuilds(context.Context, *Repository) ([]RequiredBuild, error)
+	AddRequiredBuild(context.Context, *Repository, *RequiredBuild) error
+	UpdateRequiredBuild(context.Context, *Repository, *RequiredBuild) error
+	DeleteRequiredBuild(context.Context, *Repository, int) error
+	// Fork synchronization
+	GetForkSyncEnabled(context.Context, *Repository) (bool, error)
+	SetForkSyncEnabled(context.Context, *Repository, bool) error
+	// Git LFS
+	GetLFSEnabled(context.Context, *Repository) (bool, error)
+	SetLFSEnabled(context.Context, *Repository, bool) error
+	// Pull requests
+	CountOpenPullRequests(context.Context, *Repository) (int, error)
+	GetRequiredApprovals(context.Context, *Repository) (int, error)
+	// Contents
+	GetSize(context.Context, *Repository) (int64, error)
+	HasCommits(context.Context, *Repository) (bool, error)
 }
 
+const mirroringAPI = "mirroring/1.0"
+
 type repositoryService struct {
 	client *Client
 }
@@ -24,23 +75,104 @@ type repositoryService struct {
 type Repository struct {
 	ID          int    `json:"-"`
 	Name        string `json:"name"`
+	Slug        string `json:"-"`
 	Public      bool   `json:"public"`
 	Project     string `json:"-"`
 	Description string `json:"description"`
+	// Archived is only sent when set, as servers before 8.0 cannot archive repositories
+	Archived *bool `json:"archived,omitempty"`
+	// Origin is the project/slug of the repository this repository is forked
+	// from, empty when it is not a fork
+	Origin string `json:"-"`
+	// State is the provisioning state of the repository, e.g. StateAvailable
+	State string `json:"-"`
+	// ScmID is the kind of repository, e.g. ScmGit
+	ScmID string `json:"-"`
+	// CloneURLs are the URLs the repository is cloned from by protocol, e.g.
+	// CloneHTTP
+	CloneURLs map[string]string `json:"-"`
+	// WebURL is the URL the repository is browsed at
+	WebURL string `json:"-"`
+	// CreatedDate is when the repository was created, nil when the server
+	// does not report it
+	CreatedDate *time.Time `json:"-"`
+}
+
+// ScmGit is the ScmID of git repositories
+const ScmGit = "git"
+
+// Protocols repositories are cloned with
+const (
+	CloneHTTP = "http"
+	CloneSSH  = "ssh"
+)
+
+// Provisioning states of a repository
+const (
+	StateAvailable            = "AVAILABLE"
+	StateInitialising         = "INITIALISING"
+	StateInitialisationFailed = "INITIALISATION_FAILED"
+)
+
+// IsAvailable reports whether the repository is fully provisioned. A missing
+// state is taken as available.
+func (r *Repository) IsAvailable() bool {
+	return r.State == "" || r.State == StateAvailable
+}
+
+// IsFork reports whether the repository is a fork of another repository
+func (r *Repository) IsFork() bool {
+	return r.Origin != ""
+}
+
+// IsArchived reports whether the repository is archived
+func (r *Repository) IsArchived() bool {
+	return r.Archived != nil && *r.Archived
 }
 
 type Group struct {
 	Name       string
 	Permission string
+	// Inherited is true for a grant bitbucket reports as inherited from the
+	// project rather than granted on the repository itself
+	Inherited bool
+}
+
+// UserPermission is a permission granted to an individual user
+type UserPermission struct {
+	User       string
+	Permission string
 }
 
 type repositoryJson struct {
 	ID      int    `json:"id"`
 	Name    string `json:"name"`
+	Slug    string `json:"slug"`
 	Project struct {
 		Key string `json:"key"`
 	} `json:"project"`
 	Description string `json:"description"`
+	Public      bool   `json:"public"`
+	Archived    bool   `json:"archived"`
+	State       string `json:"state"`
+	Origin      *struct {
+		Slug    string `json:"slug"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"origin"`
+	ScmID string `json:"scmId"`
+	// CreatedDate is in milliseconds since the epoch, only sent by some servers
+	CreatedDate int64 `json:"createdDate"`
+	Links       struct {
+		Clone []struct {
+			Href string `json:"href"`
+			Name string `json:"name"`
+		} `json:"clone"`
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
 }
 
 func (service *repositoryService) Get(ctx context.Context, repository *Repository) (*Repository, error) {
@@ -50,13 +182,49 @@ func (service *repositoryService) Get(ctx context.Context, repository *Repositor
 	}
 
 	var repo repositoryJson
-	err = service.client.do(ctx, req, &repo)
+	err = service.client.doCached(ctx, req, &repo)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching repository: %w", err)
 	}
 	return repo.toRepository(), nil
 }
 
+// Exists reports whether the repository exists without fetching its details
+func (service *repositoryService) Exists(ctx context.Context, repository *Repository) (bool, error) {
+	req, err := service.client.newRequest(http.MethodHead, fmt.Sprintf("projects/%s/repos/%s", repository.Project, repository.Name), nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request for checking repository: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking repository: %w", err)
+	}
+	return true, nil
+}
+
+// List returns all repositories in the project
+func (service *repositoryService) List(ctx context.Context, project string) ([]Repository, error) {
+	repositories := []Repository{}
+	err := service.client.getPaged(ctx, fmt.Sprintf("projects/%s/repos", project), func(values json.RawMessage) error {
+		var entries []repositoryJson
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for i := range entries {
+			repositories = append(repositories, *entries[i].toRepository())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing repositories: %w", err)
+	}
+	return repositories, nil
+}
+
 func (service *repositoryService) Create(ctx context.Context, repository *Repository) (*Repository, error) {
 	req, err := service.client.newRequest(http.MethodPost, fmt.Sprintf("projects/%s/repos", repository.Project), repository)
 	if err != nil {
@@ -73,6 +241,12 @@ func (service *repositoryService) Create(ctx context.Context, repository *Reposi
 }
 
 func (service *repositoryService) Update(ctx context.Context, repository *Repository) (*Repository, error) {
+	if repository.Archived != nil {
+		if err := service.client.requireFeature(ctx, "archiving repositories", minVersionArchive); err != nil {
+			return nil, fmt.Errorf("error updating repository: %w", err)
+		}
+	}
+
 	req, err := service.client.newRequest(http.MethodPut, fmt.Sprintf("projects/%s/repos/%s", repository.Project, repository.Name), repository)
 	if err != nil {
 		return nil, fmt.Errorf("error updating request for creating repository: %w", err)
@@ -87,6 +261,8 @@ func (service *repositoryService) Update(ctx context.Context, repository *Reposi
 	return repo.toRepository(), nil
 }
 
+// Delete deletes the repository. Deleting a repository that no longer exists
+// succeeds, so deletion is idempotent.
 func (service *repositoryService) Delete(ctx context.Context, repository *Repository) error {
 	req, err := service.client.newRequest(http.MethodDelete, fmt.Sprintf("projects/%s/repos/%s", repository.Project, repository.Name), nil)
 	if err != nil {
@@ -94,6 +270,9 @@ func (service *repositoryService) Delete(ctx context.Context, repository *Reposi
 	}
 
 	err = service.client.do(ctx, req, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("error deleting repository: %w", err)
 	}
@@ -101,36 +280,168 @@ func (service *repositoryService) Delete(ctx context.Context, repository *Reposi
 	return nil
 }
 
+// Move moves the repository to another project. ErrConflict is returned
+// without moving the repository when the project already has a repository
+// with the same slug.
+func (service *repositoryService) Move(ctx context.Context, repository *Repository, project string) (*Repository, error) {
+	exists, err := service.Exists(ctx, &Repository{Name: repository.Name, Project: project})
+	if err != nil {
+		return nil, fmt.Errorf("error checking project %s for repository %s: %w", project, repository.Name, err)
+	}
+	if exists {
+		return nil, fmt.Errorf("error moving repository: project %s already has a repository %s: %w", project, repository.Name, ErrConflict)
+	}
+
+	body := map[string]map[string]string{"project": {"key": project}}
+	req, err := service.client.newRequest(http.MethodPut, fmt.Sprintf("projects/%s/repos/%s", repository.Project, repository.Name), body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for moving repository: %w", err)
+	}
+
+	var repo repositoryJson
+	err = service.client.do(ctx, req, &repo)
+	if err != nil {
+		return nil, fmt.Errorf("error moving repository to project %s: %w", project, err)
+	}
+
+	return repo.toRepository(), nil
+}
+
 func (service *repositoryService) GetGroups(ctx context.Context, repository *Repository) ([]Group, error) {
 	url := fmt.Sprintf("projects/%s/repos/%s/permissions/groups", repository.Project, repository.Name)
-	req, err := service.client.newRequest(http.MethodGet, url, nil)
+
+	groups := []Group{}
+	err := service.client.getPaged(ctx, url, func(values json.RawMessage) error {
+		var entries []struct {
+			Group struct {
+				Name string `json:"name"`
+			} `json:"group"`
+			Permission string `json:"permission"`
+			Inherited  bool   `json:"inherited"`
+		}
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			groups = append(groups, Group{Name: entry.Group.Name, Permission: entry.Permission, Inherited: entry.Inherited})
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating request for getting repository groups: %w", err)
+		return nil, fmt.Errorf("error getting repository group: %w", err)
 	}
+	return groups, nil
+}
+
+// GetGroupPermission returns the permission granted directly on the
+// repository to a single group. Bitbucket filters the groups by a substring
+// of the name, so the exact group is picked from the filtered page. A group
+// without a direct grant is reported as ErrNotFound.
+func (service *repositoryService) GetGroupPermission(ctx context.Context, repository *Repository, group string) (string, error) {
+	path := pathWithQuery(fmt.Sprintf("projects/%s/repos/%s/permissions/groups", repository.Project, repository.Name), url.Values{
+		"filter": {group},
+	})
 
-	var response struct {
-		Values []struct {
+	permission := ""
+	err := service.client.getPaged(ctx, path, func(values json.RawMessage) error {
+		var entries []struct {
 			Group struct {
 				Name string `json:"name"`
 			} `json:"group"`
 			Permission string `json:"permission"`
-		} `json:"values"`
+			Inherited  bool   `json:"inherited"`
+		}
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.Inherited && strings.EqualFold(entry.Group.Name, group) {
+				permission = entry.Permission
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting repository group permission: %w", err)
+	}
+	if permission == "" {
+		return "", fmt.Errorf("group %s has no permission on repository: %w", group, ErrNotFound)
 	}
-	err = service.client.do(ctx, req, &response)
+	return permission, nil
+}
+
+// GetUsers returns the permissions granted to individual users on the repository
+func (service *repositoryService) GetUsers(ctx context.Context, repository *Repository) ([]UserPermission, error) {
+	url := fmt.Sprintf("projects/%s/repos/%s/permissions/users", repository.Project, repository.Name)
+
+	users := []UserPermission{}
+	err := service.client.getPaged(ctx, url, func(values json.RawMessage) error {
+		var entries []struct {
+			User struct {
+				Name string `json:"name"`
+			} `json:"user"`
+			Permission string `json:"permission"`
+		}
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			users = append(users, UserPermission{User: entry.User.Name, Permission: entry.Permission})
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error getting repository group: %w", err)
+		return nil, fmt.Errorf("error getting repository users: %w", err)
 	}
+	return users, nil
+}
 
-	groups := []Group{}
-	for _, entry := range response.Values {
-		groups = append(groups, Group{Name: entry.Group.Name, Permission: entry.Permission})
+// maxPullRequestCountPages bounds the pages of pull requests counted, so a
+// busy repository reports a floor rather than paging through all of them
+const maxPullRequestCountPages = 5
+
+// errCountLimit stops counting pull requests once the page limit is reached
+var errCountLimit = errors.New("count limit reached")
+
+// CountOpenPullRequests returns the number of open pull requests targeting
+// the repository. Paged responses carry the size of the page rather than a
+// total, so the pull requests are counted page by page. At most
+// maxPullRequestCountPages pages are counted, the count of a repository with
+// more open pull requests is a floor.
+func (service *repositoryService) CountOpenPullRequests(ctx context.Context, repository *Repository) (int, error) {
+	path := pathWithQuery(fmt.Sprintf("projects/%s/repos/%s/pull-requests", repository.Project, repository.Name), url.Values{
+		"state": {"OPEN"},
+	})
+
+	count := 0
+	pages := 0
+	err := service.client.getPaged(ctx, path, func(values json.RawMessage) error {
+		var entries []json.RawMessage
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		count += len(entries)
+		pages++
+		if pages >= maxPullRequestCountPages {
+			return errCountLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errCountLimit) {
+		return count, nil
 	}
-	return groups, nil
+	if err != nil {
+		return 0, fmt.Errorf("error counting repository pull requests: %w", err)
+	}
+	return count, nil
 }
 
 func (service *repositoryService) AddGroup(ctx context.Context, repository *Repository, group *Group) error {
-	url := fmt.Sprintf("projects/%s/repos/%s/permissions/groups?name=%s&permission=%s", repository.Project, repository.Name, group.Name, group.Permission)
-	req, err := service.client.newRequest(http.MethodPut, url, nil)
+	path := pathWithQuery(fmt.Sprintf("projects/%s/repos/%s/permissions/groups", repository.Project, repository.Name), url.Values{
+		"name":       {group.Name},
+		"permission": {group.Permission},
+	})
+	req, err := service.client.newRequest(http.MethodPut, path, nil)
 	if err != nil {
 		return fmt.Errorf("error creating request for adding repository group: %w", err)
 	}
@@ -143,8 +454,10 @@ func (service *repositoryService) AddGroup(ctx context.Context, repository *Repo
 }
 
 func (service *repositoryService) RevokeGroup(ctx context.Context, repository *Repository, group *Group) error {
-	url := fmt.Sprintf("projects/%s/repos/%s/permissions/groups?name=%s", repository.Project, repository.Name, group.Name)
-	req, err := service.client.newRequest(http.MethodDelete, url, nil)
+	path := pathWithQuery(fmt.Sprintf("projects/%s/repos/%s/permissions/groups", repository.Project, repository.Name), url.Values{
+		"name": {group.Name},
+	})
+	req, err := service.client.newRequest(http.MethodDelete, path, nil)
 	if err != nil {
 		return fmt.Errorf("error creating request for revoking repository group: %w", err)
 	}
@@ -156,6 +469,239 @@ func (service *repositoryService) RevokeGroup(ctx context.Context, repository *R
 	return nil
 }
 
+// GetMirrorServers returns the ids of the mirror servers the repository is mirrored to.
+// ErrUnsupported is returned if mirroring is not available on the server.
+func (service *repositoryService) GetMirrorServers(ctx context.Context, repository *Repository) ([]string, error) {
+	url := restAPIPath(mirroringAPI, fmt.Sprintf("repos/%d/mirrors", repository.ID))
+
+	mirrors := []string{}
+	err := service.client.getPaged(ctx, url, func(values json.RawMessage) error {
+		var entries []struct {
+			MirrorServer struct {
+				ID string `json:"id"`
+			} `json:"mirrorServer"`
+		}
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			mirrors = append(mirrors, entry.MirrorServer.ID)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("error getting repository mirrors: mirroring %w", ErrUnsupported)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting repository mirrors: %w", err)
+	}
+	return mirrors, nil
+}
+
+func (service *repositoryService) AddMirrorServer(ctx context.Context, repository *Repository, mirrorID string) error {
+	url := restAPIPath(mirroringAPI, fmt.Sprintf("mirrorServers/%s/repos/%d", mirrorID, repository.ID))
+	req, err := service.client.newRequest(http.MethodPut, url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for adding repository mirror: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error adding repository mirror: %w", err)
+	}
+	return nil
+}
+
+func (service *repositoryService) RemoveMirrorServer(ctx context.Context, repository *Repository, mirrorID string) error {
+	url := restAPIPath(mirroringAPI, fmt.Sprintf("mirrorServers/%s/repos/%d", mirrorID, repository.ID))
+	req, err := service.client.newRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for removing repository mirror: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error removing repository mirror: %w", err)
+	}
+	return nil
+}
+
+type pullRequestTemplateJson struct {
+	Template string `json:"template"`
+}
+
+// GetPullRequestTemplate returns the default description of new pull requests in the repository.
+// ErrUnsupported is returned if the server does not support pull request templates.
+func (service *repositoryService) GetPullRequestTemplate(ctx context.Context, repository *Repository) (string, error) {
+	req, err := service.client.newRequest(http.MethodGet, fmt.Sprintf("projects/%s/repos/%s/settings/pull-request-template", repository.Project, repository.Name), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request for getting pull request template: %w", err)
+	}
+
+	var template pullRequestTemplateJson
+	err = service.client.do(ctx, req, &template)
+	if errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("error getting pull request template: pull request templates %w", ErrUnsupported)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting pull request template: %w", err)
+	}
+	return template.Template, nil
+}
+
+func (service *repositoryService) SetPullRequestTemplate(ctx context.Context, repository *Repository, template string) error {
+	req, err := service.client.newRequest(http.MethodPut, fmt.Sprintf("projects/%s/repos/%s/settings/pull-request-template", repository.Project, repository.Name), &pullRequestTemplateJson{Template: template})
+	if err != nil {
+		return fmt.Errorf("error creating request for setting pull request template: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("error setting pull request template: pull request templates %w", ErrUnsupported)
+	}
+	if err != nil {
+		return fmt.Errorf("error setting pull request template: %w", err)
+	}
+	return nil
+}
+
+type mergeStrategyJson struct {
+	ID      string `json:"id"`
+	Enabled bool   `json:"enabled,omitempty"`
+}
+
+type pullRequestSettingsJson struct {
+	MergeConfig struct {
+		DefaultStrategy mergeStrategyJson   `json:"defaultStrategy"`
+		Strategies      []mergeStrategyJson `json:"strategies"`
+	} `json:"mergeConfig"`
+}
+
+func (service *repositoryService) getPullRequestSettings(ctx context.Context, repository *Repository) (*pullRequestSettingsJson, error) {
+	req, err := service.client.newRequest(http.MethodGet, fmt.Sprintf("projects/%s/repos/%s/settings/pull-requests", repository.Project, repository.Name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for getting pull request settings: %w", err)
+	}
+
+	settings := &pullRequestSettingsJson{}
+	err = service.client.do(ctx, req, settings)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pull request settings: %w", err)
+	}
+	return settings, nil
+}
+
+// GetDefaultMergeStrategy returns the id of the default merge strategy of pull
+// requests in the repository, e.g. no-ff
+func (service *repositoryService) GetDefaultMergeStrategy(ctx context.Context, repository *Repository) (string, error) {
+	settings, err := service.getPullRequestSettings(ctx, repository)
+	if err != nil {
+		return "", err
+	}
+	return settings.MergeConfig.DefaultStrategy.ID, nil
+}
+
+// SetDefaultMergeStrategy sets the default merge strategy of pull requests in
+// the repository. The enabled strategies are kept, and the default strategy is
+// enabled if it is not already.
+func (service *repositoryService) SetDefaultMergeStrategy(ctx context.Context, repository *Repository, strategy string) error {
+	settings, err := service.getPullRequestSettings(ctx, repository)
+	if err != nil {
+		return err
+	}
+
+	update := pullRequestSettingsJson{}
+	update.MergeConfig.DefaultStrategy = mergeStrategyJson{ID: strategy}
+	update.MergeConfig.Strategies = []mergeStrategyJson{}
+	enabled := false
+	for _, s := range settings.MergeConfig.Strategies {
+		if s.Enabled {
+			update.MergeConfig.Strategies = append(update.MergeConfig.Strategies, mergeStrategyJson{ID: s.ID})
+			enabled = enabled || s.ID == strategy
+		}
+	}
+	if !enabled {
+		update.MergeConfig.Strategies = append(update.MergeConfig.Strategies, mergeStrategyJson{ID: strategy})
+	}
+
+	req, err := service.client.newRequest(http.MethodPost, fmt.Sprintf("projects/%s/repos/%s/settings/pull-requests", repository.Project, repository.Name), &update)
+	if err != nil {
+		return fmt.Errorf("error creating request for setting default merge strategy: %w", err)
+	}
+
+	err = service.client.do(ctx, req, nil)
+	if err != nil {
+		return fmt.Errorf("error setting default merge strategy: %w", err)
+	}
+	return nil
+}
+
 func (r *repositoryJson) toRepository() *Repository {
-	return &Repository{ID: r.ID, Name: r.Name, Project: r.Project.Key, Description: r.Description}
+	archived := r.Archived
+	repository := &Repository{ID: r.ID, Name: r.Name, Slug: r.Slug, Project: r.Project.Key, Description: r.Description, Public: r.Public, Archived: &archived, State: r.State}
+	if r.Origin != nil {
+		repository.Origin = r.Origin.Project.Key + "/" + r.Origin.Slug
+	}
+	repository.ScmID = r.ScmID
+	if len(r.Links.Self) > 0 {
+		repository.WebURL = r.Links.Self[0].Href
+	}
+	if r.CreatedDate > 0 {
+		created := time.UnixMilli(r.CreatedDate).UTC()
+		repository.CreatedDate = &created
+	}
+	// the protocols of the clone links depend on the kind of repository, only
+	// those of git repositories are known
+	if r.ScmID == ScmGit {
+		for _, link := range r.Links.Clone {
+			if link.Name != CloneHTTP && link.Name != CloneSSH {
+				continue
+			}
+			if repository.CloneURLs == nil {
+				repository.CloneURLs = map[string]string{}
+			}
+			repository.CloneURLs[link.Name] = link.Href
+		}
+	}
+	return repository
+}
+
+// GetSize returns the size of the repository in bytes, excluding attachments
+func (service *repositoryService) GetSize(ctx context.Context, repository *Repository) (int64, error) {
+	req, err := service.client.newRequest(http.MethodGet, webPath(fmt.Sprintf("projects/%s/repos/%s/sizes", repository.Project, repository.Name)), nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request for getting repository size: %w", err)
+	}
+
+	var sizes struct {
+		Repository int64 `json:"repository"`
+	}
+	err = service.client.do(ctx, req, &sizes)
+	if err != nil {
+		return 0, fmt.Errorf("error getting repository size: %w", err)
+	}
+	return sizes.Repository, nil
+}
+
+// HasCommits reports whether the repository contains any commits
+func (service *repositoryService) HasCommits(ctx context.Context, repository *Repository) (bool, error) {
+	path := pathWithQuery(fmt.Sprintf("projects/%s/repos/%s/commits", repository.Project, repository.Name), url.Values{
+		"limit": {"1"},
+	})
+	req, err := service.client.newRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request for getting repository commits: %w", err)
+	}
+
+	var p page
+	err = service.client.do(ctx, req, &p)
+	if err != nil {
+		return false, fmt.Errorf("error getting repository commits: %w", err)
+	}
+
+	var commits []json.RawMessage
+	if err := json.Unmarshal(p.Values, &commits); err != nil {
+		return false, fmt.Errorf("error getting repository commits: %w", ErrResponseMalformed)
+	}
+	return len(commits) > 0, nil
 }
@@ -0,0 +1,911 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestGetGroups(t *testing.T) {
+	pages := map[string]string{
+		"0": `{"values":[{"group":{"name":"admins"},"permission":"REPO_ADMIN"}],"isLastPage":false,"nextPageStart":1}`,
+		"1": `{"values":[{"group":{"name":"readers"},"permission":"REPO_READ"},{"group":{"name":"project-admins"},"permission":"REPO_ADMIN","inherited":true}],"isLastPage":true}`,
+	}
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != apiPath+"projects/PRJ/repos/repo/permissions/groups" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", jsonMediaType)
+		_, _ = w.Write([]byte(pages[r.URL.Query().Get("start")]))
+	}))
+
+	service := &repositoryService{client: c}
+	got, err := service.GetGroups(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+	if err != nil {
+		t.Fatalf("GetGroups(...): %v", err)
+	}
+
+	want := []Group{
+		{Name: "admins", Permission: "REPO_ADMIN"},
+		{Name: "readers", Permission: "REPO_READ"},
+		{Name: "project-admins", Permission: "REPO_ADMIN", Inherited: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetGroups(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestGetGroupPermission(t *testing.T) {
+	type want struct {
+		permission string
+		err        error
+	}
+
+	// the filter matches on a substring of the name, inherited grants included
+	page := `{"values":[{"group":{"name":"devs-ext"},"permission":"REPO_READ"},{"group":{"name":"DEVS"},"permission":"REPO_WRITE"},{"group":{"name":"ops"},"permission":"REPO_ADMIN","inherited":true}],"isLastPage":true}`
+
+	cases := map[string]struct {
+		reason string
+		group  string
+		want   want
+	}{
+		"Found": {
+			reason: "The permission of the exactly matching group should be returned",
+			group:  "devs",
+			want:   want{permission: "REPO_WRITE"},
+		},
+		"NotFound": {
+			reason: "A group without any grant should be reported as not found",
+			group:  "testers",
+			want:   want{err: ErrNotFound},
+		},
+		"Inherited": {
+			reason: "A group only inheriting its permission has no grant on the repository",
+			group:  "ops",
+			want:   want{err: ErrNotFound},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != apiPath+"projects/PRJ/repos/repo/permissions/groups" {
+					http.NotFound(w, r)
+					return
+				}
+				if got := r.URL.Query().Get("filter"); got != tc.group {
+					t.Errorf("filter: want %q, got %q", tc.group, got)
+				}
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(page))
+			}))
+
+			service := &repositoryService{client: c}
+			permission, err := service.GetGroupPermission(context.Background(), &Repository{Name: "repo", Project: "PRJ"}, tc.group)
+			got := want{permission: permission, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nGetGroupPermission(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpdateDescription(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		description string
+		body        string
+	}{
+		"Markdown": {
+			reason:      "Markdown links and emphasis should be sent as written",
+			description: "See [docs](https://example.com/a?b=1&c=2) and <https://example.com> for **details**",
+			body:        `{"name":"repo","public":false,"description":"See [docs](https://example.com/a?b=1&c=2) and <https://example.com> for **details**"}`,
+		},
+		"Quotes": {
+			reason:      "Quotes and backslashes should only be escaped as json requires",
+			description: `Say "hi" to 'everyone' \o/`,
+			body:        `{"name":"repo","public":false,"description":"Say \"hi\" to 'everyone' \\o/"}`,
+		},
+		"Newlines": {
+			reason:      "Newlines and tabs should be kept",
+			description: "# Title\n\n- item\n\tcode",
+			body:        `{"name":"repo","public":false,"description":"# Title\n\n- item\n\tcode"}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if diff := cmp.Diff(tc.body, strings.TrimSpace(string(body))); diff != "" {
+					t.Errorf("\n%s\nUpdate(...): -want body, +got body:\n%s\n", tc.reason, diff)
+				}
+				// echo the description like bitbucket, which stores it raw
+				var sent Repository
+				_ = json.Unmarshal(body, &sent)
+				out, _ := json.Marshal(map[string]interface{}{"name": "repo", "slug": "repo", "project": map[string]string{"key": "PRJ"}, "description": sent.Description})
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write(out)
+			}))
+
+			service := &repositoryService{client: c}
+			repo, err := service.Update(context.Background(), &Repository{Name: "repo", Project: "PRJ", Description: tc.description})
+			if err != nil {
+				t.Fatalf("\n%s\nUpdate(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.description, repo.Description); diff != "" {
+				t.Errorf("\n%s\nUpdate(...): -want description, +got description:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		status int
+		want   error
+	}{
+		"Deleted": {
+			reason: "A repository scheduled for deletion should be deleted",
+			status: http.StatusAccepted,
+		},
+		"AlreadyDeleted": {
+			reason: "Deleting a repository that does not exist should succeed",
+			status: http.StatusNotFound,
+		},
+		"Forbidden": {
+			reason: "Other errors should be returned",
+			status: http.StatusForbidden,
+			want:   ErrPermission,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete || r.URL.Path != apiPath+"projects/PRJ/repos/repo" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				w.Header().Set("Content-Type", jsonMediaType)
+				w.WriteHeader(tc.status)
+			}))
+
+			service := &repositoryService{client: c}
+			err := service.Delete(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+			if diff := cmp.Diff(tc.want, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nDelete(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMove(t *testing.T) {
+	type want struct {
+		project string
+		moved   bool
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		exists bool
+		status int
+		want   want
+	}{
+		"Moved": {
+			reason: "The repository should be moved to the new project",
+			status: http.StatusCreated,
+			want:   want{project: "NEW", moved: true},
+		},
+		"Forbidden": {
+			reason: "Errors moving the repository should be returned",
+			status: http.StatusForbidden,
+			want:   want{moved: true, err: ErrPermission},
+		},
+		"Collision": {
+			reason: "A repository with the same slug in the new project should fail the move with ErrConflict before moving",
+			exists: true,
+			status: http.StatusCreated,
+			want:   want{err: ErrConflict},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodHead && r.URL.Path == apiPath+"projects/NEW/repos/repo":
+					if !tc.exists {
+						w.WriteHeader(http.StatusNotFound)
+					}
+				case r.Method == http.MethodPut && r.URL.Path == apiPath+"projects/PRJ/repos/repo":
+					got.moved = true
+					body, _ := io.ReadAll(r.Body)
+					if diff := cmp.Diff(`{"project":{"key":"NEW"}}`, strings.TrimSpace(string(body))); diff != "" {
+						t.Errorf("\n%s\nMove(...): -want body, +got body:\n%s\n", tc.reason, diff)
+					}
+					w.Header().Set("Content-Type", jsonMediaType)
+					w.WriteHeader(tc.status)
+					_, _ = w.Write([]byte(`{"id":1,"name":"repo","slug":"repo","project":{"key":"NEW"}}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			}))
+
+			service := &repositoryService{client: c}
+			repo, err := service.Move(context.Background(), &Repository{Name: "repo", Project: "PRJ"}, "NEW")
+			got.err = err
+			if repo != nil {
+				got.project = repo.Project
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nMove(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	pages := map[string]string{
+		"0": `{"values":[{"user":{"name":"alice"},"permission":"REPO_ADMIN"}],"isLastPage":false,"nextPageStart":1}`,
+		"1": `{"values":[{"user":{"name":"bob"},"permission":"REPO_READ"}],"isLastPage":true}`,
+	}
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != apiPath+"projects/PRJ/repos/repo/permissions/users" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", jsonMediaType)
+		_, _ = w.Write([]byte(pages[r.URL.Query().Get("start")]))
+	}))
+
+	service := &repositoryService{client: c}
+	got, err := service.GetUsers(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+	if err != nil {
+		t.Fatalf("GetUsers(...): %v", err)
+	}
+
+	want := []UserPermission{
+		{User: "alice", Permission: "REPO_ADMIN"},
+		{User: "bob", Permission: "REPO_READ"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetUsers(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestDefaultMergeStrategy(t *testing.T) {
+	type want struct {
+		strategy string
+		posted   string
+	}
+	settings := `{"mergeConfig":{"defaultStrategy":{"id":"no-ff"},"strategies":[` +
+		`{"id":"no-ff","enabled":true},{"id":"ff","enabled":true},{"id":"squash","enabled":false}]}}`
+
+	cases := map[string]struct {
+		reason   string
+		strategy string
+		want     want
+	}{
+		"Enabled": {
+			reason:   "Setting an enabled strategy as default should keep the enabled strategies",
+			strategy: "ff",
+			want: want{
+				strategy: "no-ff",
+				posted:   `{"mergeConfig":{"defaultStrategy":{"id":"ff"},"strategies":[{"id":"no-ff"},{"id":"ff"}]}}`,
+			},
+		},
+		"Disabled": {
+			reason:   "Setting a disabled strategy as default should enable it",
+			strategy: "squash",
+			want: want{
+				strategy: "no-ff",
+				posted:   `{"mergeConfig":{"defaultStrategy":{"id":"squash"},"strategies":[{"id":"no-ff"},{"id":"ff"},{"id":"squash"}]}}`,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != apiPath+"projects/PRJ/repos/repo/settings/pull-requests" {
+					http.NotFound(w, r)
+					return
+				}
+				if r.Method == http.MethodPost {
+					b, _ := io.ReadAll(r.Body)
+					got.posted = strings.TrimSpace(string(b))
+				}
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(settings))
+			}))
+			service := &repositoryService{client: c}
+			repo := &Repository{Name: "repo", Project: "PRJ"}
+
+			var err error
+			got.strategy, err = service.GetDefaultMergeStrategy(context.Background(), repo)
+			if err != nil {
+				t.Fatalf("GetDefaultMergeStrategy(...): %v", err)
+			}
+			if err := service.SetDefaultMergeStrategy(context.Background(), repo, tc.strategy); err != nil {
+				t.Fatalf("SetDefaultMergeStrategy(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ndefault merge strategy: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMirrorServers(t *testing.T) {
+	type want struct {
+		mirrors  []string
+		requests []string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		handler http.HandlerFunc
+		want    want
+	}{
+		"Enable": {
+			reason: "Adding a mirror server should PUT the repository on the mirror server",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(`{"values":[{"mirrorServer":{"id":"M1"}}],"isLastPage":true}`))
+			},
+			want: want{
+				mirrors: []string{"M1"},
+				requests: []string{
+					"GET /rest/mirroring/1.0/repos/42/mirrors",
+					"PUT /rest/mirroring/1.0/mirrorServers/M2/repos/42",
+				},
+			},
+		},
+		"Unsupported": {
+			reason: "A server without mirroring should report mirroring as unsupported",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			want: want{
+				requests: []string{"GET /rest/mirroring/1.0/repos/42/mirrors"},
+				err:      ErrUnsupported,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			requests := []string{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				tc.handler(w, r)
+			}))
+			service := &repositoryService{client: c}
+			repo := &Repository{ID: 42, Name: "repo", Project: "PRJ"}
+
+			mirrors, err := service.GetMirrorServers(context.Background(), repo)
+			if err == nil {
+				err = service.AddMirrorServer(context.Background(), repo, "M2")
+			}
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nmirroring: -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.mirrors, mirrors); diff != "" {
+				t.Errorf("\n%s\nGetMirrorServers(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.requests, requests); diff != "" {
+				t.Errorf("\n%s\nmirroring: -want requests, +got requests:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGroupQueryEncoding(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		group  Group
+		want   url.Values
+	}{
+		"Plain": {
+			reason: "A plain group name should be sent as is",
+			group:  Group{Name: "admins", Permission: "REPO_ADMIN"},
+			want:   url.Values{"name": {"admins"}, "permission": {"REPO_ADMIN"}},
+		},
+		"Spaces": {
+			reason: "A group name with spaces should be encoded",
+			group:  Group{Name: "Domain Users", Permission: "REPO_READ"},
+			want:   url.Values{"name": {"Domain Users"}, "permission": {"REPO_READ"}},
+		},
+		"Ampersand": {
+			reason: "An ampersand in a group name should not start a new parameter",
+			group:  Group{Name: "R&D=all", Permission: "REPO_WRITE"},
+			want:   url.Values{"name": {"R&D=all"}, "permission": {"REPO_WRITE"}},
+		},
+		"Unicode": {
+			reason: "A unicode group name should be encoded",
+			group:  Group{Name: "Udvikling æøå", Permission: "REPO_READ"},
+			want:   url.Values{"name": {"Udvikling æøå"}, "permission": {"REPO_READ"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := map[string]url.Values{}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got[r.Method] = r.URL.Query()
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			service := &repositoryService{client: c}
+			repo := &Repository{Name: "repo", Project: "PRJ"}
+
+			if err := service.AddGroup(context.Background(), repo, &tc.group); err != nil {
+				t.Fatalf("AddGroup(...): %v", err)
+			}
+			if err := service.RevokeGroup(context.Background(), repo, &tc.group); err != nil {
+				t.Fatalf("RevokeGroup(...): %v", err)
+			}
+
+			want := map[string]url.Values{
+				http.MethodPut:    tc.want,
+				http.MethodDelete: {"name": tc.want["name"]},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("\n%s\nquery: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPullRequestTemplate(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		stored := `{"template":""}`
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != apiPath+"projects/PRJ/repos/repo/settings/pull-request-template" {
+				http.NotFound(w, r)
+				return
+			}
+			if r.Method == http.MethodPut {
+				body, _ := io.ReadAll(r.Body)
+				stored = string(body)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(stored))
+		}))
+		service := &repositoryService{client: c}
+		repo := &Repository{Name: "repo", Project: "PRJ"}
+
+		want := "## Summary\n\n- [ ] tests added"
+		if err := service.SetPullRequestTemplate(context.Background(), repo, want); err != nil {
+			t.Fatalf("SetPullRequestTemplate(...): %v", err)
+		}
+		got, err := service.GetPullRequestTemplate(context.Background(), repo)
+		if err != nil {
+			t.Fatalf("GetPullRequestTemplate(...): %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetPullRequestTemplate(...): -want, +got:\n%s\n", diff)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		c := newTestClient(t, http.NotFoundHandler())
+		service := &repositoryService{client: c}
+
+		_, err := service.GetPullRequestTemplate(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+		if diff := cmp.Diff(ErrUnsupported, err, cmpopts.EquateErrors()); diff != "" {
+			t.Errorf("GetPullRequestTemplate(...): -want error, +got error:\n%s\n", diff)
+		}
+	})
+}
+
+func TestExists(t *testing.T) {
+	type want struct {
+		exists bool
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		status int
+		want   want
+	}{
+		"Exists": {
+			reason: "An existing repository should be reported as existing",
+			status: http.StatusOK,
+			want:   want{exists: true},
+		},
+		"NotFound": {
+			reason: "A missing repository should not be reported as an error",
+			status: http.StatusNotFound,
+			want:   want{exists: false},
+		},
+		"Error": {
+			reason: "Any other error should be returned",
+			status: http.StatusUnauthorized,
+			want:   want{err: ErrPermission},
+		},
+		"Forbidden": {
+			reason: "Credentials lacking permission on the repository should be reported as a permission error",
+			status: http.StatusForbidden,
+			want:   want{err: ErrPermission},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodHead || r.URL.Path != apiPath+"projects/PRJ/repos/repo" {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(tc.status)
+			}))
+
+			service := &repositoryService{client: c}
+			got := want{}
+			got.exists, got.err = service.Exists(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nExists(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRepositoryContents(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonMediaType)
+		switch r.URL.Path {
+		case "/projects/PRJ/repos/repo/sizes":
+			_, _ = w.Write([]byte(`{"repository":2048,"attachments":512}`))
+		case apiPath + "projects/PRJ/repos/repo/commits":
+			_, _ = w.Write([]byte(`{"values":[{"id":"abc"}],"size":1,"isLastPage":false}`))
+		case apiPath + "projects/PRJ/repos/empty/commits":
+			_, _ = w.Write([]byte(`{"values":[],"size":0,"isLastPage":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	service := &repositoryService{client: c}
+
+	size, err := service.GetSize(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+	if err != nil {
+		t.Fatalf("GetSize(...): %v", err)
+	}
+	if diff := cmp.Diff(int64(2048), size); diff != "" {
+		t.Errorf("GetSize(...): -want, +got:\n%s\n", diff)
+	}
+
+	for repo, want := range map[string]bool{"repo": true, "empty": false} {
+		got, err := service.HasCommits(context.Background(), &Repository{Name: repo, Project: "PRJ"})
+		if err != nil {
+			t.Fatalf("HasCommits(%s): %v", repo, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("HasCommits(%s): -want, +got:\n%s\n", repo, diff)
+		}
+	}
+}
+
+func TestUpdateArchived(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		version string
+		want    error
+	}{
+		"Supported": {
+			reason:  "A server supporting archiving should archive the repository",
+			version: "8.9.2",
+		},
+		"Unsupported": {
+			reason:  "A server before 8.0 should not be asked to archive the repository",
+			version: "7.21.0",
+			want:    ErrUnsupported,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				switch {
+				case r.URL.Path == apiPath+"application-properties":
+					_, _ = w.Write([]byte(`{"version":"` + tc.version + `"}`))
+				case r.Method == http.MethodPut && r.URL.Path == apiPath+"projects/PRJ/repos/repo":
+					_, _ = io.Copy(w, r.Body)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			service := &repositoryService{client: c}
+
+			archived := true
+			_, err := service.Update(context.Background(), &Repository{Name: "repo", Project: "PRJ", Archived: &archived})
+			if diff := cmp.Diff(tc.want, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUpdate(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	pages := map[string]string{
+		"0": `{"values":[{"id":1,"name":"api","slug":"api","project":{"key":"PRJ"}}],"isLastPage":false,"nextPageStart":1}`,
+		"1": `{"values":[{"id":2,"name":"web","slug":"web","project":{"key":"PRJ"},"public":true}],"isLastPage":true}`,
+	}
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != apiPath+"projects/PRJ/repos" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", jsonMediaType)
+		_, _ = w.Write([]byte(pages[r.URL.Query().Get("start")]))
+	}))
+
+	service := &repositoryService{client: c}
+	got, err := service.List(context.Background(), "PRJ")
+	if err != nil {
+		t.Fatalf("List(...): %v", err)
+	}
+
+	notArchived := false
+	want := []Repository{
+		{ID: 1, Name: "api", Slug: "api", Project: "PRJ", Archived: &notArchived},
+		{ID: 2, Name: "web", Slug: "web", Project: "PRJ", Public: true, Archived: &notArchived},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("List(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestListEmptyProject(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		body   string
+	}{
+		"EmptyValues": {
+			reason: "A project without repositories should list no repositories",
+			body:   `{"values":[],"size":0,"isLastPage":true}`,
+		},
+		"NullValues": {
+			reason: "A page with null values should list no repositories",
+			body:   `{"values":null,"isLastPage":true}`,
+		},
+		"OmittedValues": {
+			reason: "A page omitting the values should list no repositories",
+			body:   `{"size":0,"isLastPage":true}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			service := &repositoryService{client: c}
+			got, err := service.List(context.Background(), "PRJ")
+			if err != nil {
+				t.Fatalf("\n%s\nList(...): %v", tc.reason, err)
+			}
+			// an empty slice rather than nil, so it marshals as an empty list
+			if diff := cmp.Diff([]Repository{}, got); diff != "" {
+				t.Errorf("\n%s\nList(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetOrigin(t *testing.T) {
+	type want struct {
+		origin string
+		fork   bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		body   string
+		want   want
+	}{
+		"Fork": {
+			reason: "A repository with an origin should be reported as a fork of it",
+			body:   `{"id":2,"name":"repo","slug":"repo","project":{"key":"~USER"},"origin":{"id":1,"slug":"upstream","project":{"key":"PRJ"}}}`,
+			want:   want{origin: "PRJ/upstream", fork: true},
+		},
+		"NotFork": {
+			reason: "A repository without an origin should not be reported as a fork",
+			body:   `{"id":1,"name":"repo","slug":"repo","project":{"key":"PRJ"}}`,
+			want:   want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			service := &repositoryService{client: c}
+			got, err := service.Get(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+			if err != nil {
+				t.Fatalf("\n%s\nGet(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, want{origin: got.Origin, fork: got.IsFork()}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nGet(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetCloneURLs(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		body   string
+		want   map[string]string
+	}{
+		"Git": {
+			reason: "The http and ssh clone links of a git repository should be returned by protocol",
+			body: `{"id":1,"name":"repo","slug":"repo","scmId":"git","project":{"key":"PRJ"},"links":{"clone":[` +
+				`{"href":"ssh://git@bitbucket.example.com:7999/prj/repo.git","name":"ssh"},` +
+				`{"href":"https://bitbucket.example.com/scm/prj/repo.git","name":"http"}]}}`,
+			want: map[string]string{
+				CloneSSH:  "ssh://git@bitbucket.example.com:7999/prj/repo.git",
+				CloneHTTP: "https://bitbucket.example.com/scm/prj/repo.git",
+			},
+		},
+		"HTTPOnly": {
+			reason: "A server with ssh disabled should only return the http clone link",
+			body: `{"id":1,"name":"repo","slug":"repo","scmId":"git","project":{"key":"PRJ"},"links":{"clone":[` +
+				`{"href":"https://bitbucket.example.com/scm/prj/repo.git","name":"http"}]}}`,
+			want: map[string]string{CloneHTTP: "https://bitbucket.example.com/scm/prj/repo.git"},
+		},
+		"OtherScm": {
+			reason: "Clone links of repositories other than git should not be returned",
+			body: `{"id":1,"name":"repo","slug":"repo","scmId":"hg","project":{"key":"PRJ"},"links":{"clone":[` +
+				`{"href":"https://bitbucket.example.com/hg/prj/repo","name":"http"}]}}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			service := &repositoryService{client: c}
+			got, err := service.Get(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+			if err != nil {
+				t.Fatalf("\n%s\nGet(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got.CloneURLs); diff != "" {
+				t.Errorf("\n%s\nGet(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetCreatedDate(t *testing.T) {
+	created := time.Date(2023, 4, 1, 12, 30, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		reason string
+		body   string
+		want   *time.Time
+	}{
+		"Present": {
+			reason: "The creation date should be returned when the server sends it",
+			body:   fmt.Sprintf(`{"id":1,"name":"repo","slug":"repo","project":{"key":"PRJ"},"createdDate":%d}`, created.UnixMilli()),
+			want:   &created,
+		},
+		"Absent": {
+			reason: "No creation date should be returned when the server does not send it",
+			body:   `{"id":1,"name":"repo","slug":"repo","project":{"key":"PRJ"}}`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", jsonMediaType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			service := &repositoryService{client: c}
+			got, err := service.Get(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+			if err != nil {
+				t.Fatalf("\n%s\nGet(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got.CreatedDate); diff != "" {
+				t.Errorf("\n%s\nGet(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetWebURL(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonMediaType)
+		_, _ = w.Write([]byte(`{"id":1,"name":"repo","slug":"repo","project":{"key":"PRJ"},"links":{"self":[` +
+			`{"href":"https://bitbucket.example.com/projects/PRJ/repos/repo/browse"}]}}`))
+	}))
+
+	service := &repositoryService{client: c}
+	got, err := service.Get(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+	if err != nil {
+		t.Fatalf("Get(...): %v", err)
+	}
+	if want := "https://bitbucket.example.com/projects/PRJ/repos/repo/browse"; got.WebURL != want {
+		t.Errorf("Get(...): want web url %q, got %q", want, got.WebURL)
+	}
+}
+
+func TestCountOpenPullRequests(t *testing.T) {
+	queries := []string{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("state")+" "+r.URL.Query().Get("start"))
+		w.Header().Set("Content-Type", jsonMediaType)
+		if r.URL.Query().Get("start") == "0" {
+			_, _ = w.Write([]byte(`{"size":2,"limit":100,"isLastPage":false,"nextPageStart":2,"values":[{"id":1,"state":"OPEN"},{"id":2,"state":"OPEN"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"size":1,"limit":100,"isLastPage":true,"values":[{"id":3,"state":"OPEN"}]}`))
+	}))
+
+	service := &repositoryService{client: c}
+	got, err := service.CountOpenPullRequests(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+	if err != nil {
+		t.Fatalf("CountOpenPullRequests(...): %v", err)
+	}
+	if got != 3 {
+		t.Errorf("CountOpenPullRequests(...): want 3, got %d", got)
+	}
+	if diff := cmp.Diff([]string{"OPEN 0", "OPEN 2"}, queries); diff != "" {
+		t.Errorf("CountOpenPullRequests(...) queries: -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestCountOpenPullRequestsLimit(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		w.Header().Set("Content-Type", jsonMediaType)
+		_, _ = fmt.Fprintf(w, `{"size":2,"limit":100,"isLastPage":false,"nextPageStart":%d,"values":[{"id":1},{"id":2}]}`, start+2)
+	}))
+
+	service := &repositoryService{client: c}
+	got, err := service.CountOpenPullRequests(context.Background(), &Repository{Name: "repo", Project: "PRJ"})
+	if err != nil {
+		t.Fatalf("CountOpenPullRequests(...): %v", err)
+	}
+	if want := 2 * maxPullRequestCountPages; got != want {
+		t.Errorf("CountOpenPullRequests(...): want the floor %d, got %d", want, got)
+	}
+	if requests != maxPullRequestCountPages {
+		t.Errorf("CountOpenPullRequests(...): want %d requests, got %d", maxPullRequestCountPages, requests)
+	}
+}
@@ -0,0 +1,100 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const requiredBuildsAPI = "required-builds/latest"
+
+// RequiredBuild is a merge check requiring builds to pass before pull
+// requests to the branch selected by its matcher can be merged
+type RequiredBuild struct {
+	ID int
+	// BuildKeys are the keys of the builds that must pass
+	BuildKeys []string
+	// MatcherType is e.g. MatcherBranch
+	MatcherType string
+	// MatcherID is e.g. the branch ref, refs/heads/main
+	MatcherID string
+}
+
+type requiredBuildJson struct {
+	ID              int      `json:"id,omitempty"`
+	BuildParentKeys []string `json:"buildParentKeys"`
+	RefMatcher      struct {
+		ID   string `json:"id"`
+		Type struct {
+			ID string `json:"id"`
+		} `json:"type"`
+	} `json:"refMatcher"`
+}
+
+// GetRequiredBuilds returns the required builds merge checks of the repository
+func (service *repositoryService) GetRequiredBuilds(ctx context.Context, repository *Repository) ([]RequiredBuild, error) {
+	url := restAPIPath(requiredBuildsAPI, fmt.Sprintf("projects/%s/repos/%s/conditions", repository.Project, repository.Name))
+
+	builds := []RequiredBuild{}
+	err := service.client.getPaged(ctx, url, func(values json.RawMessage) error {
+		var entries []requiredBuildJson
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			builds = append(builds, RequiredBuild{
+				ID:          entry.ID,
+				BuildKeys:   entry.BuildParentKeys,
+				MatcherType: entry.RefMatcher.Type.ID,
+				MatcherID:   entry.RefMatcher.ID,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting repository required builds: %w", err)
+	}
+	return builds, nil
+}
+
+// AddRequiredBuild adds a required builds merge check to the repository
+func (service *repositoryService) AddRequiredBuild(ctx context.Context, repository *Repository, build *RequiredBuild) error {
+	url := restAPIPath(requiredBuildsAPI, fmt.Sprintf("projects/%s/repos/%s/condition", repository.Project, repository.Name))
+	return service.sendRequiredBuild(ctx, http.MethodPost, url, build)
+}
+
+// UpdateRequiredBuild replaces the build keys and matcher of an existing
+// required builds merge check of the repository
+func (service *repositoryService) UpdateRequiredBuild(ctx context.Context, repository *Repository, build *RequiredBuild) error {
+	url := restAPIPath(requiredBuildsAPI, fmt.Sprintf("projects/%s/repos/%s/condition/%d", repository.Project, repository.Name, build.ID))
+	return service.sendRequiredBuild(ctx, http.MethodPut, url, build)
+}
+
+// DeleteRequiredBuild removes a required builds merge check from the repository
+func (service *repositoryService) DeleteRequiredBuild(ctx context.Context, repository *Repository, id int) error {
+	url := restAPIPath(requiredBuildsAPI, fmt.Sprintf("projects/%s/repos/%s/condition/%d", repository.Project, repository.Name, id))
+	req, err := service.client.newRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for deleting repository required build: %w", err)
+	}
+	if err := service.client.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("error deleting repository required build: %w", err)
+	}
+	return nil
+}
+
+func (service *repositoryService) sendRequiredBuild(ctx context.Context, method string, url string, build *RequiredBuild) error {
+	body := requiredBuildJson{BuildParentKeys: build.BuildKeys}
+	body.RefMatcher.ID = build.MatcherID
+	body.RefMatcher.Type.ID = build.MatcherType
+
+	req, err := service.client.newRequest(method, url, body)
+	if err != nil {
+		return fmt.Errorf("error creating request for setting repository required build: %w", err)
+	}
+	if err := service.client.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("error setting repository required build: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package bitbucket
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRequiredBuilds(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		body   string
+	}
+	requests := []request{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{method: r.Method, path: r.URL.Path, body: strings.TrimSpace(string(b))})
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"values":[{"id":3,"buildParentKeys":["ci","lint"],"refMatcher":{"id":"refs/heads/main","displayId":"main","type":{"id":"BRANCH","name":"Branch"}}}],"isLastPage":true}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	service := &repositoryService{client: c}
+	repo := &Repository{Name: "repo", Project: "PRJ"}
+
+	builds, err := service.GetRequiredBuilds(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("GetRequiredBuilds(...): %v", err)
+	}
+	wantBuilds := []RequiredBuild{{ID: 3, BuildKeys: []string{"ci", "lint"}, MatcherType: MatcherBranch, MatcherID: "refs/heads/main"}}
+	if diff := cmp.Diff(wantBuilds, builds); diff != "" {
+		t.Errorf("GetRequiredBuilds(...): -want, +got:\n%s\n", diff)
+	}
+
+	build := &RequiredBuild{BuildKeys: []string{"ci"}, MatcherType: MatcherBranch, MatcherID: "refs/heads/develop"}
+	if err := service.AddRequiredBuild(context.Background(), repo, build); err != nil {
+		t.Fatalf("AddRequiredBuild(...): %v", err)
+	}
+	build.ID = 4
+	build.BuildKeys = []string{"ci", "security-scan"}
+	if err := service.UpdateRequiredBuild(context.Background(), repo, build); err != nil {
+		t.Fatalf("UpdateRequiredBuild(...): %v", err)
+	}
+	if err := service.DeleteRequiredBuild(context.Background(), repo, 3); err != nil {
+		t.Fatalf("DeleteRequiredBuild(...): %v", err)
+	}
+
+	requiredBuilds := "/rest/required-builds/latest/projects/PRJ/repos/repo/"
+	want := []request{
+		{method: http.MethodGet, path: requiredBuilds + "conditions"},
+		{method: http.MethodPost, path: requiredBuilds + "condition", body: `{"buildParentKeys":["ci"],"refMatcher":{"id":"refs/heads/develop","type":{"id":"BRANCH"}}}`},
+		{method: http.MethodPut, path: requiredBuilds + "condition/4", body: `{"buildParentKeys":["ci","security-scan"],"refMatcher":{"id":"refs/heads/develop","type":{"id":"BRANCH"}}}`},
+		{method: http.MethodDelete, path: requiredBuilds + "condition/3"},
+	}
+	if diff := cmp.Diff(want, requests, cmp.AllowUnexported(request{})); diff != "" {
+		t.Errorf("required builds requests: -want, +got:\n%s\n", diff)
+	}
+}
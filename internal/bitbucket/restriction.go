@@ -0,0 +1,119 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const branchPermissionsAPI = "branch-permissions/2.0"
+
+// Types of branch restrictions
+const (
+	RestrictionReadOnly        = "read-only"
+	RestrictionNoDeletes       = "no-deletes"
+	RestrictionFastForwardOnly = "fast-forward-only"
+	RestrictionPullRequestOnly = "pull-request-only"
+)
+
+// Types of matchers selecting the refs a restriction applies to
+const (
+	MatcherBranch  = "BRANCH"
+	MatcherPattern = "PATTERN"
+)
+
+// Restriction restricts changes to the refs selected by its matcher
+type Restriction struct {
+	ID   int
+	Type string
+	// MatcherType is e.g. MatcherBranch
+	MatcherType string
+	// MatcherID is e.g. the branch ref, refs/heads/main
+	MatcherID string
+	// Groups are the names of the groups exempted from the restriction
+	Groups []string
+}
+
+type restrictionJson struct {
+	ID      int    `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Matcher struct {
+		ID   string `json:"id"`
+		Type struct {
+			ID string `json:"id"`
+		} `json:"type"`
+	} `json:"matcher"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// GetRestrictions returns the branch restrictions of the repository
+func (service *repositoryService) GetRestrictions(ctx context.Context, repository *Repository) ([]Restriction, error) {
+	url := restAPIPath(branchPermissionsAPI, fmt.Sprintf("projects/%s/repos/%s/restrictions", repository.Project, repository.Name))
+
+	restrictions := []Restriction{}
+	err := service.client.getPaged(ctx, url, func(values json.RawMessage) error {
+		var entries []restrictionJson
+		if err := json.Unmarshal(values, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			restrictions = append(restrictions, Restriction{
+				ID:          entry.ID,
+				Type:        entry.Type,
+				MatcherType: entry.Matcher.Type.ID,
+				MatcherID:   entry.Matcher.ID,
+				Groups:      entry.Groups,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting repository restrictions: %w", err)
+	}
+	return restrictions, nil
+}
+
+// AddRestriction adds a branch restriction to the repository. A restriction
+// with the ID of an existing restriction replaces it.
+func (service *repositoryService) AddRestriction(ctx context.Context, repository *Repository, restriction *Restriction) error {
+	body := restrictionJson{ID: restriction.ID, Type: restriction.Type, Groups: restriction.Groups}
+	body.Matcher.ID = restriction.MatcherID
+	body.Matcher.Type.ID = restriction.MatcherType
+
+	url := restAPIPath(branchPermissionsAPI, fmt.Sprintf("projects/%s/repos/%s/restrictions", repository.Project, repository.Name))
+	req, err := service.client.newRequest(http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("error creating request for adding repository restriction: %w", err)
+	}
+	if err := service.client.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("error adding repository restriction: %w", err)
+	}
+	return nil
+}
+
+// GetDefaultBranch returns the ref of the default branch of the repository.
+// ErrNotFound is returned while the repository has no branches.
+func (service *repositoryService) GetDefaultBranch(ctx context.Context, repository *Repository) (string, error) {
+	req, err := service.client.newRequest(http.MethodGet, fmt.Sprintf("projects/%s/repos/%s/branches/default", repository.Project, repository.Name), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request for getting default branch: %w", err)
+	}
+
+	var branch struct {
+		ID string `json:"id"`
+	}
+	err = service.client.do(ctx, req, &branch)
+	if err == nil && branch.ID == "" {
+		// bitbucket answers 204 for an empty repository on some versions
+		err = ErrNotFound
+	}
+	if errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("error getting default branch: %w", ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting default branch: %w", err)
+	}
+	return branch.ID, nil
+}
@@ -0,0 +1,103 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRestrictions(t *testing.T) {
+	restrictionsPath := "/rest/branch-permissions/2.0/projects/PRJ/repos/repo/restrictions"
+	var added string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == restrictionsPath && r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"values":[{"id":1,"type":"no-deletes","matcher":{"id":"refs/heads/main","displayId":"main","type":{"id":"BRANCH"}}}],"isLastPage":true}`))
+		case r.URL.Path == restrictionsPath && r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			added = strings.TrimSpace(string(body))
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"id":2}`))
+		case r.URL.Path == apiPath+"projects/PRJ/repos/repo/branches/default":
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"id":"refs/heads/main","displayId":"main"}`))
+		case r.URL.Path == apiPath+"projects/PRJ/repos/empty/branches/default":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	service := &repositoryService{client: c}
+	repo := &Repository{Name: "repo", Project: "PRJ"}
+
+	got, err := service.GetRestrictions(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("GetRestrictions(...): %v", err)
+	}
+	want := []Restriction{{ID: 1, Type: RestrictionNoDeletes, MatcherType: MatcherBranch, MatcherID: "refs/heads/main"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetRestrictions(...): -want, +got:\n%s\n", diff)
+	}
+
+	if err := service.AddRestriction(context.Background(), repo, &Restriction{Type: RestrictionPullRequestOnly, MatcherType: MatcherBranch, MatcherID: "refs/heads/main"}); err != nil {
+		t.Fatalf("AddRestriction(...): %v", err)
+	}
+	if diff := cmp.Diff(`{"type":"pull-request-only","matcher":{"id":"refs/heads/main","type":{"id":"BRANCH"}}}`, added); diff != "" {
+		t.Errorf("AddRestriction(...): -want body, +got body:\n%s\n", diff)
+	}
+
+	branch, err := service.GetDefaultBranch(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("GetDefaultBranch(...): %v", err)
+	}
+	if diff := cmp.Diff("refs/heads/main", branch); diff != "" {
+		t.Errorf("GetDefaultBranch(...): -want, +got:\n%s\n", diff)
+	}
+	if _, err := service.GetDefaultBranch(context.Background(), &Repository{Name: "empty", Project: "PRJ"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDefaultBranch(empty): want ErrNotFound, got %v", err)
+	}
+}
+
+func TestRestrictionGroups(t *testing.T) {
+	restrictionsPath := "/rest/branch-permissions/2.0/projects/PRJ/repos/repo/restrictions"
+	var posted string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == restrictionsPath && r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"values":[{"id":1,"type":"no-deletes","matcher":{"id":"refs/heads/main","displayId":"main","type":{"id":"BRANCH"}},"users":[],"groups":["release-managers"],"accessKeys":[]}],"isLastPage":true}`))
+		case r.URL.Path == restrictionsPath && r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			posted = strings.TrimSpace(string(body))
+			w.Header().Set("Content-Type", jsonMediaType)
+			_, _ = w.Write([]byte(`{"id":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	service := &repositoryService{client: c}
+	repo := &Repository{Name: "repo", Project: "PRJ"}
+
+	got, err := service.GetRestrictions(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("GetRestrictions(...): %v", err)
+	}
+	want := []Restriction{{ID: 1, Type: RestrictionNoDeletes, MatcherType: MatcherBranch, MatcherID: "refs/heads/main", Groups: []string{"release-managers"}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetRestrictions(...): -want, +got:\n%s\n", diff)
+	}
+
+	update := &Restriction{ID: 1, Type: RestrictionNoDeletes, MatcherType: MatcherBranch, MatcherID: "refs/heads/main", Groups: []string{"release-managers", "admins"}}
+	if err := service.AddRestriction(context.Background(), repo, update); err != nil {
+		t.Fatalf("AddRestriction(...): %v", err)
+	}
+	if diff := cmp.Diff(`{"id":1,"type":"no-deletes","matcher":{"id":"refs/heads/main","type":{"id":"BRANCH"}},"groups":["release-managers","admins"]}`, posted); diff != "" {
+		t.Errorf("AddRestriction(...): -want body, +got body:\n%s\n", diff)
+	}
+}
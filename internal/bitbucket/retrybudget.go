@@ -0,0 +1,96 @@
+package bitbucket
+
+import (
+	"sync"
+	"time"
+)
+
+// Default retry budget shared by the clients of a bitbucket server
+const (
+	defaultRetryBudget       = 20
+	defaultRetryBudgetWindow = time.Minute
+)
+
+// retryBudget is a token bucket limiting the retries of requests failing to
+// connect, so a broad outage fails fast instead of multiplying the requests
+// sent to a struggling server. The bucket holds at most retries tokens and is
+// refilled evenly over the window. A nil budget does not limit retries.
+type retryBudget struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	// rate is the number of tokens refilled per second
+	rate float64
+	last time.Time
+	now  func() time.Time
+}
+
+func newRetryBudget(retries int, window time.Duration) *retryBudget {
+	b := &retryBudget{capacity: float64(retries), tokens: float64(retries), now: time.Now}
+	if window > 0 {
+		b.rate = float64(retries) / window.Seconds()
+	}
+	b.last = b.now()
+	return b
+}
+
+// take spends a token of the budget, reporting false when it is exhausted
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryBudgetKey identifies the budget shared by the clients of a server
+// configured alike
+type retryBudgetKey struct {
+	host    string
+	retries int
+	window  time.Duration
+}
+
+var (
+	retryBudgetsMu sync.Mutex
+	// retryBudgets are shared by the clients of a server, which are created
+	// for every reconcile, so the budget spans all of them
+	retryBudgets = map[retryBudgetKey]*retryBudget{}
+)
+
+// sharedRetryBudget returns the budget of the clients of host with the
+// same retries and window, creating it on first use
+func sharedRetryBudget(host string, retries int, window time.Duration) *retryBudget {
+	key := retryBudgetKey{host: host, retries: retries, window: window}
+	retryBudgetsMu.Lock()
+	defer retryBudgetsMu.Unlock()
+	b, ok := retryBudgets[key]
+	if !ok {
+		b = newRetryBudget(retries, window)
+		retryBudgets[key] = b
+	}
+	return b
+}
+
+// WithRetryBudget limits the retries of requests failing to connect to
+// retries per window, shared by all clients of the same bitbucket server with
+// the same budget. Once the budget is spent requests fail without retrying
+// until it refills. Zero retries disables retrying.
+func WithRetryBudget(retries int, window time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retryBudgetRetries = retries
+		c.retryBudgetWindow = window
+	}
+}
@@ -0,0 +1,84 @@
+package bitbucket
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newRetryBudget(2, time.Minute)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	var got []bool
+	for i := 0; i < 3; i++ {
+		got = append(got, b.take())
+	}
+	// half the window refills half the budget
+	now = now.Add(30 * time.Second)
+	got = append(got, b.take(), b.take())
+	// the budget never holds more than its retries
+	now = now.Add(time.Hour)
+	got = append(got, b.take(), b.take(), b.take())
+
+	want := []bool{true, true, false, true, false, true, true, false}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("take(): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestRetryBudgetStopsRetries(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	c.connectRetries = 5
+	c.connectBackoff = time.Millisecond
+	c.retryBudget = newRetryBudget(2, time.Hour)
+	c.transport().DisableKeepAlives = true
+	dials := 0
+	c.transport().DialContext = func(_ context.Context, _, _ string) (net.Conn, error) {
+		dials++
+		return nil, refused
+	}
+
+	var got []int
+	for i := 0; i < 2; i++ {
+		dials = 0
+		req, err := c.newRequest(http.MethodGet, "projects", nil)
+		if err != nil {
+			t.Fatalf("newRequest(...): %v", err)
+		}
+		if err := c.do(context.Background(), req, nil); err == nil {
+			t.Fatal("do(...): want an error connecting, got none")
+		}
+		got = append(got, dials)
+	}
+
+	// the first request spends the budget on two retries, the second fails
+	// without retrying
+	if diff := cmp.Diff([]int{3, 1}, got); diff != "" {
+		t.Errorf("do(...): -want dials, +got dials:\n%s\n", diff)
+	}
+}
+
+func TestSharedRetryBudget(t *testing.T) {
+	a := sharedRetryBudget("bitbucket.example.com", 5, time.Minute)
+	if b := sharedRetryBudget("bitbucket.example.com", 5, time.Minute); a != b {
+		t.Error("sharedRetryBudget(...): want clients of the same server to share the budget")
+	}
+	if b := sharedRetryBudget("bitbucket-2.example.com", 5, time.Minute); a == b {
+		t.Error("sharedRetryBudget(...): want clients of other servers to have their own budget")
+	}
+	if b := sharedRetryBudget("bitbucket.example.com", 10, time.Minute); a == b {
+		t.Error("sharedRetryBudget(...): want clients with another budget to have their own budget")
+	}
+}
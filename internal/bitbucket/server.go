@@ -0,0 +1,67 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ServerService provides information about the bitbucket server
+type ServerService interface {
+	GetServerInfo(context.Context) (*ServerInfo, error)
+	VerifyAccess(ctx context.Context, projectKey string) error
+	VerifyScopes(ctx context.Context, projectKey string, repository string) error
+}
+
+type serverService struct {
+	client *Client
+}
+
+// ServerInfo describes the bitbucket server, as returned by its application properties
+type ServerInfo struct {
+	Version     string `json:"version"`
+	BuildNumber string `json:"buildNumber"`
+	BuildDate   string `json:"buildDate"`
+	DisplayName string `json:"displayName"`
+}
+
+// GetServerInfo returns the version and build of the server. The information
+// is fetched once and cached on the client.
+func (service *serverService) GetServerInfo(ctx context.Context) (*ServerInfo, error) {
+	return service.client.getServerInfo(ctx)
+}
+
+// getServerInfo returns the cached server information, fetching it on first use.
+// Failures are not cached so the next call retries.
+func (c *Client) getServerInfo(ctx context.Context) (*ServerInfo, error) {
+	c.serverInfoMu.Lock()
+	defer c.serverInfoMu.Unlock()
+
+	if c.serverInfo != nil {
+		return c.serverInfo, nil
+	}
+
+	req, err := c.newRequest(http.MethodGet, "application-properties", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for getting application properties: %w", err)
+	}
+
+	info := &ServerInfo{}
+	if err := c.do(ctx, req, info); err != nil {
+		return nil, fmt.Errorf("error getting application properties: %w", err)
+	}
+	c.serverInfo = info
+	return info, nil
+}
+
+// VerifyAccess checks that the credentials can administer the repositories
+// of the project
+func (service *serverService) VerifyAccess(ctx context.Context, projectKey string) error {
+	return service.client.VerifyAccess(ctx, projectKey)
+}
+
+// VerifyScopes checks that the effective permissions of the credentials allow
+// administering the repository
+func (service *serverService) VerifyScopes(ctx context.Context, projectKey string, repository string) error {
+	return service.client.VerifyScopes(ctx, projectKey, repository)
+}
@@ -0,0 +1,40 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// applicationProperties is a sample response of the application properties api
+const applicationProperties = `{"version":"8.9.2","buildNumber":"8009002","buildDate":"1685513362427","displayName":"Bitbucket"}`
+
+func TestGetServerInfo(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != apiPath+"application-properties" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		w.Header().Set("Content-Type", jsonMediaType)
+		_, _ = w.Write([]byte(applicationProperties))
+	}))
+	service := &serverService{client: c}
+
+	want := &ServerInfo{Version: "8.9.2", BuildNumber: "8009002", BuildDate: "1685513362427", DisplayName: "Bitbucket"}
+	for i := 0; i < 2; i++ {
+		got, err := service.GetServerInfo(context.Background())
+		if err != nil {
+			t.Fatalf("GetServerInfo(...): %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetServerInfo(...): -want, +got:\n%s\n", diff)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("GetServerInfo(...): want the server info fetched once, got %d requests", requests)
+	}
+}
@@ -3,12 +3,14 @@ package bitbucket
 type BitBucketService struct {
 	Projects     ProjectService
 	Repositories RepositoryService
+	Server       ServerService
 }
 
 func NewService(client *Client) (*BitBucketService, error) {
 	service := BitBucketService{
 		Projects:     &projectService{client: client},
 		Repositories: &repositoryService{client: client},
+		Server:       &serverService{client: client},
 	}
 	return &service, nil
 }
@@ -22,18 +22,21 @@ import (
 
 	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/config"
 	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/project"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/projectpermission"
 	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/repository"
 )
 
 // Setup creates all BitbucketServer controllers with the supplied logger and adds them to
-// the supplied manager.
-func Setup(mgr ctrl.Manager, o controller.Options) error {
-	for _, setup := range []func(ctrl.Manager, controller.Options) error{
+// the supplied manager. The setup options, e.g. config.WithCredentialExtractor,
+// customize the controllers.
+func Setup(mgr ctrl.Manager, o controller.Options, opts ...config.SetupOption) error {
+	for _, setup := range []func(ctrl.Manager, controller.Options, ...config.SetupOption) error{
 		config.Setup,
 		project.Setup,
+		projectpermission.Setup,
 		repository.Setup,
 	} {
-		if err := setup(mgr, o); err != nil {
+		if err := setup(mgr, o, opts...); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	errInvalidConnectionKey  = "connection detail key %s cannot be renamed to %q: %s"
+	errConnectionKeyConflict = "connection detail keys %s and %s are both published as %s"
+)
+
+// ConnectionKeys renames the connection detail keys published by a managed
+// resource. Keys not in the map keep their name.
+type ConnectionKeys map[string]string
+
+// NewConnectionKeys returns the renaming of the connection detail keys of a
+// managed resource publishing keys. Renaming keys of other kinds of managed
+// resources is ignored, so one mapping can be shared by all of them. It is an
+// error to rename a key to an invalid secret key or to the name another key
+// is published as.
+func NewConnectionKeys(mapping map[string]string, keys ...string) (ConnectionKeys, error) {
+	published := map[string]string{}
+	renamed := ConnectionKeys{}
+	for _, key := range keys {
+		name := key
+		if to, ok := mapping[key]; ok {
+			if errs := validation.IsConfigMapKey(to); len(errs) > 0 {
+				return nil, errors.Errorf(errInvalidConnectionKey, key, to, strings.Join(errs, ", "))
+			}
+			name = to
+			renamed[key] = to
+		}
+		if other, ok := published[name]; ok {
+			return nil, errors.Errorf(errConnectionKeyConflict, other, key, name)
+		}
+		published[name] = key
+	}
+	return renamed, nil
+}
+
+// Apply returns the connection details with their keys renamed.
+func (k ConnectionKeys) Apply(cd managed.ConnectionDetails) managed.ConnectionDetails {
+	out := make(managed.ConnectionDetails, len(cd))
+	for key, value := range cd {
+		if to, ok := k[key]; ok {
+			key = to
+		}
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func TestConnectionKeys(t *testing.T) {
+	type want struct {
+		cd  managed.ConnectionDetails
+		err error
+	}
+
+	cd := managed.ConnectionDetails{"projectKey": []byte("PRJ"), "repositorySlug": []byte("repo")}
+
+	cases := map[string]struct {
+		reason  string
+		mapping map[string]string
+		want    want
+	}{
+		"Default": {
+			reason: "Without a mapping the keys should keep their name",
+			want:   want{cd: cd},
+		},
+		"Rename": {
+			reason:  "Mapped keys should be renamed and keys of other managed resources ignored",
+			mapping: map[string]string{"repositorySlug": "slug", "other": "projectKey"},
+			want:    want{cd: managed.ConnectionDetails{"projectKey": []byte("PRJ"), "slug": []byte("repo")}},
+		},
+		"Swap": {
+			reason:  "Keys should be able to swap names",
+			mapping: map[string]string{"repositorySlug": "projectKey", "projectKey": "repositorySlug"},
+			want:    want{cd: managed.ConnectionDetails{"repositorySlug": []byte("PRJ"), "projectKey": []byte("repo")}},
+		},
+		"CollisionWithDefault": {
+			reason:  "Renaming a key to the default name of another key should be rejected",
+			mapping: map[string]string{"repositorySlug": "projectKey"},
+			want:    want{err: errors.Errorf(errConnectionKeyConflict, "projectKey", "repositorySlug", "projectKey")},
+		},
+		"Collision": {
+			reason:  "Renaming two keys to the same name should be rejected",
+			mapping: map[string]string{"projectKey": "location", "repositorySlug": "location"},
+			want:    want{err: errors.Errorf(errConnectionKeyConflict, "projectKey", "repositorySlug", "location")},
+		},
+		"Invalid": {
+			reason:  "Renaming a key to an invalid secret key should be rejected",
+			mapping: map[string]string{"repositorySlug": "repository slug"},
+			want:    want{err: errors.Errorf(errInvalidConnectionKey, "repositorySlug", "repository slug", "a valid config key must consist of alphanumeric characters, '-', '_' or '.' (e.g. 'key.name',  or 'KEY_NAME',  or 'key-name', regex used for validation is '[-._a-zA-Z0-9]+')")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			keys, err := NewConnectionKeys(tc.mapping, "projectKey", "repositorySlug")
+			got.err = err
+			if err == nil {
+				got.cd = keys.Apply(cd)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewConnectionKeys(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
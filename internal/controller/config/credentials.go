@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
+)
+
+const (
+	errGetCredentials        = "cannot get credentials"
+	errGetCredentialOverride = "cannot get credentials of the managed resource"
+	errEmptyCredential       = "key %s of secret %s/%s holding the credentials of the managed resource is empty"
+)
+
+// A CredentialExtractor extracts the credentials of a ProviderConfig, e.g. to
+// fetch a short-lived token from a vault rather than reading a secret.
+type CredentialExtractor interface {
+	Extract(ctx context.Context, kube client.Client, pc *v1alpha1.ProviderConfig) ([]byte, error)
+}
+
+// A CredentialExtractorFn is a function that satisfies CredentialExtractor.
+type CredentialExtractorFn func(ctx context.Context, kube client.Client, pc *v1alpha1.ProviderConfig) ([]byte, error)
+
+// Extract calls the function.
+func (fn CredentialExtractorFn) Extract(ctx context.Context, kube client.Client, pc *v1alpha1.ProviderConfig) ([]byte, error) {
+	return fn(ctx, kube, pc)
+}
+
+// CommonCredentialExtractor extracts the credentials from the source
+// configured in the ProviderConfig. It is the default CredentialExtractor.
+var CommonCredentialExtractor CredentialExtractor = CredentialExtractorFn(func(ctx context.Context, kube client.Client, pc *v1alpha1.ProviderConfig) ([]byte, error) {
+	cd := pc.Spec.Credentials
+	return resource.CommonCredentialExtractor(ctx, cd.Source, kube, cd.CommonCredentialSelectors)
+})
+
+// Credentials returns the credentials a managed resource connects to
+// bitbucket with. Those in the secret referenced by the managed resource
+// override the credentials the extractor, CommonCredentialExtractor when nil,
+// extracts from its ProviderConfig.
+func Credentials(ctx context.Context, kube client.Client, extractor CredentialExtractor, pc *v1alpha1.ProviderConfig, override *xpv1.SecretKeySelector) ([]byte, error) {
+	if override == nil {
+		if extractor == nil {
+			extractor = CommonCredentialExtractor
+		}
+		data, err := extractor.Extract(ctx, kube, pc)
+		return data, errors.Wrap(err, errGetCredentials)
+	}
+
+	data, err := resource.ExtractSecret(ctx, kube, xpv1.CommonCredentialSelectors{SecretRef: override})
+	if err != nil {
+		return nil, errors.Wrap(err, errGetCredentialOverride)
+	}
+	// a missing key would otherwise silently connect without credentials
+	if len(data) == 0 {
+		return nil, errors.Errorf(errEmptyCredential, override.Key, override.Namespace, override.Name)
+	}
+	return data, nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
+)
+
+func TestCredentials(t *testing.T) {
+	type want struct {
+		creds string
+		err   error
+	}
+
+	// secrets holds the secrets in the fake cluster
+	secrets := map[string]map[string][]byte{
+		"provider":   {"credentials": []byte("provider-token")},
+		"repository": {"token": []byte("repository-token")},
+	}
+	kube := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+		data, ok := secrets[key.Name]
+		if !ok {
+			return errors.New("not found")
+		}
+		obj.(*corev1.Secret).Data = data
+		return nil
+	}}
+
+	pc := &v1alpha1.ProviderConfig{Spec: v1alpha1.ProviderConfigSpec{Credentials: v1alpha1.ProviderCredentials{
+		Source: xpv1.CredentialsSourceSecret,
+		CommonCredentialSelectors: xpv1.CommonCredentialSelectors{SecretRef: &xpv1.SecretKeySelector{
+			SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "provider"},
+			Key:             "credentials",
+		}},
+	}}}
+	ref := func(name, key string) *xpv1.SecretKeySelector {
+		return &xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "team", Name: name}, Key: key}
+	}
+
+	// vault hands out a short-lived token instead of reading a secret
+	vault := CredentialExtractorFn(func(_ context.Context, _ client.Client, _ *v1alpha1.ProviderConfig) ([]byte, error) {
+		return []byte("vault-token"), nil
+	})
+	errBoom := errors.New("boom")
+	failing := CredentialExtractorFn(func(_ context.Context, _ client.Client, _ *v1alpha1.ProviderConfig) ([]byte, error) {
+		return nil, errBoom
+	})
+
+	cases := map[string]struct {
+		reason    string
+		extractor CredentialExtractor
+		override  *xpv1.SecretKeySelector
+		want      want
+	}{
+		"ProviderConfig": {
+			reason: "Without an override the credentials of the ProviderConfig should be used",
+			want:   want{creds: "provider-token"},
+		},
+		"CommonExtractor": {
+			reason:    "The common extractor should read the credentials configured in the ProviderConfig",
+			extractor: CommonCredentialExtractor,
+			want:      want{creds: "provider-token"},
+		},
+		"CustomExtractor": {
+			reason:    "A custom extractor should provide the credentials of the ProviderConfig",
+			extractor: vault,
+			want:      want{creds: "vault-token"},
+		},
+		"CustomExtractorFailed": {
+			reason:    "Errors of a custom extractor should be returned",
+			extractor: failing,
+			want:      want{err: errors.Wrap(errBoom, errGetCredentials)},
+		},
+		"CustomExtractorOverride": {
+			reason:    "The credentials referenced by the managed resource should override a custom extractor",
+			extractor: vault,
+			override:  ref("repository", "token"),
+			want:      want{creds: "repository-token"},
+		},
+		"Override": {
+			reason:   "The credentials referenced by the managed resource should override the ProviderConfig",
+			override: ref("repository", "token"),
+			want:     want{creds: "repository-token"},
+		},
+		"MissingKey": {
+			reason:   "An override without credentials under the key should not fall back to no credentials",
+			override: ref("repository", "credentials"),
+			want:     want{err: errors.Errorf(errEmptyCredential, "credentials", "team", "repository")},
+		},
+		"MissingSecret": {
+			reason:   "An override referencing a missing secret should return an error",
+			override: ref("missing", "token"),
+			want: want{err: errors.Wrap(
+				errors.Wrap(errors.New("not found"), "cannot get credentials secret"), errGetCredentialOverride)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			creds, err := Credentials(context.Background(), kube, tc.extractor, pc, tc.override)
+			got := want{creds: string(creds), err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCredentials(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewSetupOptions(t *testing.T) {
+	vault := CredentialExtractorFn(func(_ context.Context, _ client.Client, _ *v1alpha1.ProviderConfig) ([]byte, error) {
+		return []byte("vault-token"), nil
+	})
+
+	cases := map[string]struct {
+		reason string
+		opts   []SetupOption
+		want   string
+	}{
+		"Default": {
+			reason: "The credentials should be extracted from the ProviderConfig by default",
+			want:   "provider-token",
+		},
+		"CustomExtractor": {
+			reason: "A custom extractor should replace the default one",
+			opts:   []SetupOption{WithCredentialExtractor(vault)},
+			want:   "vault-token",
+		},
+	}
+
+	kube := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		obj.(*corev1.Secret).Data = map[string][]byte{"credentials": []byte("provider-token")}
+		return nil
+	}}
+	pc := &v1alpha1.ProviderConfig{Spec: v1alpha1.ProviderConfigSpec{Credentials: v1alpha1.ProviderCredentials{
+		Source:                    xpv1.CredentialsSourceSecret,
+		CommonCredentialSelectors: xpv1.CommonCredentialSelectors{SecretRef: &xpv1.SecretKeySelector{Key: "credentials"}},
+	}}}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			creds, err := NewSetupOptions(tc.opts...).CredentialExtractor.Extract(context.Background(), kube, pc)
+			if err != nil {
+				t.Fatalf("\n%s\nExtract(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, string(creds)); diff != "" {
+				t.Errorf("\n%s\nExtract(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+const (
+	errTLSMinVersion  = "unsupported TLS minimum version %q"
+	errTLSCipherSuite = "unsupported TLS cipher suite %q"
+	errGetClientCert  = "cannot get client certificate secret"
+	errClientCert     = "cannot load client certificate from secret %s/%s"
+	errSOCKS5Proxy    = "invalid socks5 proxy %q, expected socks5://[user:password@]host:port"
+	errFailoverURL    = "invalid failover url %q, expected http(s)://host[:port][/path]"
+)
+
+// tlsVersions maps the TLS versions accepted in a ProviderConfig to their tls package value
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ClientOptions translates the optional settings of a ProviderConfig into
+// options for the bitbucket client. An error is returned for invalid settings.
+func ClientOptions(ctx context.Context, kube client.Client, spec v1alpha1.ProviderConfigSpec) ([]bitbucket.ClientOption, error) {
+	opts := []bitbucket.ClientOption{}
+	if len(spec.FailoverURLs) > 0 {
+		for _, raw := range spec.FailoverURLs {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, errors.Errorf(errFailoverURL, raw)
+			}
+		}
+		opts = append(opts, bitbucket.WithFailoverURLs(spec.FailoverURLs...))
+	}
+	if spec.MaxResponseBodySize != nil {
+		opts = append(opts, bitbucket.WithMaxResponseSize(*spec.MaxResponseBodySize))
+	}
+	if spec.AcceptLanguage != nil {
+		opts = append(opts, bitbucket.WithAcceptLanguage(*spec.AcceptLanguage))
+	}
+	if spec.TLSMinVersion != nil {
+		version, ok := tlsVersions[*spec.TLSMinVersion]
+		if !ok {
+			return nil, errors.Errorf(errTLSMinVersion, *spec.TLSMinVersion)
+		}
+		opts = append(opts, bitbucket.WithTLSMinVersion(version))
+	}
+	if len(spec.TLSCipherSuites) > 0 {
+		suites, err := cipherSuites(spec.TLSCipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, bitbucket.WithTLSCipherSuites(suites))
+	}
+	if spec.DialTimeout != nil {
+		opts = append(opts, bitbucket.WithDialTimeout(spec.DialTimeout.Duration))
+	}
+	if spec.SOCKS5Proxy != nil {
+		dialer, err := socks5Dialer(*spec.SOCKS5Proxy, spec.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, bitbucket.WithProxyDialer(dialer))
+	}
+	if spec.ResponseHeaderTimeout != nil {
+		opts = append(opts, bitbucket.WithResponseHeaderTimeout(spec.ResponseHeaderTimeout.Duration))
+	}
+	if spec.DisablePing != nil && *spec.DisablePing {
+		opts = append(opts, bitbucket.WithoutPing())
+	}
+	if spec.PingEndpoint != nil {
+		opts = append(opts, bitbucket.WithPingEndpoint(*spec.PingEndpoint))
+	}
+	if spec.RetryBudget != nil {
+		opts = append(opts, bitbucket.WithRetryBudget(spec.RetryBudget.Retries, spec.RetryBudget.Window.Duration))
+	}
+	if spec.DisableRedirects != nil && *spec.DisableRedirects {
+		opts = append(opts, bitbucket.WithoutRedirects())
+	}
+	if spec.ClientCertificateSecretRef != nil {
+		cert, err := clientCertificate(ctx, kube, spec.ClientCertificateSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, bitbucket.WithClientCertificate(cert))
+	}
+	return opts, nil
+}
+
+// socks5Dialer returns a dialer connecting through the SOCKS5 proxy at the
+// url. The dial timeout, if any, applies to connecting to the proxy.
+func socks5Dialer(raw string, timeout *metav1.Duration) (proxy.ContextDialer, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "socks5" && u.Scheme != "socks5h") || u.Hostname() == "" || u.Port() == "" {
+		return nil, errors.Errorf(errSOCKS5Proxy, raw)
+	}
+	forward := &net.Dialer{}
+	if timeout != nil {
+		forward.Timeout = timeout.Duration
+	}
+	dialer, err := proxy.FromURL(u, forward)
+	if err != nil {
+		return nil, errors.Wrapf(err, errSOCKS5Proxy, raw)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.Errorf(errSOCKS5Proxy, raw)
+	}
+	return contextDialer, nil
+}
+
+// clientCertificate loads the certificate and key pair of a kubernetes.io/tls secret
+func clientCertificate(ctx context.Context, kube client.Client, ref *xpv1.SecretReference) (tls.Certificate, error) {
+	secret := &corev1.Secret{}
+	if err := kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+		return tls.Certificate{}, errors.Wrap(err, errGetClientCert)
+	}
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return tls.Certificate{}, errors.Wrapf(err, errClientCert, ref.Namespace, ref.Name)
+	}
+	return cert, nil
+}
+
+// cipherSuites looks up the ids of the named cipher suites, only secure
+// cipher suites are accepted
+func cipherSuites(names []string) ([]uint16, error) {
+	known := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, errors.Errorf(errTLSCipherSuite, name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// SetupOptions configure the controllers of the provider beyond the common
+// controller options.
+type SetupOptions struct {
+	// CredentialExtractor extracts the credentials of ProviderConfigs
+	CredentialExtractor CredentialExtractor
+}
+
+// A SetupOption configures the controllers of the provider.
+type SetupOption func(*SetupOptions)
+
+// WithCredentialExtractor replaces the CommonCredentialExtractor the
+// credentials of ProviderConfigs are extracted with.
+func WithCredentialExtractor(e CredentialExtractor) SetupOption {
+	return func(o *SetupOptions) {
+		o.CredentialExtractor = e
+	}
+}
+
+// NewSetupOptions returns the setup options with the supplied options applied
+// to the defaults.
+func NewSetupOptions(opts ...SetupOption) SetupOptions {
+	o := SetupOptions{CredentialExtractor: CommonCredentialExtractor}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
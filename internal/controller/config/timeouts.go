@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	errUnknownOperation = "unknown operation %q in operation-timeouts, expected one of observe, create, update or delete"
+	errOperationTimeout = "timeout of operation %s must be positive, got %s"
+)
+
+// Operations on a managed resource that can be limited in time
+const (
+	OperationObserve = "observe"
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+)
+
+// timedExternal runs each operation of the wrapped client with the timeout of
+// the operation, if any
+type timedExternal struct {
+	managed.ExternalClient
+	timeouts map[string]time.Duration
+}
+
+// WithTimeouts wraps the client so its operations are limited by the
+// operation-timeouts of a ProviderConfig. The client is returned as is when
+// there are no timeouts. It is an error to limit an unknown operation.
+func WithTimeouts(e managed.ExternalClient, timeouts map[string]metav1.Duration) (managed.ExternalClient, error) {
+	if len(timeouts) == 0 {
+		return e, nil
+	}
+	t := &timedExternal{ExternalClient: e, timeouts: map[string]time.Duration{}}
+	for op, d := range timeouts {
+		switch op {
+		case OperationObserve, OperationCreate, OperationUpdate, OperationDelete:
+		default:
+			return nil, errors.Errorf(errUnknownOperation, op)
+		}
+		if d.Duration <= 0 {
+			return nil, errors.Errorf(errOperationTimeout, op, d.Duration)
+		}
+		t.timeouts[op] = d.Duration
+	}
+	return t, nil
+}
+
+// context returns the context to run the operation with
+func (t *timedExternal) context(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	if d, ok := t.timeouts[op]; ok {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
+
+func (t *timedExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	ctx, cancel := t.context(ctx, OperationObserve)
+	defer cancel()
+	return t.ExternalClient.Observe(ctx, mg)
+}
+
+func (t *timedExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	ctx, cancel := t.context(ctx, OperationCreate)
+	defer cancel()
+	return t.ExternalClient.Create(ctx, mg)
+}
+
+func (t *timedExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	ctx, cancel := t.context(ctx, OperationUpdate)
+	defer cancel()
+	return t.ExternalClient.Update(ctx, mg)
+}
+
+func (t *timedExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	ctx, cancel := t.context(ctx, OperationDelete)
+	defer cancel()
+	return t.ExternalClient.Delete(ctx, mg)
+}
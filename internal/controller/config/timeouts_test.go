@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// deadlineExternal records the time left until the deadline of each operation
+type deadlineExternal struct {
+	left map[string]time.Duration
+}
+
+func (d *deadlineExternal) record(ctx context.Context, op string) {
+	if deadline, ok := ctx.Deadline(); ok {
+		d.left[op] = time.Until(deadline)
+	}
+}
+
+func (d *deadlineExternal) Observe(ctx context.Context, _ resource.Managed) (managed.ExternalObservation, error) {
+	d.record(ctx, OperationObserve)
+	return managed.ExternalObservation{}, nil
+}
+
+func (d *deadlineExternal) Create(ctx context.Context, _ resource.Managed) (managed.ExternalCreation, error) {
+	d.record(ctx, OperationCreate)
+	return managed.ExternalCreation{}, nil
+}
+
+func (d *deadlineExternal) Update(ctx context.Context, _ resource.Managed) (managed.ExternalUpdate, error) {
+	d.record(ctx, OperationUpdate)
+	return managed.ExternalUpdate{}, nil
+}
+
+func (d *deadlineExternal) Delete(ctx context.Context, _ resource.Managed) error {
+	d.record(ctx, OperationDelete)
+	return nil
+}
+
+func TestWithTimeouts(t *testing.T) {
+	type want struct {
+		// timeouts are the timeouts of the operations, rounded up to the minute
+		timeouts map[string]time.Duration
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		timeouts map[string]metav1.Duration
+		want     want
+	}{
+		"None": {
+			reason: "Without timeouts no operation should have a deadline",
+			want:   want{timeouts: map[string]time.Duration{}},
+		},
+		"PerOperation": {
+			reason: "Each operation should run with its own timeout",
+			timeouts: map[string]metav1.Duration{
+				OperationObserve: {Duration: time.Minute},
+				OperationCreate:  {Duration: 5 * time.Minute},
+				OperationDelete:  {Duration: 2 * time.Minute},
+			},
+			want: want{timeouts: map[string]time.Duration{
+				OperationObserve: time.Minute,
+				OperationCreate:  5 * time.Minute,
+				OperationDelete:  2 * time.Minute,
+			}},
+		},
+		"UnknownOperation": {
+			reason:   "Limiting an unknown operation should be rejected",
+			timeouts: map[string]metav1.Duration{"connect": {Duration: time.Minute}},
+			want:     want{err: errors.Errorf(errUnknownOperation, "connect")},
+		},
+		"NotPositive": {
+			reason:   "A timeout that is not positive should be rejected",
+			timeouts: map[string]metav1.Duration{OperationUpdate: {}},
+			want:     want{err: errors.Errorf(errOperationTimeout, OperationUpdate, time.Duration(0))},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &deadlineExternal{left: map[string]time.Duration{}}
+			e, err := WithTimeouts(d, tc.timeouts)
+			got := want{err: err}
+			if err == nil {
+				ctx := context.Background()
+				_, _ = e.Observe(ctx, nil)
+				_, _ = e.Create(ctx, nil)
+				_, _ = e.Update(ctx, nil)
+				_ = e.Delete(ctx, nil)
+				got.timeouts = map[string]time.Duration{}
+				for op, left := range d.left {
+					got.timeouts[op] = left.Round(time.Minute)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nWithTimeouts(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -30,6 +30,8 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,37 +39,41 @@ import (
 	"github.com/MrVinkel/provider-bitbucketserver/apis/project/v1alpha1"
 	apisv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
 	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/config"
 	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/features"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/graceful"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/jitter"
 )
 
 const (
 	errNotProject   = "managed resource is not a Project custom resource"
 	errTrackPCUsage = "cannot track ProviderConfig usage"
 	errGetPC        = "cannot get ProviderConfig"
-	errGetCreds     = "cannot get credentials"
+	errPCPending    = "ProviderConfig %s does not exist yet"
+	errCredsPending = "credentials secret does not exist yet"
 
-	errNewClient = "cannot create new Service"
+	errNewClient     = "cannot create new Service"
+	errClientOptions = "cannot configure client from ProviderConfig"
+
+	errGetDefaultPermission = "cannot get project default permission"
 )
 
 // A BitbucketService provides operations against bitbucket
 var (
-	bitbucketService = func(baseURL string, creds []byte, caCertPath *string) (*bitbucket.BitBucketService, error) {
-		client, err := bitbucket.NewClient(baseURL, string(creds), caCertPath)
-		if err != nil {
-			// crash if we get an error setting up client
-			log.Fatalln(err)
-		}
-		service, err := bitbucket.NewService(client)
+	bitbucketService = func(baseURL string, creds []byte, caCertPath *string, opts ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+		// errors, e.g. credentials rejected by the ping, fail the connect so
+		// they are reported on the project
+		client, err := bitbucket.NewClient(baseURL, string(creds), caCertPath, opts...)
 		if err != nil {
-			// crash if we get an error setting up bitbucket service
-			log.Fatalln(err)
+			return nil, err
 		}
-		return service, err
+		return bitbucket.NewService(client)
 	}
 )
 
 // Setup adds a controller that reconciles Project managed resources.
-func Setup(mgr ctrl.Manager, o controller.Options) error {
+func Setup(mgr ctrl.Manager, o controller.Options, setupOpts ...config.SetupOption) error {
+	so := config.NewSetupOptions(setupOpts...)
 	log.Printf("Setting up controller for %s\n", v1alpha1.ProjectGroupKind)
 	name := managed.ControllerName(v1alpha1.ProjectGroupKind)
 
@@ -80,6 +86,7 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		managed.WithExternalConnecter(&connector{
 			kube:         mgr.GetClient(),
 			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			credentials:  so.CredentialExtractor,
 			newServiceFn: bitbucketService}),
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
 		managed.WithPollInterval(o.PollInterval),
@@ -98,7 +105,7 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		WithOptions(o.ForControllerRuntime()).
 		WithEventFilter(resource.DesiredStateChanged()).
 		For(&v1alpha1.Project{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+		Complete(jitter.NewReconciler(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter)))
 }
 
 // A connector is expected to produce an ExternalClient when its Connect method
@@ -106,7 +113,8 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 type connector struct {
 	kube         client.Client
 	usage        resource.Tracker
-	newServiceFn func(baseURL string, creds []byte, caCertPath *string) (*bitbucket.BitBucketService, error)
+	credentials  config.CredentialExtractor
+	newServiceFn func(baseURL string, creds []byte, caCertPath *string, opts ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error)
 }
 
 // Connect typically produces an ExternalClient by:
@@ -126,21 +134,41 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 
 	pc := &apisv1alpha1.ProviderConfig{}
 	if err := c.kube.Get(ctx, types.NamespacedName{Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
+		// the reconciler requeues with backoff until the ProviderConfig exists
+		if kerrors.IsNotFound(err) {
+			cr.SetConditions(v1alpha1.ProviderConfigPending())
+			return nil, errors.Errorf(errPCPending, cr.GetProviderConfigReference().Name)
+		}
 		return nil, errors.Wrap(err, errGetPC)
 	}
 
-	cd := pc.Spec.Credentials
-	data, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	data, err := config.Credentials(ctx, c.kube, c.credentials, pc, nil)
+	if kerrors.IsNotFound(err) {
+		cr.SetConditions(v1alpha1.CredentialsPending())
+		return nil, errors.Wrap(err, errCredsPending)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cr.GetCondition(v1alpha1.TypePending).Status == corev1.ConditionTrue {
+		cr.SetConditions(v1alpha1.NotPending())
+	}
+
+	opts, err := config.ClientOptions(ctx, c.kube, pc.Spec)
 	if err != nil {
-		return nil, errors.Wrap(err, errGetCreds)
+		return nil, errors.Wrap(err, errClientOptions)
 	}
 
-	svc, err := c.newServiceFn(pc.Spec.BaseURL, data, pc.Spec.CaCertPath)
+	svc, err := c.newServiceFn(pc.Spec.BaseURL, data, pc.Spec.CaCertPath, opts...)
 	if err != nil {
 		return nil, errors.Wrap(err, errNewClient)
 	}
 
-	return &external{service: svc}, nil
+	e, err := config.WithTimeouts(&external{service: svc}, pc.Spec.OperationTimeouts)
+	if err != nil {
+		return nil, errors.Wrap(err, errClientOptions)
+	}
+	return e, nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -182,6 +210,19 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		}, nil
 	}
 
+	if dp := cr.Spec.ForProvider.DefaultPermission; dp != nil {
+		permission, err := c.service.Projects.GetDefaultPermission(ctx, p.Key)
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errGetDefaultPermission)
+		}
+		if permission != *dp {
+			return managed.ExternalObservation{
+				ResourceExists:   true,
+				ResourceUpToDate: false,
+			}, nil
+		}
+	}
+
 	cr.Status.AtProvider.ID = p.ID
 
 	return managed.ExternalObservation{
@@ -207,6 +248,10 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalCreation{}, errors.New(errNotProject)
 	}
 
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
 	cr.SetConditions(xpv1.Creating())
 
 	log.Printf("Attempting to create Project %s\n", cr.Name)
@@ -223,6 +268,12 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		log.Println(err)
 		return managed.ExternalCreation{}, err
 	}
+	if dp := cr.Spec.ForProvider.DefaultPermission; dp != nil {
+		if err := c.service.Projects.SetDefaultPermission(ctx, p.Key, *dp); err != nil {
+			log.Println(err)
+			return managed.ExternalCreation{}, err
+		}
+	}
 	log.Printf("Finished creating Project %+v\n", p)
 	meta.SetExternalName(cr, fmt.Sprint(p.Key))
 
@@ -235,6 +286,10 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalUpdate{}, errors.New(errNotProject)
 	}
 
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
 	log.Printf("Attempting to update Project %s\n", cr.Name)
 
 	updateReq := &bitbucket.UpdateProjectRequest{
@@ -249,6 +304,13 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalUpdate{}, err
 	}
 
+	if dp := cr.Spec.ForProvider.DefaultPermission; dp != nil {
+		if err := c.service.Projects.SetDefaultPermission(ctx, updateReq.Key, *dp); err != nil {
+			log.Println(err)
+			return managed.ExternalUpdate{}, err
+		}
+	}
+
 	log.Printf("Finished updating Project %+v\n", p)
 
 	return managed.ExternalUpdate{ConnectionDetails: managed.ConnectionDetails{}}, nil
@@ -260,6 +322,10 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 		return errors.New(errNotProject)
 	}
 
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
 	log.Printf("Attempting to delete Project %s\n", cr.Name)
 
 	cr.SetConditions(xpv1.Deleting())
@@ -0,0 +1,277 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectpermission
+
+import (
+	"context"
+	"log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/connection"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/projectpermission/v1alpha1"
+	apisv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/config"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/features"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/graceful"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/jitter"
+)
+
+const (
+	errNotProjectPermission = "managed resource is not a ProjectPermission custom resource"
+	errTrackPCUsage         = "cannot track ProviderConfig usage"
+	errGetPC                = "cannot get ProviderConfig"
+	errPCPending            = "ProviderConfig %s does not exist yet"
+	errCredsPending         = "credentials secret does not exist yet"
+
+	errNewClient     = "cannot create new Service"
+	errClientOptions = "cannot configure client from ProviderConfig"
+
+	errGetPermission    = "cannot get project permission"
+	errGrantPermission  = "cannot grant project permission"
+	errRevokePermission = "cannot revoke project permission"
+)
+
+// A BitbucketService provides operations against bitbucket
+var (
+	bitbucketService = func(baseURL string, creds []byte, caCertPath *string, opts ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+		// errors, e.g. credentials rejected by the ping, fail the connect so
+		// they are reported on the project permission
+		client, err := bitbucket.NewClient(baseURL, string(creds), caCertPath, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return bitbucket.NewService(client)
+	}
+)
+
+// Setup adds a controller that reconciles ProjectPermission managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options, setupOpts ...config.SetupOption) error {
+	so := config.NewSetupOptions(setupOpts...)
+	log.Printf("Setting up controller for %s\n", v1alpha1.ProjectPermissionGroupKind)
+	name := managed.ControllerName(v1alpha1.ProjectPermissionGroupKind)
+
+	cps := []managed.ConnectionPublisher{managed.NewAPISecretPublisher(mgr.GetClient(), mgr.GetScheme())}
+	if o.Features.Enabled(features.EnableAlphaExternalSecretStores) {
+		cps = append(cps, connection.NewDetailsManager(mgr.GetClient(), apisv1alpha1.StoreConfigGroupVersionKind))
+	}
+
+	opts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			credentials:  so.CredentialExtractor,
+			newServiceFn: bitbucketService}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithConnectionPublishers(cps...),
+	}
+
+	if o.Features.Enabled(features.EnableAlphaManagementPolicies) {
+		opts = append(opts, managed.WithManagementPolicies())
+	}
+
+	r := managed.NewReconciler(mgr, resource.ManagedKind(v1alpha1.ProjectPermissionGroupVersionKind), opts...)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&v1alpha1.ProjectPermission{}).
+		Complete(jitter.NewReconciler(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter)))
+}
+
+// A connector is expected to produce an ExternalClient when its Connect method
+// is called.
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	credentials  config.CredentialExtractor
+	newServiceFn func(baseURL string, creds []byte, caCertPath *string, opts ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error)
+}
+
+// Connect typically produces an ExternalClient by:
+// 1. Tracking that the managed resource is using a ProviderConfig.
+// 2. Getting the managed resource's ProviderConfig.
+// 3. Getting the credentials specified by the ProviderConfig.
+// 4. Using the credentials to form a client.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*v1alpha1.ProjectPermission)
+	if !ok {
+		return nil, errors.New(errNotProjectPermission)
+	}
+
+	if err := c.usage.Track(ctx, mg); err != nil {
+		return nil, errors.Wrap(err, errTrackPCUsage)
+	}
+
+	pc := &apisv1alpha1.ProviderConfig{}
+	if err := c.kube.Get(ctx, types.NamespacedName{Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
+		// the reconciler requeues with backoff until the ProviderConfig exists
+		if kerrors.IsNotFound(err) {
+			cr.SetConditions(v1alpha1.ProviderConfigPending())
+			return nil, errors.Errorf(errPCPending, cr.GetProviderConfigReference().Name)
+		}
+		return nil, errors.Wrap(err, errGetPC)
+	}
+
+	data, err := config.Credentials(ctx, c.kube, c.credentials, pc, nil)
+	if kerrors.IsNotFound(err) {
+		cr.SetConditions(v1alpha1.CredentialsPending())
+		return nil, errors.Wrap(err, errCredsPending)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cr.GetCondition(v1alpha1.TypePending).Status == corev1.ConditionTrue {
+		cr.SetConditions(v1alpha1.NotPending())
+	}
+
+	opts, err := config.ClientOptions(ctx, c.kube, pc.Spec)
+	if err != nil {
+		return nil, errors.Wrap(err, errClientOptions)
+	}
+
+	svc, err := c.newServiceFn(pc.Spec.BaseURL, data, pc.Spec.CaCertPath, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+
+	e, err := config.WithTimeouts(&external{service: svc}, pc.Spec.OperationTimeouts)
+	if err != nil {
+		return nil, errors.Wrap(err, errClientOptions)
+	}
+	return e, nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	// A 'client' used to connect to the external resource API.
+	service *bitbucket.BitBucketService
+}
+
+// subject returns the bitbucket subject the permission is granted to
+func subject(cr *v1alpha1.ProjectPermission) bitbucket.PermissionSubject {
+	s := bitbucket.PermissionSubject{Type: bitbucket.SubjectGroup, Name: cr.Spec.ForProvider.Subject}
+	if cr.Spec.ForProvider.SubjectType == v1alpha1.SubjectTypeUser {
+		s.Type = bitbucket.SubjectUser
+	}
+	return s
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*v1alpha1.ProjectPermission)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotProjectPermission)
+	}
+
+	permission, err := c.service.Projects.GetPermission(ctx, cr.Spec.ForProvider.ProjectKey, subject(cr))
+	if errors.Is(err, bitbucket.ErrNotFound) {
+		// a deleted project takes the permissions granted on it along
+		log.Printf("Project with key (%s) does not exist\n", cr.Spec.ForProvider.ProjectKey)
+		cr.Status.AtProvider.Permission = ""
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errGetPermission)
+	}
+	cr.Status.AtProvider.Permission = permission
+	if permission == "" {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	cr.SetConditions(xpv1.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists:    true,
+		ResourceUpToDate:  permission == cr.Spec.ForProvider.Permission,
+		ConnectionDetails: managed.ConnectionDetails{},
+	}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*v1alpha1.ProjectPermission)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotProjectPermission)
+	}
+
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
+	cr.SetConditions(xpv1.Creating())
+
+	log.Printf("Granting %s %s on project %s\n", cr.Spec.ForProvider.Subject, cr.Spec.ForProvider.Permission, cr.Spec.ForProvider.ProjectKey)
+
+	if err := c.service.Projects.GrantPermission(ctx, cr.Spec.ForProvider.ProjectKey, subject(cr), cr.Spec.ForProvider.Permission); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errGrantPermission)
+	}
+	return managed.ExternalCreation{ConnectionDetails: managed.ConnectionDetails{}}, nil
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.ProjectPermission)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotProjectPermission)
+	}
+
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
+	log.Printf("Changing permission of %s on project %s to %s\n", cr.Spec.ForProvider.Subject, cr.Spec.ForProvider.ProjectKey, cr.Spec.ForProvider.Permission)
+
+	// granting a permission replaces the one the subject had
+	if err := c.service.Projects.GrantPermission(ctx, cr.Spec.ForProvider.ProjectKey, subject(cr), cr.Spec.ForProvider.Permission); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errGrantPermission)
+	}
+	return managed.ExternalUpdate{ConnectionDetails: managed.ConnectionDetails{}}, nil
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.ProjectPermission)
+	if !ok {
+		return errors.New(errNotProjectPermission)
+	}
+
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
+	cr.SetConditions(xpv1.Deleting())
+
+	log.Printf("Revoking permissions of %s on project %s\n", cr.Spec.ForProvider.Subject, cr.Spec.ForProvider.ProjectKey)
+
+	if err := c.service.Projects.RevokePermission(ctx, cr.Spec.ForProvider.ProjectKey, subject(cr)); err != nil {
+		return errors.Wrap(err, errRevokePermission)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectpermission
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/projectpermission/v1alpha1"
+	apisv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket/fake"
+)
+
+func projectPermission(subjectType string) *v1alpha1.ProjectPermission {
+	return &v1alpha1.ProjectPermission{Spec: v1alpha1.ProjectPermissionSpec{ForProvider: v1alpha1.ProjectPermissionParameters{
+		ProjectKey:  "PRJ",
+		SubjectType: subjectType,
+		Subject:     "devs",
+		Permission:  "PROJECT_WRITE",
+	}}}
+}
+
+func TestObserve(t *testing.T) {
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+	errBoom := errors.New("boom")
+
+	cases := map[string]struct {
+		reason     string
+		permission string
+		err        error
+		want       want
+	}{
+		"NotGranted": {
+			reason: "A subject without permission on the project should be reported as not existing",
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"UpToDate": {
+			reason:     "A subject granted the permission should be reported as up to date",
+			permission: "PROJECT_WRITE",
+			want:       want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: managed.ConnectionDetails{}}},
+		},
+		"OtherPermission": {
+			reason:     "A subject granted another permission should be reported as not up to date",
+			permission: "PROJECT_READ",
+			want:       want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false, ConnectionDetails: managed.ConnectionDetails{}}},
+		},
+		"ProjectDeleted": {
+			reason: "A permission on a deleted project should be reported as not existing so it can be deleted",
+			err:    errors.Wrap(bitbucket.ErrNotFound, "error getting project permission"),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"Error": {
+			reason: "Errors getting the permission should be returned",
+			err:    errBoom,
+			want:   want{err: errors.Wrap(errBoom, errGetPermission)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := &fake.MockProjectService{
+				MockGetPermission: func(_ context.Context, key string, s bitbucket.PermissionSubject) (string, error) {
+					if key != "PRJ" || s != (bitbucket.PermissionSubject{Type: bitbucket.SubjectGroup, Name: "devs"}) {
+						return "", errors.Errorf("unexpected project %s or subject %+v", key, s)
+					}
+					return tc.permission, tc.err
+				},
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: svc}}
+			cr := projectPermission(v1alpha1.SubjectTypeGroup)
+
+			got := want{}
+			got.o, got.err = e.Observe(context.Background(), cr)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.permission, cr.Status.AtProvider.Permission); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want permission, +got permission:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGrantAndRevoke(t *testing.T) {
+	type call struct {
+		op         string
+		subject    bitbucket.PermissionSubject
+		permission string
+	}
+
+	var calls []call
+	svc := &fake.MockProjectService{
+		MockGrantPermission: func(_ context.Context, _ string, s bitbucket.PermissionSubject, permission string) error {
+			calls = append(calls, call{op: "grant", subject: s, permission: permission})
+			return nil
+		},
+		MockRevokePermission: func(_ context.Context, _ string, s bitbucket.PermissionSubject) error {
+			calls = append(calls, call{op: "revoke", subject: s})
+			return nil
+		},
+	}
+	e := external{service: &bitbucket.BitBucketService{Projects: svc}}
+	cr := projectPermission(v1alpha1.SubjectTypeUser)
+
+	if _, err := e.Create(context.Background(), cr); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	cr.Spec.ForProvider.Permission = "PROJECT_ADMIN"
+	if _, err := e.Update(context.Background(), cr); err != nil {
+		t.Fatalf("e.Update(...): %v", err)
+	}
+	if err := e.Delete(context.Background(), cr); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+
+	user := bitbucket.PermissionSubject{Type: bitbucket.SubjectUser, Name: "devs"}
+	want := []call{
+		{op: "grant", subject: user, permission: "PROJECT_WRITE"},
+		{op: "grant", subject: user, permission: "PROJECT_ADMIN"},
+		{op: "revoke", subject: user},
+	}
+	if diff := cmp.Diff(want, calls, cmp.AllowUnexported(call{})); diff != "" {
+		t.Errorf("Create, Update and Delete: -want calls, +got calls:\n%s\n", diff)
+	}
+}
+
+func TestConnectPending(t *testing.T) {
+	type want struct {
+		connected bool
+		pending   corev1.ConditionStatus
+		reason    xpv1.ConditionReason
+	}
+
+	pc := &apisv1alpha1.ProviderConfig{}
+	pc.Spec.BaseURL = "https://bitbucket.example.com"
+	pc.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+	pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "creds"},
+		Key:             "credentials",
+	}
+
+	// kube serves the ProviderConfig and credentials secret that exist
+	kube := func(pcExists, secretExists bool) client.Client {
+		return &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				if !pcExists {
+					return kerrors.NewNotFound(schema.GroupResource{Resource: "providerconfigs"}, key.Name)
+				}
+				pc.DeepCopyInto(o)
+			case *corev1.Secret:
+				if !secretExists {
+					return kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+				}
+				o.Data = map[string][]byte{"credentials": []byte("user:pass")}
+			}
+			return nil
+		}}
+	}
+
+	cases := map[string]struct {
+		reason     string
+		kube       client.Client
+		conditions []xpv1.Condition
+		want       want
+	}{
+		"MissingProviderConfig": {
+			reason: "A missing ProviderConfig should mark the permission as pending",
+			kube:   kube(false, false),
+			want:   want{pending: corev1.ConditionTrue, reason: v1alpha1.ReasonProviderConfigMissing},
+		},
+		"MissingSecret": {
+			reason: "A missing credentials secret should mark the permission as pending",
+			kube:   kube(true, false),
+			want:   want{pending: corev1.ConditionTrue, reason: v1alpha1.ReasonCredentialsMissing},
+		},
+		"Connected": {
+			reason: "A permission that was pending should no longer be once its credentials exist",
+			kube:   kube(true, true),
+			conditions: []xpv1.Condition{
+				v1alpha1.CredentialsPending(),
+			},
+			want: want{connected: true, pending: corev1.ConditionFalse, reason: v1alpha1.ReasonConnected},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &connector{
+				kube:  tc.kube,
+				usage: resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				newServiceFn: func(_ string, _ []byte, _ *string, _ ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+					return &bitbucket.BitBucketService{}, nil
+				},
+			}
+			cr := projectPermission(v1alpha1.SubjectTypeGroup)
+			cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			cr.SetConditions(tc.conditions...)
+
+			_, err := c.Connect(context.Background(), cr)
+			pending := cr.GetCondition(v1alpha1.TypePending)
+			got := want{connected: err == nil, pending: pending.Status, reason: pending.Reason}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+// errorReasons maps the errors returned by bitbucket to the reason of the
+// BitbucketError condition set on the repository. The first match wins,
+// errors not listed leave the condition unchanged.
+var errorReasons = []struct {
+	err    error
+	reason xpv1.ConditionReason
+}{
+	{err: bitbucket.ErrPermission, reason: v1alpha1.ReasonAuthenticationFailed},
+	{err: bitbucket.ErrNotFound, reason: xpv1.ReasonReconcileError},
+	{err: bitbucket.ErrConflict, reason: v1alpha1.ReasonConflict},
+	{err: bitbucket.ErrUnsupported, reason: v1alpha1.ReasonUnsupported},
+	{err: bitbucket.ErrMaintenance, reason: v1alpha1.ReasonMaintenance},
+	{err: bitbucket.ErrResponseMalformed, reason: v1alpha1.ReasonInvalidResponse},
+	{err: bitbucket.ErrResponseTooLarge, reason: v1alpha1.ReasonInvalidResponse},
+	{err: bitbucket.ErrTooManyPages, reason: v1alpha1.ReasonInvalidResponse},
+}
+
+// setErrorCondition sets the BitbucketError condition matching err, or clears
+// it when the repository was reconciled without an error
+func setErrorCondition(mg resource.Managed, err error) {
+	if err == nil {
+		if mg.GetCondition(v1alpha1.TypeBitbucketError).Status == corev1.ConditionTrue {
+			mg.SetConditions(v1alpha1.NoBitbucketError())
+		}
+		return
+	}
+	for _, r := range errorReasons {
+		if errors.Is(err, r.err) {
+			mg.SetConditions(v1alpha1.BitbucketError(r.reason, err))
+			return
+		}
+	}
+}
+
+// conditionedExternal sets the BitbucketError condition from the errors
+// returned by the wrapped client
+type conditionedExternal struct {
+	managed.ExternalClient
+}
+
+func (c *conditionedExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	o, err := c.ExternalClient.Observe(ctx, mg)
+	setErrorCondition(mg, err)
+	return o, err
+}
+
+func (c *conditionedExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, err := c.ExternalClient.Create(ctx, mg)
+	setErrorCondition(mg, err)
+	return cr, err
+}
+
+func (c *conditionedExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	u, err := c.ExternalClient.Update(ctx, mg)
+	setErrorCondition(mg, err)
+	return u, err
+}
+
+func (c *conditionedExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	err := c.ExternalClient.Delete(ctx, mg)
+	setErrorCondition(mg, err)
+	return err
+}
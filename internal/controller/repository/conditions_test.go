@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+func TestErrorCondition(t *testing.T) {
+	type want struct {
+		status corev1.ConditionStatus
+		reason xpv1.ConditionReason
+	}
+
+	cases := map[string]struct {
+		reason     string
+		err        error
+		conditions []xpv1.Condition
+		want       want
+	}{
+		"Permission": {
+			reason: "Authentication errors should be reported as such",
+			err:    bitbucket.ErrPermission,
+			want:   want{status: corev1.ConditionTrue, reason: v1alpha1.ReasonAuthenticationFailed},
+		},
+		"NotFound": {
+			reason: "A repository that disappears during an update should be reported as a reconcile error",
+			err:    bitbucket.ErrNotFound,
+			want:   want{status: corev1.ConditionTrue, reason: xpv1.ReasonReconcileError},
+		},
+		"Conflict": {
+			reason: "Conflicts should be reported as such",
+			err:    bitbucket.ErrConflict,
+			want:   want{status: corev1.ConditionTrue, reason: v1alpha1.ReasonConflict},
+		},
+		"Unsupported": {
+			reason: "Features the server does not support should be reported as such",
+			err:    bitbucket.ErrUnsupported,
+			want:   want{status: corev1.ConditionTrue, reason: v1alpha1.ReasonUnsupported},
+		},
+		"Maintenance": {
+			reason: "A server in maintenance should be reported as such",
+			err:    bitbucket.ErrMaintenance,
+			want:   want{status: corev1.ConditionTrue, reason: v1alpha1.ReasonMaintenance},
+		},
+		"ResponseMalformed": {
+			reason: "Malformed responses should be reported as invalid",
+			err:    bitbucket.ErrResponseMalformed,
+			want:   want{status: corev1.ConditionTrue, reason: v1alpha1.ReasonInvalidResponse},
+		},
+		"ResponseTooLarge": {
+			reason: "Responses that are too large should be reported as invalid",
+			err:    bitbucket.ErrResponseTooLarge,
+			want:   want{status: corev1.ConditionTrue, reason: v1alpha1.ReasonInvalidResponse},
+		},
+		"TooManyPages": {
+			reason: "Endless pagination should be reported as an invalid response",
+			err:    bitbucket.ErrTooManyPages,
+			want:   want{status: corev1.ConditionTrue, reason: v1alpha1.ReasonInvalidResponse},
+		},
+		"Unmapped": {
+			reason: "Errors not returned by bitbucket should not set the condition",
+			err:    errors.New("boom"),
+			want:   want{status: corev1.ConditionUnknown},
+		},
+		"Cleared": {
+			reason:     "A reconcile without an error should clear the condition",
+			conditions: []xpv1.Condition{v1alpha1.BitbucketError(v1alpha1.ReasonConflict, bitbucket.ErrConflict)},
+			want:       want{status: corev1.ConditionFalse, reason: v1alpha1.ReasonNoError},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// the sentinels are usually wrapped by the time they reach the controller
+			err := tc.err
+			if err != nil {
+				err = errors.Wrap(fmt.Errorf("request failed: %w", err), "cannot update repository")
+			}
+			e := &conditionedExternal{ExternalClient: &managed.ExternalClientFns{
+				UpdateFn: func(_ context.Context, _ resource.Managed) (managed.ExternalUpdate, error) {
+					return managed.ExternalUpdate{}, err
+				},
+			}}
+			cr := repository(func(r *v1alpha1.Repository) { r.SetConditions(tc.conditions...) })
+
+			_, _ = e.Update(context.Background(), cr)
+			c := cr.GetCondition(v1alpha1.TypeBitbucketError)
+			got := want{status: c.Status, reason: c.Reason}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
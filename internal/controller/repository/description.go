@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+)
+
+const errDescriptionTemplate = "cannot render description template"
+
+// descriptionData is what a description template is rendered against, e.g.
+// {{ .Labels.team }} or {{ index .Annotations "example.com/ticket" }}
+type descriptionData struct {
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// renderDescription returns the description sent to bitbucket, the
+// description of the spec with any template rendered against the labels and
+// annotations of the repository. Referencing a missing label or annotation is
+// an error rather than rendering an empty value.
+func renderDescription(cr *v1alpha1.Repository) (string, error) {
+	description := cr.Spec.ForProvider.Description
+	if !strings.Contains(description, "{{") {
+		return description, nil
+	}
+
+	tmpl, err := template.New("description").Option("missingkey=error").Parse(description)
+	if err != nil {
+		return "", errors.Wrap(err, errDescriptionTemplate)
+	}
+	out := &strings.Builder{}
+	err = tmpl.Execute(out, descriptionData{Labels: cr.GetLabels(), Annotations: cr.GetAnnotations()})
+	if err != nil {
+		return "", errors.Wrap(err, errDescriptionTemplate)
+	}
+	return out.String(), nil
+}
+
+// descriptionEqual compares descriptions ignoring trailing whitespace, which
+// bitbucket trims from the description it stores
+func descriptionEqual(a, b string) bool {
+	return strings.TrimRightFunc(a, unicode.IsSpace) == strings.TrimRightFunc(b, unicode.IsSpace)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket/fake"
+)
+
+func withDescription(description string) repositoryModifier {
+	return func(r *v1alpha1.Repository) {
+		r.Spec.ForProvider.Description = description
+		r.SetLabels(map[string]string{"team": "platform"})
+		r.SetAnnotations(map[string]string{"example.com/ticket": "OPS-42"})
+	}
+}
+
+func TestRenderDescription(t *testing.T) {
+	type want struct {
+		description string
+		err         bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *v1alpha1.Repository
+		want   want
+	}{
+		"Plain": {
+			reason: "A description without a template should be used as is",
+			mg:     repository(withDescription("my repository")),
+			want:   want{description: "my repository"},
+		},
+		"Template": {
+			reason: "A template should be rendered against the labels and annotations",
+			mg:     repository(withDescription(`owned by {{ .Labels.team }}, see {{ index .Annotations "example.com/ticket" }}`)),
+			want:   want{description: "owned by platform, see OPS-42"},
+		},
+		"MissingLabel": {
+			reason: "A template referencing a missing label should be an error",
+			mg:     repository(withDescription("owned by {{ .Labels.owner }}")),
+			want:   want{err: true},
+		},
+		"Invalid": {
+			reason: "A template that does not parse should be an error",
+			mg:     repository(withDescription("owned by {{ .Labels.team")),
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			description, err := renderDescription(tc.mg)
+			got := want{description: description, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nrenderDescription(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDescriptionDrift(t *testing.T) {
+	type want struct {
+		upToDate bool
+		updated  []string
+	}
+	template := "owned by {{ .Labels.team }}"
+	markdown := "# Billing\n\nSee [\"docs\"](https://example.com/a?b=1&c=2) & <https://example.com>, it's **important**"
+
+	cases := map[string]struct {
+		reason   string
+		spec     string
+		existing string
+		want     want
+	}{
+		"Rendered": {
+			reason:   "A repository with the rendered description should be up to date",
+			spec:     template,
+			existing: "owned by platform",
+			want:     want{upToDate: true},
+		},
+		"Template": {
+			reason:   "A repository with the unrendered template should be updated to the rendered description",
+			spec:     template,
+			existing: template,
+			want:     want{updated: []string{"owned by platform"}},
+		},
+		"TrailingWhitespace": {
+			reason:   "A spec with trailing whitespace should be up to date with the description trimmed by bitbucket",
+			spec:     template + " \n",
+			existing: "owned by platform",
+			want:     want{upToDate: true},
+		},
+		"Markdown": {
+			reason:   "A description with markdown, quotes and newlines should be compared as written",
+			spec:     markdown,
+			existing: markdown,
+			want:     want{upToDate: true},
+		},
+		"MarkdownChanged": {
+			reason:   "A change to the markdown should be drift",
+			spec:     markdown,
+			existing: strings.Replace(markdown, "**", "*", 1),
+			want:     want{updated: []string{markdown}},
+		},
+		"NewlinesChanged": {
+			reason:   "Newlines within the description should be compared exactly",
+			spec:     markdown,
+			existing: strings.Replace(markdown, "\n\n", "\n", 1),
+			want:     want{updated: []string{markdown}},
+		},
+		"LeadingWhitespace": {
+			reason:   "Only trailing whitespace should be ignored",
+			spec:     " " + template,
+			existing: "owned by platform",
+			want:     want{updated: []string{" owned by platform"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: &fake.MockRepositoryService{
+				MockGet: func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+					return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project, Description: tc.existing}, nil
+				},
+				MockUpdate: func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+					got.updated = append(got.updated, r.Description)
+					return r, nil
+				},
+				MockGetGroups: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Group, error) {
+					return nil, nil
+				},
+				MockCountOpenPullRequests: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+					return 0, nil
+				},
+				MockGetRequiredApprovals: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+					return 0, nil
+				},
+				MockGetUsers: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.UserPermission, error) {
+					return nil, nil
+				},
+			}}}
+			cr := repository(withDescription(tc.spec))
+
+			o, err := e.Observe(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			got.upToDate = o.ResourceUpToDate
+			if !o.ResourceUpToDate {
+				if _, err := e.Update(context.Background(), cr); err != nil {
+					t.Fatalf("e.Update(...): %v", err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ndescription drift: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
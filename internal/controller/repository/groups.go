@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+// defaultGroupConcurrency is the number of group permissions applied
+// concurrently unless configured in the ProviderConfig
+const defaultGroupConcurrency = 4
+
+// specGroups returns the groups in the spec as bitbucket groups
+func specGroups(cr *v1alpha1.Repository) []bitbucket.Group {
+	groups := make([]bitbucket.Group, 0, len(cr.Spec.ForProvider.Groups))
+	for _, g := range cr.Spec.ForProvider.Groups {
+		groups = append(groups, bitbucket.Group{Name: g.Name, Permission: g.Permission})
+	}
+	return groups
+}
+
+// checkGroupCount rejects a spec listing more groups than the ProviderConfig
+// allows, e.g. a generated spec gone wrong. Groups listed more than once are
+// counted once.
+func (c *external) checkGroupCount(cr *v1alpha1.Repository) error {
+	if c.maxGroups <= 0 {
+		return nil
+	}
+	names := map[string]bool{}
+	for _, g := range cr.Spec.ForProvider.Groups {
+		names[strings.ToLower(g.Name)] = true
+	}
+	if len(names) > c.maxGroups {
+		return errors.Errorf(errTooManyGroups, cr.Spec.ForProvider.Name, len(names), c.maxGroups)
+	}
+	return nil
+}
+
+// directGroups returns the groups granted on the repository itself. Grants
+// inherited from the project are managed on the project, so they are neither
+// compared to the spec nor revoked.
+func directGroups(groups []bitbucket.Group) []bitbucket.Group {
+	direct := []bitbucket.Group{}
+	for _, g := range groups {
+		if !g.Inherited {
+			direct = append(direct, g)
+		}
+	}
+	return direct
+}
+
+// observeGroups returns the direct grants on the repository that are compared
+// to the spec. Without pruning only the groups in the spec matter, so a
+// single group is looked up on its own instead of listing every group.
+func (c *external) observeGroups(ctx context.Context, cr *v1alpha1.Repository, repo *bitbucket.Repository) ([]bitbucket.Group, error) {
+	if !pruneUnknownGroups(cr) && len(cr.Spec.ForProvider.Groups) == 1 {
+		name := cr.Spec.ForProvider.Groups[0].Name
+		permission, err := c.service.Repositories.GetGroupPermission(ctx, repo, name)
+		if errors.Is(err, bitbucket.ErrNotFound) {
+			return []bitbucket.Group{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []bitbucket.Group{{Name: name, Permission: permission}}, nil
+	}
+
+	groups, err := c.service.Repositories.GetGroups(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	groups = directGroups(groups)
+	if !pruneUnknownGroups(cr) {
+		groups = specifiedGroups(cr.Spec.ForProvider.Groups, groups)
+	}
+	return groups, nil
+}
+
+// forEachGroup calls fn for every group with at most groupConcurrency calls
+// in flight. All groups are attempted, the errors are returned aggregated in
+// the order of the groups so the reported error does not depend on timing.
+func (c *external) forEachGroup(ctx context.Context, groups []bitbucket.Group, fn func(context.Context, *bitbucket.Group) error) error {
+	limit := c.groupConcurrency
+	if limit < 1 {
+		limit = 1
+	}
+
+	errs := make([]error, len(groups))
+	sem := make(chan struct{}, limit)
+	wg := sync.WaitGroup{}
+	for i := range groups {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(ctx, &groups[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return kerrors.NewAggregate(errs)
+}
+
+// groupScopeError explains a permission failure of group operations, which
+// unlike the other repository operations require admin permission on the
+// repository, so credentials able to create the repository may still fail.
+func groupScopeError(err error, format string, name string) error {
+	if errors.Is(err, bitbucket.ErrPermission) {
+		return errors.Wrapf(err, format, name)
+	}
+	return err
+}
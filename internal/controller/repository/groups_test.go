@@ -0,0 +1,376 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+func TestForEachGroup(t *testing.T) {
+	type want struct {
+		applied     []string
+		maxInFlight int
+		err         error
+	}
+
+	groups := func(n int) []bitbucket.Group {
+		g := []bitbucket.Group{}
+		for i := 0; i < n; i++ {
+			g = append(g, bitbucket.Group{Name: fmt.Sprintf("group-%02d", i), Permission: "REPO_READ"})
+		}
+		return g
+	}
+	names := func(n int) []string {
+		s := []string{}
+		for _, g := range groups(n) {
+			s = append(s, g.Name)
+		}
+		return s
+	}
+
+	cases := map[string]struct {
+		reason      string
+		concurrency int
+		groups      []bitbucket.Group
+		fail        map[string]bool
+		want        want
+	}{
+		"Bounded": {
+			reason:      "All groups should be applied with no more calls in flight than the concurrency",
+			concurrency: 3,
+			groups:      groups(12),
+			want:        want{applied: names(12), maxInFlight: 3},
+		},
+		"Sequential": {
+			reason: "Without a concurrency the groups should be applied one at a time",
+			groups: groups(4),
+			want:   want{applied: names(4), maxInFlight: 1},
+		},
+		"Errors": {
+			reason:      "Every group should be attempted and the errors reported in the order of the groups",
+			concurrency: 4,
+			groups:      groups(8),
+			fail:        map[string]bool{"group-06": true, "group-01": true},
+			want: want{
+				applied:     names(8),
+				maxInFlight: 4,
+				err: kerrors.NewAggregate([]error{
+					errors.New("group-01 failed"),
+					errors.New("group-06 failed"),
+				}),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mu := sync.Mutex{}
+			inFlight := 0
+			got := want{}
+
+			e := external{groupConcurrency: tc.concurrency}
+			got.err = e.forEachGroup(context.Background(), tc.groups, func(_ context.Context, group *bitbucket.Group) error {
+				mu.Lock()
+				inFlight++
+				if inFlight > got.maxInFlight {
+					got.maxInFlight = inFlight
+				}
+				got.applied = append(got.applied, group.Name)
+				mu.Unlock()
+
+				// give the other calls a chance to run concurrently
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+
+				if tc.fail[group.Name] {
+					return errors.Errorf("%s failed", group.Name)
+				}
+				return nil
+			})
+			sort.Strings(got.applied)
+
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.forEachGroup(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGroupScope(t *testing.T) {
+	errBoom := errors.New("boom")
+	errForbidden := fmt.Errorf("error adding repository group: %w", bitbucket.ErrPermission)
+
+	cases := map[string]struct {
+		reason string
+		create bool
+		err    error
+		want   error
+	}{
+		"CreateForbidden": {
+			reason: "A permission failure setting groups of a new repository should explain the repository was created",
+			create: true,
+			err:    errForbidden,
+			want:   errors.Wrapf(kerrors.NewAggregate([]error{errForbidden}), errGroupsCreated, "repo"),
+		},
+		"UpdateForbidden": {
+			reason: "A permission failure setting groups should explain the credentials lack admin permission",
+			err:    errForbidden,
+			want:   errors.Wrapf(kerrors.NewAggregate([]error{errForbidden}), errGroupsScope, "repo"),
+		},
+		"OtherError": {
+			reason: "Other failures setting groups should be returned as is",
+			create: true,
+			err:    errBoom,
+			want:   kerrors.NewAggregate([]error{errBoom}),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockCreate = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				return r, nil
+			}
+			svc.MockAddGroup = func(_ context.Context, _ *bitbucket.Repository, _ *bitbucket.Group) error {
+				return tc.err
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+
+			cr := repository(withGroups(v1alpha1.AdGroup{Name: "devs", Permission: "REPO_ADMIN"}))
+			var err error
+			if tc.create {
+				_, err = e.Create(context.Background(), cr)
+			} else {
+				_, err = e.Update(context.Background(), cr)
+			}
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nsetting groups: -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if errors.Is(tc.err, bitbucket.ErrPermission) && !errors.Is(err, bitbucket.ErrPermission) {
+				t.Errorf("\n%s\nerrors.Is(err, ErrPermission): want true, got false", tc.reason)
+			}
+		})
+	}
+}
+
+func TestPartialRevoke(t *testing.T) {
+	type want struct {
+		revoked []string
+		err     error
+	}
+	errStuck := errors.New("group is stuck")
+	errForbidden := fmt.Errorf("error revoking repository group: %w", bitbucket.ErrPermission)
+
+	cases := map[string]struct {
+		reason  string
+		failing map[string]error
+		want    want
+	}{
+		"OneFails": {
+			reason:  "A failing revoke should not stop the other stale groups from being revoked",
+			failing: map[string]error{"stale-b": errStuck},
+			want: want{
+				revoked: []string{"stale-a", "stale-c"},
+				err:     kerrors.NewAggregate([]error{errors.Wrapf(errStuck, errRevokeGroup, "stale-b")}),
+			},
+		},
+		"SeveralFail": {
+			reason:  "Every failed revoke should be reported with its group",
+			failing: map[string]error{"stale-a": errStuck, "stale-c": errStuck},
+			want: want{
+				revoked: []string{"stale-b"},
+				err: kerrors.NewAggregate([]error{
+					errors.Wrapf(errStuck, errRevokeGroup, "stale-a"),
+					errors.Wrapf(errStuck, errRevokeGroup, "stale-c"),
+				}),
+			},
+		},
+		"Forbidden": {
+			reason:  "A permission failure revoking a group should still explain the credentials lack admin permission",
+			failing: map[string]error{"stale-a": errForbidden},
+			want: want{
+				revoked: []string{"stale-b", "stale-c"},
+				err:     errors.Wrapf(kerrors.NewAggregate([]error{errors.Wrapf(errForbidden, errRevokeGroup, "stale-a")}), errGroupsScope, "repo"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			existing := []bitbucket.Group{{Name: "stale-a", Permission: "REPO_READ"}, {Name: "stale-b", Permission: "REPO_READ"}, {Name: "stale-c", Permission: "REPO_READ"}}
+			calls := &groupCalls{}
+			svc := newGroupService(existing, calls)
+			svc.MockRevokeGroup = func(_ context.Context, _ *bitbucket.Repository, g *bitbucket.Group) error {
+				if err, ok := tc.failing[g.Name]; ok {
+					return err
+				}
+				calls.revoked = append(calls.revoked, g.Name)
+				return nil
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+
+			_, err := e.Update(context.Background(), repository())
+			got := want{revoked: calls.revoked, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveGroups(t *testing.T) {
+	type want struct {
+		groups []bitbucket.Group
+		listed bool
+		err    error
+	}
+	existing := []bitbucket.Group{
+		{Name: "devs", Permission: "REPO_WRITE"},
+		{Name: "manual", Permission: "REPO_READ"},
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *v1alpha1.Repository
+		want   want
+	}{
+		"SingleGroupFound": {
+			reason: "A single group should be looked up without listing every group",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "devs", Permission: "REPO_WRITE"}), withPruneUnknownGroups(false)),
+			want:   want{groups: []bitbucket.Group{{Name: "devs", Permission: "REPO_WRITE"}}},
+		},
+		"SingleGroupNotFound": {
+			reason: "A single group without a grant should be observed as missing",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "testers", Permission: "REPO_READ"}), withPruneUnknownGroups(false)),
+			want:   want{groups: []bitbucket.Group{}},
+		},
+		"Prune": {
+			reason: "Every group should be listed when unknown groups are pruned",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "devs", Permission: "REPO_WRITE"})),
+			want:   want{groups: existing, listed: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			listed := false
+			svc := newGroupService(existing, &groupCalls{})
+			svc.MockGetGroups = func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Group, error) {
+				listed = true
+				return existing, nil
+			}
+			e := external{service: &bitbucket.BitBucketService{Repositories: svc}}
+
+			groups, err := e.observeGroups(context.Background(), tc.mg, &bitbucket.Repository{Name: "repo", Project: "PRJ"})
+			got := want{groups: groups, listed: listed, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.observeGroups(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMaxGroups(t *testing.T) {
+	type want struct {
+		createErr error
+		updateErr error
+	}
+	groups := func(names ...string) []v1alpha1.AdGroup {
+		g := []v1alpha1.AdGroup{}
+		for _, name := range names {
+			g = append(g, v1alpha1.AdGroup{Name: name, Permission: "REPO_READ"})
+		}
+		return g
+	}
+
+	cases := map[string]struct {
+		reason    string
+		maxGroups int
+		groups    []v1alpha1.AdGroup
+		want      want
+	}{
+		"Unlimited": {
+			reason: "Any number of groups should be applied without a limit",
+			groups: groups("a", "b", "c"),
+		},
+		"BelowLimit": {
+			reason:    "Fewer groups than the limit should be applied",
+			maxGroups: 2,
+			groups:    groups("a"),
+		},
+		"AtLimit": {
+			reason:    "As many groups as the limit should be applied",
+			maxGroups: 2,
+			groups:    groups("a", "b"),
+		},
+		"AboveLimit": {
+			reason:    "More groups than the limit should be rejected before calling bitbucket",
+			maxGroups: 2,
+			groups:    groups("a", "b", "c"),
+			want: want{
+				createErr: errors.Errorf(errTooManyGroups, "repo", 3, 2),
+				updateErr: errors.Errorf(errTooManyGroups, "repo", 3, 2),
+			},
+		},
+		"Duplicates": {
+			reason:    "A group listed twice should be counted once",
+			maxGroups: 2,
+			groups:    groups("a", "b", "B"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			calls := &groupCalls{}
+			svc := newGroupService(nil, calls)
+			svc.MockCreate = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				return r, nil
+			}
+			e := external{
+				maxGroups: tc.maxGroups,
+				service:   &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc},
+			}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.Groups = tc.groups })
+
+			got := want{}
+			_, got.createErr = e.Create(context.Background(), cr)
+			_, got.updateErr = e.Update(context.Background(), cr)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nreconciling groups: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if tc.want.createErr != nil && len(calls.added) > 0 {
+				t.Errorf("\n%s\nreconciling groups: want no groups added, got %v", tc.reason, calls.added)
+			}
+		})
+	}
+}
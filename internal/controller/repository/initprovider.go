@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+)
+
+// withInitProvider returns a copy of the repository whose parameters unset in
+// spec.forProvider are seeded from spec.initProvider. The copy is what Create
+// applies, afterwards only spec.forProvider is reconciled.
+func withInitProvider(cr *v1alpha1.Repository) *v1alpha1.Repository {
+	out := cr.DeepCopy()
+	fp, ip := &out.Spec.ForProvider, out.Spec.InitProvider
+	if fp.Name == "" {
+		fp.Name = ip.Name
+	}
+	if fp.Project == "" {
+		fp.Project = ip.Project
+	}
+	if !fp.Public {
+		fp.Public = ip.Public
+	}
+	if fp.Description == "" {
+		fp.Description = ip.Description
+	}
+	if fp.Groups == nil {
+		fp.Groups = ip.Groups
+	}
+	if fp.Mirroring == nil {
+		fp.Mirroring = ip.Mirroring
+	}
+	if fp.PullRequestTemplate == nil {
+		fp.PullRequestTemplate = ip.PullRequestTemplate
+	}
+	if fp.DefaultMergeStrategy == nil {
+		fp.DefaultMergeStrategy = ip.DefaultMergeStrategy
+	}
+	if fp.Archived == nil {
+		fp.Archived = ip.Archived
+	}
+	if fp.Branches == nil {
+		fp.Branches = ip.Branches
+	}
+	if fp.DefaultBranch == nil {
+		fp.DefaultBranch = ip.DefaultBranch
+	}
+	if fp.ProtectDefaultBranch == nil {
+		fp.ProtectDefaultBranch = ip.ProtectDefaultBranch
+	}
+	if fp.DefaultBranchProtectionExemptGroups == nil {
+		fp.DefaultBranchProtectionExemptGroups = ip.DefaultBranchProtectionExemptGroups
+	}
+	if fp.EnabledHooks == nil {
+		fp.EnabledHooks = ip.EnabledHooks
+	}
+	if fp.RequiredBuilds == nil {
+		fp.RequiredBuilds = ip.RequiredBuilds
+	}
+	if fp.LFSEnabled == nil {
+		fp.LFSEnabled = ip.LFSEnabled
+	}
+	if fp.ForkSync == nil {
+		fp.ForkSync = ip.ForkSync
+	}
+	if fp.TemplateFrom == nil {
+		fp.TemplateFrom = ip.TemplateFrom
+	}
+	return out
+}
+
+// initOnlyDescription reports whether the description is only set in
+// spec.initProvider, so it is not reconciled after creation
+func initOnlyDescription(cr *v1alpha1.Repository) bool {
+	return cr.Spec.ForProvider.Description == "" && cr.Spec.InitProvider.Description != ""
+}
+
+// initOnlyPublic reports whether the repository is only made public in
+// spec.initProvider, so its visibility is not reconciled after creation
+func initOnlyPublic(cr *v1alpha1.Repository) bool {
+	return !cr.Spec.ForProvider.Public && cr.Spec.InitProvider.Public
+}
+
+// initOnlyGroups reports whether groups are only set in spec.initProvider, so
+// they are neither reconciled nor pruned after creation
+func initOnlyGroups(cr *v1alpha1.Repository) bool {
+	return cr.Spec.ForProvider.Groups == nil && len(cr.Spec.InitProvider.Groups) > 0
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+func TestInitProvider(t *testing.T) {
+	type want struct {
+		description string
+		public      bool
+		groups      []string
+		upToDate    bool
+		forProvider v1alpha1.RepositoryParameters
+	}
+	devs := v1alpha1.AdGroup{Name: "devs", Permission: "REPO_WRITE"}
+
+	cases := map[string]struct {
+		reason string
+		mods   []repositoryModifier
+		want   want
+	}{
+		"InitOnly": {
+			reason: "Parameters only in initProvider should be applied on creation and not reconciled afterwards",
+			mods: []repositoryModifier{func(r *v1alpha1.Repository) {
+				r.Spec.InitProvider = v1alpha1.RepositoryInitParameters{Description: "seeded", Public: true, Groups: []v1alpha1.AdGroup{devs}}
+			}},
+			want: want{
+				description: "seeded",
+				public:      true,
+				groups:      []string{"devs"},
+				upToDate:    true,
+				forProvider: v1alpha1.RepositoryParameters{Name: "repo", Project: "PRJ"},
+			},
+		},
+		"ForProviderWins": {
+			reason: "Parameters set in forProvider should take precedence over initProvider",
+			mods: []repositoryModifier{func(r *v1alpha1.Repository) {
+				r.Spec.ForProvider.Description = "managed"
+				r.Spec.InitProvider = v1alpha1.RepositoryInitParameters{Description: "seeded"}
+			}},
+			want: want{
+				description: "managed",
+				upToDate:    true,
+				forProvider: v1alpha1.RepositoryParameters{Name: "repo", Project: "PRJ", Description: "managed"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// an in memory bitbucket holding the created repository and its groups
+			var created *bitbucket.Repository
+			calls := &groupCalls{}
+			svc := newGroupService(nil, calls)
+			svc.MockCreate = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				created = &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project, Description: r.Description, Public: r.Public, State: bitbucket.StateAvailable}
+				return created, nil
+			}
+			svc.MockGet = func(_ context.Context, _ *bitbucket.Repository) (*bitbucket.Repository, error) {
+				if created == nil {
+					return nil, bitbucket.ErrNotFound
+				}
+				return created, nil
+			}
+			svc.MockGetGroups = func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Group, error) {
+				groups := []bitbucket.Group{}
+				for _, g := range calls.added {
+					groups = append(groups, bitbucket.Group{Name: g, Permission: devs.Permission})
+				}
+				return groups, nil
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(tc.mods...)
+
+			if _, err := e.Create(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\ne.Create(...): %v", tc.reason, err)
+			}
+			o, err := e.Observe(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			got := want{
+				description: created.Description,
+				public:      created.Public,
+				groups:      calls.added,
+				upToDate:    o.ResourceUpToDate,
+				forProvider: cr.Spec.ForProvider,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
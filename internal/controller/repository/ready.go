@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+const (
+	errGetReady            = "cannot get repository while waiting for it to be available"
+	errNotReady            = "repository %s was not available within %s"
+	errInitialisationError = "bitbucket failed to initialise repository %s"
+
+	// defaultReadyPollInterval is how often the repository is fetched while
+	// waiting for it to be available
+	defaultReadyPollInterval = time.Second
+)
+
+// waitUntilAvailable polls the repository until bitbucket reports it as
+// available, at most readyTimeout. It returns right away when readyTimeout is
+// not set.
+func (c *external) waitUntilAvailable(ctx context.Context, repository *bitbucket.Repository) (*bitbucket.Repository, error) {
+	if c.readyTimeout <= 0 || repository.IsAvailable() {
+		return repository, nil
+	}
+
+	interval := c.readyPollInterval
+	if interval <= 0 {
+		interval = defaultReadyPollInterval
+	}
+	timeout := time.NewTimer(c.readyTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return nil, errors.Errorf(errNotReady, repository.Name, c.readyTimeout)
+		case <-ticker.C:
+		}
+
+		r, err := c.service.Repositories.Get(ctx, repository)
+		if err != nil {
+			return nil, errors.Wrap(err, errGetReady)
+		}
+		if r.State == bitbucket.StateInitialisationFailed {
+			return nil, errors.Errorf(errInitialisationError, repository.Name)
+		}
+		if r.IsAvailable() {
+			return r, nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket/fake"
+)
+
+func TestWaitUntilAvailable(t *testing.T) {
+	type want struct {
+		gets int
+		err  error
+	}
+	errBoom := errors.New("boom")
+
+	cases := map[string]struct {
+		reason  string
+		timeout time.Duration
+		created string
+		// states are returned by successive gets, the last one repeatedly
+		states []string
+		err    error
+		// anyGets skips checking the number of gets, which depends on timing
+		anyGets bool
+		want    want
+	}{
+		"Disabled": {
+			reason:  "Without a timeout the repository should not be polled",
+			created: bitbucket.StateInitialising,
+			want:    want{gets: 0},
+		},
+		"Available": {
+			reason:  "A repository created available should not be polled",
+			timeout: time.Second,
+			created: bitbucket.StateAvailable,
+			want:    want{gets: 0},
+		},
+		"BecomesAvailable": {
+			reason:  "The repository should be polled until it is available",
+			timeout: time.Second,
+			created: bitbucket.StateInitialising,
+			states:  []string{bitbucket.StateInitialising, bitbucket.StateInitialising, bitbucket.StateAvailable},
+			want:    want{gets: 3},
+		},
+		"Timeout": {
+			reason:  "Waiting should give up when the repository is not available in time",
+			timeout: 50 * time.Millisecond,
+			created: bitbucket.StateInitialising,
+			states:  []string{bitbucket.StateInitialising},
+			anyGets: true,
+			want:    want{err: errors.Errorf(errNotReady, "repo", 50*time.Millisecond)},
+		},
+		"Failed": {
+			reason:  "A repository bitbucket failed to initialise should be reported",
+			timeout: time.Second,
+			created: bitbucket.StateInitialising,
+			states:  []string{bitbucket.StateInitialisationFailed},
+			want:    want{gets: 1, err: errors.Errorf(errInitialisationError, "repo")},
+		},
+		"GetError": {
+			reason:  "Errors getting the repository should be returned",
+			timeout: time.Second,
+			created: bitbucket.StateInitialising,
+			err:     errBoom,
+			want:    want{gets: 1, err: errors.Wrap(errBoom, errGetReady)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			svc := &fake.MockRepositoryService{
+				MockGet: func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+					got.gets++
+					if tc.err != nil {
+						return nil, tc.err
+					}
+					state := tc.states[len(tc.states)-1]
+					if got.gets <= len(tc.states) {
+						state = tc.states[got.gets-1]
+					}
+					return &bitbucket.Repository{Name: r.Name, State: state}, nil
+				},
+			}
+			e := external{
+				service:           &bitbucket.BitBucketService{Repositories: svc},
+				readyTimeout:      tc.timeout,
+				readyPollInterval: time.Millisecond,
+			}
+
+			_, got.err = e.waitUntilAvailable(context.Background(), &bitbucket.Repository{Name: "repo", State: tc.created})
+			if tc.anyGets {
+				got.gets = tc.want.gets
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.waitUntilAvailable(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -20,9 +20,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,37 +45,87 @@ import (
 	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
 	apisv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
 	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/config"
 	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/features"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/graceful"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/jitter"
 )
 
 const (
 	errNotRepository = "managed resource is not a Repository custom resource"
 	errTrackPCUsage  = "cannot track ProviderConfig usage"
 	errGetPC         = "cannot get ProviderConfig"
-	errGetCreds      = "cannot get credentials"
+	errPCPending     = "ProviderConfig %s does not exist yet"
+	errCredsPending  = "credentials secret does not exist yet"
 
-	errNewClient = "cannot create new Service"
+	errNewClient      = "cannot create new Service"
+	errClientOptions  = "cannot configure client from ProviderConfig"
+	errConnectionKeys = "cannot rename connection detail keys"
+	errNamePattern    = "cannot compile repository-name-pattern"
+	errVerifyAccess   = "cannot verify access of the credentials"
+	errVerifyScopes   = "cannot verify scopes of the credentials"
+	errMove           = "cannot move repository"
+	errSlugCollision  = "repository %s exists in both project %s and %s, cannot move it"
+	errNoProject      = "project is not set and ProviderConfig %s has no default-project"
+
+	errAdopt              = "cannot adopt existing repository"
+	errAdoptMismatch      = "repository %s already exists in %s with a different description or visibility"
+	errDescriptionTooLong = "description is %d characters long, bitbucket allows at most %d"
+	errGetSize            = "cannot get repository size"
+	errGetCommits         = "cannot get repository commits"
+	errGetProject         = "cannot get project of the repository"
+	errDeleteUnconfirmed  = "repository %s %s, annotate it with %s: \"true\" to confirm the deletion"
+	errGroupsCreated      = "repository %s was created but its group permissions could not be set, the credentials lack admin permission on the repository"
+	errGroupsScope        = "cannot set group permissions of repository %s, the credentials lack admin permission on the repository"
+	errRevokeGroup        = "cannot revoke group %s"
+	errNameMismatch       = "repository name %s does not match the repository-name-pattern %s of the ProviderConfig"
+	errTooManyGroups      = "repository %s lists %d groups, more than the max-groups-per-repository %d of the ProviderConfig"
+	errProtectionCreated  = "repository %s was created but its default branch could not be protected, retrying on the next reconcile"
+
+	// reasonDeletionOrphaned is the reason of the event recorded when a
+	// repository that could not be deleted is left in bitbucket
+	reasonDeletionOrphaned event.Reason = "DeletionOrphaned"
+
+	// reasonProtectionFailed is the reason of the event recorded when a
+	// created repository could not be protected
+	reasonProtectionFailed event.Reason = "ProtectionFailed"
+
+	// reasonVisibilityChanged is the reason of the event recorded when the
+	// public flag of a repository changes
+	reasonVisibilityChanged event.Reason = "VisibilityChanged"
+
+	// reasonAnonymousAccess is the reason of the event recorded when a
+	// private repository becomes readable anonymously through its project
+	reasonAnonymousAccess event.Reason = "AnonymousAccess"
+
+	// maxDescriptionLength is the longest repository description bitbucket accepts
+	maxDescriptionLength = 255
+
+	// connection detail keys
+	keyProjectKey        = "projectKey"
+	keyRepositorySlug    = "repositorySlug"
+	keyHTTPCloneURL      = "httpCloneUrl"
+	keySSHCloneURL       = "sshCloneUrl"
+	keyPreferredCloneURL = "preferredCloneUrl"
+	keyWebURL            = "webUrl"
 )
 
 // A BitbucketService provides operations against bitbucket
 var (
-	bitbucketService = func(baseURL string, creds []byte, caCertPath *string) (*bitbucket.BitBucketService, error) {
-		client, err := bitbucket.NewClient(baseURL, string(creds), caCertPath)
-		if err != nil {
-			// crash if we get an error setting up client
-			log.Fatalln(err)
-		}
-		service, err := bitbucket.NewService(client)
+	bitbucketService = func(baseURL string, creds []byte, caCertPath *string, opts ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+		// errors, e.g. credentials rejected by the ping, fail the connect so
+		// they are reported on the repository
+		client, err := bitbucket.NewClient(baseURL, string(creds), caCertPath, opts...)
 		if err != nil {
-			// crash if we get an error setting up bitbucket service
-			log.Fatalln(err)
+			return nil, err
 		}
-		return service, err
+		return bitbucket.NewService(client)
 	}
 )
 
 // Setup adds a controller that reconciles Repository managed resources.
-func Setup(mgr ctrl.Manager, o controller.Options) error {
+func Setup(mgr ctrl.Manager, o controller.Options, setupOpts ...config.SetupOption) error {
+	so := config.NewSetupOptions(setupOpts...)
 	name := managed.ControllerName(v1alpha1.RepositoryGroupKind)
 
 	cps := []managed.ConnectionPublisher{managed.NewAPISecretPublisher(mgr.GetClient(), mgr.GetScheme())}
@@ -76,14 +133,18 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		cps = append(cps, connection.NewDetailsManager(mgr.GetClient(), apisv1alpha1.StoreConfigGroupVersionKind))
 	}
 
+	recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
 	opts := []managed.ReconcilerOption{
 		managed.WithExternalConnecter(&connector{
 			kube:         mgr.GetClient(),
 			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			credentials:  so.CredentialExtractor,
+			recorder:     recorder,
+			etags:        bitbucket.NewETagCache(),
 			newServiceFn: bitbucketService}),
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
 		managed.WithPollInterval(o.PollInterval),
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithRecorder(recorder),
 		managed.WithConnectionPublishers(cps...),
 	}
 
@@ -98,7 +159,7 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		WithOptions(o.ForControllerRuntime()).
 		WithEventFilter(resource.DesiredStateChanged()).
 		For(&v1alpha1.Repository{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+		Complete(jitter.NewReconciler(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter)))
 }
 
 // A connector is expected to produce an ExternalClient when its Connect method
@@ -106,7 +167,10 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 type connector struct {
 	kube         client.Client
 	usage        resource.Tracker
-	newServiceFn func(baseURL string, creds []byte, caCertPath *string) (*bitbucket.BitBucketService, error)
+	credentials  config.CredentialExtractor
+	recorder     event.Recorder
+	etags        *bitbucket.ETagCache
+	newServiceFn func(baseURL string, creds []byte, caCertPath *string, opts ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error)
 }
 
 // Connect typically produces an ExternalClient by:
@@ -114,7 +178,18 @@ type connector struct {
 // 2. Getting the managed resource's ProviderConfig.
 // 3. Getting the credentials specified by the ProviderConfig.
 // 4. Using the credentials to form a client.
+// Credentials are usually rejected while connecting, before the client
+// setting the BitbucketError condition exists, so it is set here too.
 func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	e, err := c.connect(ctx, mg)
+	if err != nil {
+		setErrorCondition(mg, err)
+	}
+	return e, err
+}
+
+// connect produces the ExternalClient for Connect
+func (c *connector) connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
 	cr, ok := mg.(*v1alpha1.Repository)
 	if !ok {
 		return nil, errors.New(errNotRepository)
@@ -126,21 +201,84 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 
 	pc := &apisv1alpha1.ProviderConfig{}
 	if err := c.kube.Get(ctx, types.NamespacedName{Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
+		// the reconciler requeues with backoff until the ProviderConfig exists
+		if kerrors.IsNotFound(err) {
+			cr.SetConditions(v1alpha1.ProviderConfigPending())
+			return nil, errors.Errorf(errPCPending, cr.GetProviderConfigReference().Name)
+		}
 		return nil, errors.Wrap(err, errGetPC)
 	}
 
-	cd := pc.Spec.Credentials
-	data, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	data, err := config.Credentials(ctx, c.kube, c.credentials, pc, cr.Spec.CredentialsSecretRef)
+	if kerrors.IsNotFound(err) {
+		cr.SetConditions(v1alpha1.CredentialsPending())
+		return nil, errors.Wrap(err, errCredsPending)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, errGetCreds)
+		return nil, err
+	}
+	if cr.GetCondition(v1alpha1.TypePending).Status == corev1.ConditionTrue {
+		cr.SetConditions(v1alpha1.NotPending())
 	}
 
-	svc, err := c.newServiceFn(pc.Spec.BaseURL, data, pc.Spec.CaCertPath)
+	projectDefaulted, err := defaultProject(cr, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := config.ClientOptions(ctx, c.kube, pc.Spec)
+	if err != nil {
+		return nil, errors.Wrap(err, errClientOptions)
+	}
+	if c.etags != nil {
+		opts = append(opts, bitbucket.WithETagCache(c.etags))
+	}
+
+	svc, err := c.newServiceFn(pc.Spec.BaseURL, data, pc.Spec.CaCertPath, opts...)
 	if err != nil {
 		return nil, errors.Wrap(err, errNewClient)
 	}
 
-	return &external{service: svc}, nil
+	if pc.Spec.VerifyAccess != nil && *pc.Spec.VerifyAccess {
+		if err := svc.Server.VerifyAccess(ctx, cr.Spec.ForProvider.Project); err != nil {
+			return nil, errors.Wrap(err, errVerifyAccess)
+		}
+	}
+	if pc.Spec.VerifyTokenScopes != nil && *pc.Spec.VerifyTokenScopes {
+		if err := svc.Server.VerifyScopes(ctx, cr.Spec.ForProvider.Project, cr.Spec.ForProvider.Name); err != nil {
+			return nil, errors.Wrap(err, errVerifyScopes)
+		}
+	}
+
+	keys, err := config.NewConnectionKeys(pc.Spec.ConnectionDetailKeys, keyProjectKey, keyRepositorySlug, keyHTTPCloneURL, keySSHCloneURL, keyPreferredCloneURL, keyWebURL)
+	if err != nil {
+		return nil, errors.Wrap(err, errConnectionKeys)
+	}
+
+	e := &external{service: svc, recorder: c.recorder, groupConcurrency: defaultGroupConcurrency, connectionKeys: keys, projectDefaulted: projectDefaulted}
+	if pc.Spec.GroupConcurrency != nil {
+		e.groupConcurrency = *pc.Spec.GroupConcurrency
+	}
+	if pc.Spec.RepositoryReadyTimeout != nil {
+		e.readyTimeout = pc.Spec.RepositoryReadyTimeout.Duration
+	}
+	if pc.Spec.PreferredCloneProtocol != nil {
+		e.cloneProtocol = *pc.Spec.PreferredCloneProtocol
+	}
+	if pc.Spec.MaxGroupsPerRepository != nil {
+		e.maxGroups = *pc.Spec.MaxGroupsPerRepository
+	}
+	if pc.Spec.RepositoryNamePattern != nil {
+		e.namePattern, err = regexp.Compile(*pc.Spec.RepositoryNamePattern)
+		if err != nil {
+			return nil, errors.Wrap(err, errNamePattern)
+		}
+	}
+	timed, err := config.WithTimeouts(e, pc.Spec.OperationTimeouts)
+	if err != nil {
+		return nil, errors.Wrap(err, errClientOptions)
+	}
+	return &conditionedExternal{ExternalClient: timed}, nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -148,6 +286,42 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 type external struct {
 	// A 'client' used to connect to the external resource API.
 	service *bitbucket.BitBucketService
+	// recorder records events on the managed resource, may be nil
+	recorder event.Recorder
+	// groupConcurrency is the number of group permissions applied concurrently
+	groupConcurrency int
+	// connectionKeys renames the published connection detail keys
+	connectionKeys config.ConnectionKeys
+	// readyTimeout is how long Create waits for a new repository to be
+	// available, not at all when zero
+	readyTimeout time.Duration
+	// readyPollInterval is how often the repository is fetched while waiting
+	readyPollInterval time.Duration
+	// namePattern is matched by the names of created repositories, may be nil
+	namePattern *regexp.Regexp
+	// maxGroups is the number of groups a repository may list, unlimited
+	// when zero
+	maxGroups int
+	// cloneProtocol is the protocol of the clone URL published as the
+	// preferred one, http when empty
+	cloneProtocol string
+	// projectDefaulted is true when the project was set from the default of
+	// the ProviderConfig and is yet to be persisted in the spec
+	projectDefaulted bool
+}
+
+// defaultProject sets the project of a repository without one to the
+// default-project of the ProviderConfig and reports whether it did. Either
+// has to provide the project.
+func defaultProject(cr *v1alpha1.Repository, pc *apisv1alpha1.ProviderConfig) (bool, error) {
+	if cr.Spec.ForProvider.Project != "" {
+		return false, nil
+	}
+	if pc.Spec.DefaultProject == nil || *pc.Spec.DefaultProject == "" {
+		return false, errors.Errorf(errNoProject, pc.GetName())
+	}
+	cr.Spec.ForProvider.Project = *pc.Spec.DefaultProject
+	return true, nil
 }
 
 func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -159,38 +333,151 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	repoName := cr.Spec.ForProvider.Name
 	projectName := cr.Spec.ForProvider.Project
 
-	repository, err := c.service.Repositories.Get(ctx, &bitbucket.Repository{
-		Name:    repoName,
-		Project: projectName,
-	})
+	// a repository being deleted only needs to be checked for existence
+	if meta.WasDeleted(cr) {
+		// reporting an orphaned repository as gone lets the finalizer be removed
+		if orphaned(cr) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		exists, err := c.service.Repositories.Exists(ctx, &bitbucket.Repository{
+			Name:    repoName,
+			Project: projectName,
+		})
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, "error checking Bitbucket repository")
+		}
+		return managed.ExternalObservation{ResourceExists: exists, ResourceUpToDate: true}, nil
+	}
+
+	// the removed annotation and a defaulted project are persisted by
+	// reporting the resource as late initialized
+	forced := forceSync(cr)
+	if forced {
+		log.Printf("Forcing full observation of repository %s\n", cr.Name)
+		ctx = bitbucket.WithoutCache(ctx)
+		meta.RemoveAnnotations(cr, v1alpha1.AnnotationForceSync)
+	}
+	lateInitialized := forced || c.projectDefaulted
+
+	repository, err := c.getRepository(ctx, cr)
 	if err != nil {
 		if errors.Is(err, bitbucket.ErrNotFound) {
 			log.Printf("Repository (%s) does not exist in (%s)\n", repoName, projectName)
-			return managed.ExternalObservation{ResourceExists: false}, nil
+			return managed.ExternalObservation{ResourceExists: false, ResourceLateInitialized: lateInitialized}, nil
 		}
 		return managed.ExternalObservation{}, errors.Wrap(err, "error fetching Bitbucket repository")
 	}
 
 	cr.SetConditions(xpv1.Available())
 	cr.Status.AtProvider.ID = repository.ID
+	cr.Status.AtProvider.Project = repository.Project
+	cr.Status.AtProvider.Archived = repository.IsArchived()
+	cr.Status.AtProvider.IsFork = repository.IsFork()
+	cr.Status.AtProvider.Origin = repository.Origin
+	cr.Status.AtProvider.CreatedAt = nil
+	if repository.CreatedDate != nil {
+		created := metav1.NewTime(*repository.CreatedDate)
+		cr.Status.AtProvider.CreatedAt = &created
+	}
 
-	// check description is up-to-date
-	if repository.Description != cr.Spec.ForProvider.Description {
+	// archived repositories are read-only, so unless asked to unarchive it
+	// report the repository as up to date rather than failing every update
+	if repository.IsArchived() && !unarchive(cr) {
+		cr.SetConditions(v1alpha1.Archived())
+		cr.Status.AtProvider.DriftReason = ""
+		synced(cr)
 		return managed.ExternalObservation{
-			ResourceExists:   true,
-			ResourceUpToDate: false,
+			ResourceExists:          true,
+			ResourceUpToDate:        true,
+			ResourceLateInitialized: lateInitialized,
+			ConnectionDetails:       c.connectionDetails(repository),
 		}, nil
 	}
+	if cr.GetCondition(v1alpha1.TypeArchived).Status != corev1.ConditionUnknown {
+		cr.SetConditions(v1alpha1.NotArchived())
+	}
+
+	description, err := renderDescription(cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	// collect every field that differs so the drift can be reported in status
+	drift := []string{}
+	if !strings.EqualFold(repository.Project, projectName) {
+		drift = append(drift, "project")
+	}
+	if !descriptionEqual(repository.Description, description) && !initOnlyDescription(cr) {
+		drift = append(drift, "description")
+	}
+	// only a public project grants anonymous access to a private repository,
+	// which the repository cannot revoke, so it is reported but not drift.
+	// Failing to get the project keeps the previously reported access.
+	anonymous, err := c.anonymousAccess(ctx, repository)
+	if err != nil {
+		log.Printf("Cannot check anonymous access of repository %+v: %v\n", repository, err)
+	} else {
+		if anonymous && !repository.Public && !cr.Spec.ForProvider.Public && !cr.Status.AtProvider.AnonymousAccess {
+			c.recordAnonymousAccess(cr, repository)
+		}
+		cr.Status.AtProvider.AnonymousAccess = anonymous
+	}
+	if !initOnlyPublic(cr) && repository.Public != cr.Spec.ForProvider.Public {
+		drift = append(drift, "public")
+	}
+	if !archivedUpToDate(cr, repository) {
+		drift = append(drift, "archived")
+	}
 
 	// check if groups are up-to-date
-	groups, err := c.service.Repositories.GetGroups(ctx, repository)
+	groups, err := c.observeGroups(ctx, cr, repository)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, "error fetching Bitbucket repository groups")
+	}
 	if !groupsEqual(cr.Spec.ForProvider.Groups, groups) {
-		return managed.ExternalObservation{
-			ResourceExists:   true,
-			ResourceUpToDate: false,
-		}, nil
+		drift = append(drift, "groups")
 	}
 
+	// user permissions are not managed, only reported, failing to list them
+	// keeps the previously reported permissions
+	users, err := c.service.Repositories.GetUsers(ctx, repository)
+	if err != nil {
+		log.Printf("Cannot list user permissions of repository %+v: %v\n", repository, err)
+	} else {
+		cr.Status.AtProvider.Users = userPermissions(users)
+	}
+
+	// the count is only reported, failing to count keeps the previous count
+	openPullRequests, err := c.service.Repositories.CountOpenPullRequests(ctx, repository)
+	if err != nil {
+		log.Printf("Cannot count open pull requests of repository %+v: %v\n", repository, err)
+	} else {
+		cr.Status.AtProvider.OpenPullRequests = openPullRequests
+	}
+
+	// the required approvals are only reported, failing to get them keeps
+	// the previous value
+	requiredApprovals, err := c.service.Repositories.GetRequiredApprovals(ctx, repository)
+	if err != nil {
+		log.Printf("Cannot get required approvals of repository %+v: %v\n", repository, err)
+	} else {
+		cr.Status.AtProvider.RequiredApprovals = requiredApprovals
+	}
+
+	// check if settings managed through their own endpoints are up-to-date
+	for _, s := range c.settings() {
+		upToDate, err := s.upToDate(ctx, cr, repository)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		if !upToDate {
+			drift = append(drift, s.name)
+		}
+	}
+
+	cr.Status.AtProvider.DriftReason = strings.Join(drift, ", ")
+	synced(cr)
+
 	return managed.ExternalObservation{
 		// Return false when the external resource does not exist. This lets
 		// the managed resource reconciler know that it needs to call Create to
@@ -200,34 +487,194 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		// Return false when the external resource exists, but it not up to date
 		// with the desired managed resource state. This lets the managed
 		// resource reconciler know that it needs to call Update.
-		ResourceUpToDate: true,
+		ResourceUpToDate: len(drift) == 0,
+
+		// Persist the removal of the force sync annotation.
+		ResourceLateInitialized: lateInitialized,
 
 		// Return any details that may be required to connect to the external
 		// resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
+		ConnectionDetails: c.connectionDetails(repository),
 	}, nil
 }
 
+// getRepository gets the repository from the project in the spec. A
+// repository whose project changed in the spec stays in the project it was
+// last observed in until it is moved, so it is looked up there before it is
+// taken as gone. A repository with the same slug in the project of the spec
+// while the repository still exists in the project it was last observed in is
+// a conflict rather than the moved repository.
+func (c *external) getRepository(ctx context.Context, cr *v1alpha1.Repository) (*bitbucket.Repository, error) {
+	repository, err := c.service.Repositories.Get(ctx, &bitbucket.Repository{
+		Name:    cr.Spec.ForProvider.Name,
+		Project: cr.Spec.ForProvider.Project,
+	})
+	observed := cr.Status.AtProvider.Project
+	if observed == "" || strings.EqualFold(observed, cr.Spec.ForProvider.Project) {
+		return repository, err
+	}
+	if err == nil {
+		stale, err := c.service.Repositories.Exists(ctx, &bitbucket.Repository{
+			Name:    cr.Spec.ForProvider.Name,
+			Project: observed,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, errMove)
+		}
+		if stale {
+			return nil, errors.Wrapf(bitbucket.ErrConflict, errSlugCollision, cr.Spec.ForProvider.Name, cr.Spec.ForProvider.Project, observed)
+		}
+		return repository, nil
+	}
+	if !errors.Is(err, bitbucket.ErrNotFound) {
+		return repository, err
+	}
+	log.Printf("Repository (%s) does not exist in (%s), looking it up in (%s)\n", cr.Spec.ForProvider.Name, cr.Spec.ForProvider.Project, observed)
+	return c.service.Repositories.Get(ctx, &bitbucket.Repository{
+		Name:    cr.Spec.ForProvider.Name,
+		Project: observed,
+	})
+}
+
+// synced records that the repository was successfully reconciled against bitbucket
+func synced(cr *v1alpha1.Repository) {
+	now := metav1.Now()
+	cr.Status.AtProvider.LastSyncTime = &now
+}
+
+// forceSync reports whether the repository is annotated to be observed
+// bypassing any cached state
+func forceSync(cr *v1alpha1.Repository) bool {
+	_, ok := cr.GetAnnotations()[v1alpha1.AnnotationForceSync]
+	return ok
+}
+
+// userPermissions returns the user permissions for the status
+func userPermissions(users []bitbucket.UserPermission) []v1alpha1.UserPermission {
+	if len(users) == 0 {
+		return nil
+	}
+	out := make([]v1alpha1.UserPermission, 0, len(users))
+	for _, u := range users {
+		out = append(out, v1alpha1.UserPermission{User: u.User, Permission: u.Permission})
+	}
+	return out
+}
+
+// connectionDetails returns the canonical location of the repository in
+// bitbucket, the URLs it is cloned from and the URL it is browsed at
+func (c *external) connectionDetails(repository *bitbucket.Repository) managed.ConnectionDetails {
+	cd := managed.ConnectionDetails{
+		keyProjectKey:     []byte(repository.Project),
+		keyRepositorySlug: []byte(repository.Slug),
+	}
+	if u, ok := repository.CloneURLs[bitbucket.CloneHTTP]; ok {
+		cd[keyHTTPCloneURL] = []byte(u)
+	}
+	if u, ok := repository.CloneURLs[bitbucket.CloneSSH]; ok {
+		cd[keySSHCloneURL] = []byte(u)
+	}
+	if u := c.preferredCloneURL(repository); u != "" {
+		cd[keyPreferredCloneURL] = []byte(u)
+	}
+	if repository.WebURL != "" {
+		cd[keyWebURL] = []byte(repository.WebURL)
+	}
+	return c.connectionKeys.Apply(cd)
+}
+
+// preferredCloneURL returns the clone URL of the preferred protocol, or of the
+// other protocol when the repository cannot be cloned with the preferred one
+func (c *external) preferredCloneURL(repository *bitbucket.Repository) string {
+	preferred, other := bitbucket.CloneHTTP, bitbucket.CloneSSH
+	if c.cloneProtocol == bitbucket.CloneSSH {
+		preferred, other = other, preferred
+	}
+	if u, ok := repository.CloneURLs[preferred]; ok {
+		return u
+	}
+	return repository.CloneURLs[other]
+}
+
+// anonymousAccess reports whether anonymous users can read the repository,
+// which a public project grants regardless of the public flag of the repository
+func (c *external) anonymousAccess(ctx context.Context, repository *bitbucket.Repository) (bool, error) {
+	if repository.Public {
+		return true, nil
+	}
+	project, err := c.service.Projects.Get(ctx, &bitbucket.GetProjectRequest{Key: repository.Project})
+	if err != nil {
+		return false, errors.Wrap(err, errGetProject)
+	}
+	return project.Public, nil
+}
+
+// coreFieldsUpToDate reports whether the fields set through the repository
+// endpoint itself match the spec
+func coreFieldsUpToDate(cr *v1alpha1.Repository, repository *bitbucket.Repository, desired *bitbucket.Repository) bool {
+	return len(changedFields(cr, repository, desired)) == 0
+}
+
+// archivedUpToDate reports whether the archive state matches the spec, if managed
+func archivedUpToDate(cr *v1alpha1.Repository, repository *bitbucket.Repository) bool {
+	return cr.Spec.ForProvider.Archived == nil || *cr.Spec.ForProvider.Archived == repository.IsArchived()
+}
+
+// unarchive reports whether the spec asks for the repository to be unarchived
+func unarchive(cr *v1alpha1.Repository) bool {
+	return cr.Spec.ForProvider.Archived != nil && !*cr.Spec.ForProvider.Archived
+}
+
+// groupsEqual reports whether the groups in the spec match the groups in bitbucket.
+// Both are compared as sets, so neither order nor duplicates matter. Group
+// names are compared case-insensitively as bitbucket may return them in a
+// different casing than specified, permissions must match exactly.
 func groupsEqual(crGroups []v1alpha1.AdGroup, groups []bitbucket.Group) bool {
-	if len(crGroups) != len(groups) {
+	want := map[string]bool{}
+	for _, crGroup := range crGroups {
+		want[groupKey(crGroup.Name, crGroup.Permission)] = true
+	}
+	got := map[string]bool{}
+	for _, group := range groups {
+		got[groupKey(group.Name, group.Permission)] = true
+	}
+
+	if len(want) != len(got) {
 		return false
 	}
+	for key := range want {
+		if !got[key] {
+			return false
+		}
+	}
+	return true
+}
 
-	for _, crGroup := range crGroups {
-		found := false
+// groupKey identifies a permission of a group in groupsEqual
+func groupKey(name, permission string) string {
+	return strings.ToLower(name) + "/" + permission
+}
 
-		for _, group := range groups {
-			if crGroup.Name == group.Name && crGroup.Permission == group.Permission {
-				found = true
+// pruneUnknownGroups reports whether groups not in the spec should be revoked
+func pruneUnknownGroups(cr *v1alpha1.Repository) bool {
+	if initOnlyGroups(cr) {
+		return false
+	}
+	return cr.Spec.ForProvider.PruneUnknownGroups == nil || *cr.Spec.ForProvider.PruneUnknownGroups
+}
+
+// specifiedGroups filters groups down to the ones named in the spec
+func specifiedGroups(crGroups []v1alpha1.AdGroup, groups []bitbucket.Group) []bitbucket.Group {
+	filtered := []bitbucket.Group{}
+	for _, group := range groups {
+		for _, crGroup := range crGroups {
+			if strings.EqualFold(group.Name, crGroup.Name) {
+				filtered = append(filtered, group)
 				break
 			}
 		}
-
-		if !found {
-			return false
-		}
 	}
-	return true
+	return filtered
 }
 
 func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
@@ -236,31 +683,82 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalCreation{}, errors.New(errNotRepository)
 	}
 
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
 	cr.SetConditions(xpv1.Creating())
 
+	// initProvider seeds the parameters unset in forProvider on creation
+	desired := withInitProvider(cr)
+
+	if c.namePattern != nil && !c.namePattern.MatchString(desired.Spec.ForProvider.Name) {
+		return managed.ExternalCreation{}, errors.Errorf(errNameMismatch, desired.Spec.ForProvider.Name, c.namePattern)
+	}
+	if err := c.checkGroupCount(desired); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	description, err := renderDescription(desired)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if err := validateDescription(description); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
 	repoToCreate := &bitbucket.Repository{
-		Name:        cr.Spec.ForProvider.Name,
-		Project:     cr.Spec.ForProvider.Project,
-		Description: cr.Spec.ForProvider.Description,
+		Name:        desired.Spec.ForProvider.Name,
+		Project:     desired.Spec.ForProvider.Project,
+		Description: description,
+		Public:      desired.Spec.ForProvider.Public,
 	}
 
 	log.Printf("Attempting to create Repository %+v\n", repoToCreate)
 
 	repository, err := c.service.Repositories.Create(ctx, repoToCreate)
+	if errors.Is(err, bitbucket.ErrConflict) {
+		// another replica may have won the race to create the repository
+		repository, err = c.adopt(ctx, desired, repoToCreate)
+	}
+	if err != nil {
+		log.Println(err)
+		return managed.ExternalCreation{}, err
+	}
+
+	// don't configure or publish a repository bitbucket is still provisioning
+	repository, err = c.waitUntilAvailable(ctx, repository)
 	if err != nil {
 		log.Println(err)
 		return managed.ExternalCreation{}, err
 	}
 
-	for _, g := range cr.Spec.ForProvider.Groups {
-		group := bitbucket.Group{
-			Name:       g.Name,
-			Permission: g.Permission,
+	// protect the repository before anything else, a failure is retried by
+	// the next reconcile as the missing restrictions are reported as drift
+	if err := c.updateDefaultBranchProtection(ctx, desired, repository); err != nil {
+		log.Printf("Error protecting default branch: %v", err)
+		err = errors.Wrapf(err, errProtectionCreated, repository.Name)
+		if c.recorder != nil {
+			c.recorder.Event(cr, event.Warning(reasonProtectionFailed, err))
 		}
-		log.Printf("Creating permission %+v for repository %+v\n", group, repository)
-		err = c.service.Repositories.AddGroup(ctx, repository, &group)
-		if err != nil {
-			log.Printf("Error creating permission: %v", err)
+		return managed.ExternalCreation{}, err
+	}
+
+	log.Printf("Creating permissions %+v for repository %+v\n", desired.Spec.ForProvider.Groups, repository)
+	if err := c.forEachGroup(ctx, specGroups(desired), func(ctx context.Context, group *bitbucket.Group) error {
+		return c.service.Repositories.AddGroup(ctx, repository, group)
+	}); err != nil {
+		log.Printf("Error creating permission: %v", err)
+		return managed.ExternalCreation{}, groupScopeError(err, errGroupsCreated, repository.Name)
+	}
+	// copy the template before applying the spec so the spec takes precedence
+	c.copyTemplate(ctx, desired, repository)
+	for _, s := range c.settings() {
+		if s.name == settingDefaultBranchProtection {
+			continue
+		}
+		if err := s.update(ctx, desired, repository); err != nil {
+			log.Printf("Error configuring %s: %v", s.name, err)
 			return managed.ExternalCreation{}, err
 		}
 	}
@@ -271,69 +769,202 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	return managed.ExternalCreation{
 		// Optionally return any details that may be required to connect to the
 		// external resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
+		ConnectionDetails: c.connectionDetails(repository),
 	}, nil
 }
 
+// recordVisibilityChange reports a change of the public flag of the
+// repository, which is security relevant, as a warning event and in the audit log
+func (c *external) recordVisibilityChange(cr *v1alpha1.Repository, repository *bitbucket.Repository, wasPublic bool) {
+	msg := fmt.Sprintf("visibility of repository %s/%s changed from public=%t to public=%t", repository.Project, repository.Slug, wasPublic, repository.Public)
+	log.Printf("AUDIT: %s\n", msg)
+	if c.recorder != nil {
+		c.recorder.Event(cr, event.Event{Type: event.TypeWarning, Reason: reasonVisibilityChanged, Message: msg})
+	}
+}
+
+// recordAnonymousAccess records that a private repository is readable
+// anonymously because its project is public
+func (c *external) recordAnonymousAccess(cr *v1alpha1.Repository, repository *bitbucket.Repository) {
+	msg := fmt.Sprintf("repository %s/%s is readable anonymously because its project is public, make the project private or set public to true", repository.Project, repository.Slug)
+	log.Println(msg)
+	if c.recorder != nil {
+		c.recorder.Event(cr, event.Event{Type: event.TypeWarning, Reason: reasonAnonymousAccess, Message: msg})
+	}
+}
+
+// adopt takes over an existing repository that matches the spec
+func (c *external) adopt(ctx context.Context, cr *v1alpha1.Repository, repoToCreate *bitbucket.Repository) (*bitbucket.Repository, error) {
+	repository, err := c.service.Repositories.Get(ctx, repoToCreate)
+	if err != nil {
+		return nil, errors.Wrap(err, errAdopt)
+	}
+	if !coreFieldsUpToDate(cr, repository, repoToCreate) {
+		return nil, errors.Errorf(errAdoptMismatch, repoToCreate.Name, repoToCreate.Project)
+	}
+	log.Printf("Adopting existing repository %+v\n", repository)
+	return repository, nil
+}
+
 func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
 	cr, ok := mg.(*v1alpha1.Repository)
 	if !ok {
 		return managed.ExternalUpdate{}, errors.New(errNotRepository)
 	}
 
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
+	u, summary, err := c.update(ctx, cr)
+	logUpdate(cr, summary, err)
+	return u, err
+}
+
+// update updates the repository and returns a summary of the changes it
+// applied, which are the changes applied so far when it fails
+func (c *external) update(ctx context.Context, cr *v1alpha1.Repository) (managed.ExternalUpdate, updateSummary, error) {
+	summary := updateSummary{}
 	log.Printf("Attempting to update repository %s\n", cr.Name)
 
+	description, err := renderDescription(cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, summary, err
+	}
+	if err := validateDescription(description); err != nil {
+		return managed.ExternalUpdate{}, summary, err
+	}
+	if err := c.checkGroupCount(cr); err != nil {
+		return managed.ExternalUpdate{}, summary, err
+	}
+
 	repoToUpdate := &bitbucket.Repository{
 		Name:        cr.Spec.ForProvider.Name,
 		Project:     cr.Spec.ForProvider.Project,
-		Description: cr.Spec.ForProvider.Description,
+		Description: description,
+		Public:      cr.Spec.ForProvider.Public,
+		Archived:    cr.Spec.ForProvider.Archived,
 	}
 
-	repo, err := c.service.Repositories.Update(ctx, repoToUpdate)
+	repo, err := c.getRepository(ctx, cr)
 	if err != nil {
 		log.Println(err)
-		return managed.ExternalUpdate{}, err
+		return managed.ExternalUpdate{}, summary, err
+	}
+	// a repository whose project changed in the spec is moved before the
+	// rest is updated in its new project
+	if !strings.EqualFold(repo.Project, repoToUpdate.Project) {
+		log.Printf("Moving repository %s from %s to %s\n", repo.Name, repo.Project, repoToUpdate.Project)
+		summary.MovedFrom = repo.Project
+		repo, err = c.service.Repositories.Move(ctx, repo, repoToUpdate.Project)
+		if err != nil {
+			return managed.ExternalUpdate{}, summary, errors.Wrap(err, errMove)
+		}
+		cr.Status.AtProvider.Project = repo.Project
+	}
+	// fields seeded from initProvider keep their current value
+	if initOnlyDescription(cr) {
+		repoToUpdate.Description = repo.Description
+	}
+	if initOnlyPublic(cr) {
+		repoToUpdate.Public = repo.Public
+	}
+
+	// only PUT the repository when its own fields changed, e.g. not when only groups drifted
+	if fields := changedFields(cr, repo, repoToUpdate); len(fields) > 0 {
+		wasPublic := repo.Public
+		repo, err = c.service.Repositories.Update(ctx, repoToUpdate)
+		if err != nil {
+			log.Println(err)
+			return managed.ExternalUpdate{}, summary, err
+		}
+		summary.Fields = fields
+		if repo.Public != wasPublic {
+			c.recordVisibilityChange(cr, repo, wasPublic)
+		}
+	}
+
+	// the rest cannot be written once the repository is archived
+	if repo.IsArchived() {
+		log.Printf("Repository %+v is archived, skipping the rest of the update\n", repo)
+		synced(cr)
+		return managed.ExternalUpdate{ConnectionDetails: c.connectionDetails(repo)}, summary, nil
 	}
 
 	groups, err := c.service.Repositories.GetGroups(ctx, repo)
 	if err != nil {
 		log.Println(err)
-		return managed.ExternalUpdate{}, err
+		return managed.ExternalUpdate{}, summary, err
 	}
+	groups = directGroups(groups)
+	granted := grantedGroups(cr, groups)
 
 	// Update all groups
-	for _, group := range cr.Spec.ForProvider.Groups {
-		log.Printf("Updating permission %+v for repository %+v\n", group, repo)
-		err = c.service.Repositories.AddGroup(ctx, repo, &bitbucket.Group{Name: group.Name, Permission: group.Permission})
-		if err != nil {
-			return managed.ExternalUpdate{}, err
-		}
+	log.Printf("Updating permissions %+v for repository %+v\n", cr.Spec.ForProvider.Groups, repo)
+	if err := c.forEachGroup(ctx, specGroups(cr), func(ctx context.Context, group *bitbucket.Group) error {
+		return c.service.Repositories.AddGroup(ctx, repo, group)
+	}); err != nil {
+		return managed.ExternalUpdate{}, summary, groupScopeError(err, errGroupsScope, repo.Name)
 	}
+	summary.GroupsGranted = granted
 
 	// Delete unknown groups
-	for _, group := range groups {
-		found := false
-		for _, crGroup := range cr.Spec.ForProvider.Groups {
-			if group.Name == crGroup.Name {
-				found = true
-				break
+	if pruneUnknownGroups(cr) {
+		unknown := []bitbucket.Group{}
+		for _, group := range groups {
+			found := false
+			for _, crGroup := range cr.Spec.ForProvider.Groups {
+				if strings.EqualFold(group.Name, crGroup.Name) {
+					found = true
+					break
+				}
 			}
-		}
-		if !found {
-			err = c.service.Repositories.RevokeGroup(ctx, repo, &group)
-			if err != nil {
-				return managed.ExternalUpdate{}, err
+			if !found {
+				unknown = append(unknown, group)
 			}
 		}
+		// every revoke is attempted, the failed ones are named in the error
+		if err := c.forEachGroup(ctx, unknown, func(ctx context.Context, group *bitbucket.Group) error {
+			return errors.Wrapf(c.service.Repositories.RevokeGroup(ctx, repo, group), errRevokeGroup, group.Name)
+		}); err != nil {
+			return managed.ExternalUpdate{}, summary, groupScopeError(err, errGroupsScope, repo.Name)
+		}
+		for _, group := range unknown {
+			summary.GroupsRevoked = append(summary.GroupsRevoked, group.Name)
+		}
+	}
+
+	// only the settings that drifted are updated
+	for _, s := range c.settings() {
+		upToDate, err := s.upToDate(ctx, cr, repo)
+		if err != nil {
+			return managed.ExternalUpdate{}, summary, err
+		}
+		if upToDate {
+			continue
+		}
+		if err := s.update(ctx, cr, repo); err != nil {
+			return managed.ExternalUpdate{}, summary, err
+		}
+		summary.Settings = append(summary.Settings, s.name)
 	}
 
 	log.Printf("Finished updating repository %+v\n", repo)
+	synced(cr)
 
 	return managed.ExternalUpdate{
 		// Optionally return any details that may be required to connect to the
 		// external resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
-	}, nil
+		ConnectionDetails: c.connectionDetails(repo),
+	}, summary, nil
+}
+
+// validateDescription rejects descriptions bitbucket would refuse with a 400
+func validateDescription(description string) error {
+	if n := utf8.RuneCountInString(description); n > maxDescriptionLength {
+		return errors.Errorf(errDescriptionTooLong, n, maxDescriptionLength)
+	}
+	return nil
 }
 
 func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
@@ -342,12 +973,82 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 		return errors.New(errNotRepository)
 	}
 
+	// let the requests finish when the provider is shutting down
+	ctx, cancel := graceful.Context(ctx)
+	defer cancel()
+
 	log.Printf("Attempting to delete repository %s\n", cr.Spec.ForProvider.Name)
 
 	cr.SetConditions(xpv1.Deleting())
 
-	return c.service.Repositories.Delete(ctx, &bitbucket.Repository{
+	repository := &bitbucket.Repository{
 		Name:    cr.Spec.ForProvider.Name,
 		Project: cr.Spec.ForProvider.Project,
-	})
+	}
+	if err := c.confirmDelete(ctx, cr, repository); err != nil {
+		return err
+	}
+
+	err := c.service.Repositories.Delete(ctx, repository)
+	if err == nil {
+		return nil
+	}
+
+	// the transition time of the condition is when the deletion first failed
+	cr.SetConditions(v1alpha1.DeletionBlocked())
+	after := cr.Spec.ForProvider.OrphanStuckDeletionAfter
+	if after == nil || time.Since(cr.GetCondition(v1alpha1.TypeDeletionBlocked).LastTransitionTime.Time) < after.Duration {
+		return err
+	}
+
+	log.Printf("Orphaning repository %s that could not be deleted for %s: %v\n", repository.Name, after.Duration, err)
+	cr.SetConditions(v1alpha1.DeletionOrphaned())
+	if c.recorder != nil {
+		msg := fmt.Sprintf("repository could not be deleted for %s and is left in bitbucket: %v", after.Duration, err)
+		c.recorder.Event(cr, event.Event{Type: event.TypeWarning, Reason: reasonDeletionOrphaned, Message: msg})
+	}
+	return nil
+}
+
+// orphaned returns true if the deletion of the repository was given up
+func orphaned(cr *v1alpha1.Repository) bool {
+	return cr.GetCondition(v1alpha1.TypeDeletionBlocked).Reason == v1alpha1.ReasonDeletionOrphaned
+}
+
+// confirmDelete returns an error if the repository holds data that requires
+// the deletion to be confirmed and the deletion has not been confirmed
+func (c *external) confirmDelete(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	dc := cr.Spec.ForProvider.DeleteConfirmation
+	if dc == nil || cr.GetAnnotations()[v1alpha1.AnnotationConfirmDelete] == "true" {
+		return nil
+	}
+
+	// a repository that is already gone holds no data
+	if dc.MaxSize != nil {
+		size, err := c.service.Repositories.GetSize(ctx, repository)
+		if errors.Is(err, bitbucket.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, errGetSize)
+		}
+		if size > *dc.MaxSize {
+			return errors.Errorf(errDeleteUnconfirmed, repository.Name, fmt.Sprintf("is larger than %d bytes", *dc.MaxSize), v1alpha1.AnnotationConfirmDelete)
+		}
+	}
+
+	if dc.IfCommits {
+		hasCommits, err := c.service.Repositories.HasCommits(ctx, repository)
+		if errors.Is(err, bitbucket.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, errGetCommits)
+		}
+		if hasCommits {
+			return errors.Errorf(errDeleteUnconfirmed, repository.Name, "contains commits", v1alpha1.AnnotationConfirmDelete)
+		}
+	}
+
+	return nil
 }
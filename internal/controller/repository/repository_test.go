@@ -16,6 +16,35 @@ limitations under the License.
 
 package repository
 
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	apisv1alpha1 "github.com/MrVinkel/provider-bitbucketserver/apis/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket/fake"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/controller/config"
+)
+
 // Unlike many Kubernetes projects Crossplane does not use third party testing
 // libraries, per the common Go test review comments. Crossplane encourages the
 // use of table driven unit tests. The tests of the crossplane-runtime project
@@ -61,3 +90,1930 @@ package repository
 // 		})
 // 	}
 // }
+
+func TestValidateDescription(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		description string
+		want        error
+	}{
+		"Empty": {
+			reason:      "An empty description should be accepted",
+			description: "",
+		},
+		"AtLimit": {
+			reason:      "A description of exactly the maximum length should be accepted",
+			description: strings.Repeat("a", maxDescriptionLength),
+		},
+		"MultiByteAtLimit": {
+			reason:      "The length should be counted in characters, not bytes",
+			description: strings.Repeat("ø", maxDescriptionLength),
+		},
+		"OverLimit": {
+			reason:      "A description one character over the maximum length should be rejected",
+			description: strings.Repeat("a", maxDescriptionLength+1),
+			want:        errors.Errorf(errDescriptionTooLong, maxDescriptionLength+1, maxDescriptionLength),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateDescription(tc.description)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nvalidateDescription(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGroupsEqual(t *testing.T) {
+	type args struct {
+		crGroups []v1alpha1.AdGroup
+		groups   []bitbucket.Group
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   bool
+	}{
+		"Equal": {
+			reason: "Identical groups should be equal",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_ADMIN"}},
+				groups:   []bitbucket.Group{{Name: "admins", Permission: "REPO_ADMIN"}},
+			},
+			want: true,
+		},
+		"MismatchedNameCasing": {
+			reason: "Group names differing only in casing should be equal",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "My_AD_Group", Permission: "REPO_WRITE"}},
+				groups:   []bitbucket.Group{{Name: "my_ad_group", Permission: "REPO_WRITE"}},
+			},
+			want: true,
+		},
+		"MismatchedPermissionCasing": {
+			reason: "Permissions should be compared exactly",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "repo_admin"}},
+				groups:   []bitbucket.Group{{Name: "admins", Permission: "REPO_ADMIN"}},
+			},
+			want: false,
+		},
+		"DifferentPermission": {
+			reason: "A group with a different permission should not be equal",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_READ"}},
+				groups:   []bitbucket.Group{{Name: "ADMINS", Permission: "REPO_ADMIN"}},
+			},
+			want: false,
+		},
+		"MissingGroup": {
+			reason: "A group missing in bitbucket should not be equal",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_ADMIN"}},
+				groups:   []bitbucket.Group{},
+			},
+			want: false,
+		},
+		"Reordered": {
+			reason: "The order of the groups should not matter",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "devs", Permission: "REPO_WRITE"}},
+				groups:   []bitbucket.Group{{Name: "devs", Permission: "REPO_WRITE"}, {Name: "admins", Permission: "REPO_ADMIN"}},
+			},
+			want: true,
+		},
+		"DuplicateInSpec": {
+			reason: "A group listed twice in the spec should not be reported as drift",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "Admins", Permission: "REPO_ADMIN"}},
+				groups:   []bitbucket.Group{{Name: "admins", Permission: "REPO_ADMIN"}},
+			},
+			want: true,
+		},
+		"DuplicateInBitbucket": {
+			reason: "A group returned twice by bitbucket should not be reported as drift",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "devs", Permission: "REPO_WRITE"}},
+				groups:   []bitbucket.Group{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "devs", Permission: "REPO_WRITE"}, {Name: "devs", Permission: "REPO_WRITE"}},
+			},
+			want: true,
+		},
+		"DuplicateHidesMissingGroup": {
+			reason: "A duplicate should not make up for a group missing in bitbucket",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "devs", Permission: "REPO_WRITE"}},
+				groups:   []bitbucket.Group{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "admins", Permission: "REPO_ADMIN"}},
+			},
+			want: false,
+		},
+		"ExtraGroup": {
+			reason: "A group in bitbucket missing in the spec should not be equal",
+			args: args{
+				crGroups: []v1alpha1.AdGroup{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "admins", Permission: "REPO_ADMIN"}},
+				groups:   []bitbucket.Group{{Name: "admins", Permission: "REPO_ADMIN"}, {Name: "devs", Permission: "REPO_WRITE"}},
+			},
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := groupsEqual(tc.args.crGroups, tc.args.groups)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ngroupsEqual(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+type repositoryModifier func(*v1alpha1.Repository)
+
+func withGroups(groups ...v1alpha1.AdGroup) repositoryModifier {
+	return func(r *v1alpha1.Repository) { r.Spec.ForProvider.Groups = groups }
+}
+
+func withPruneUnknownGroups(prune bool) repositoryModifier {
+	return func(r *v1alpha1.Repository) { r.Spec.ForProvider.PruneUnknownGroups = &prune }
+}
+
+func repository(m ...repositoryModifier) *v1alpha1.Repository {
+	cr := &v1alpha1.Repository{}
+	cr.Spec.ForProvider.Name = "repo"
+	cr.Spec.ForProvider.Project = "PRJ"
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+// privateProject returns a project service serving a project that is not public
+func privateProject() *fake.MockProjectService {
+	return &fake.MockProjectService{
+		MockGet: func(_ context.Context, req *bitbucket.GetProjectRequest) (*bitbucket.Project, error) {
+			return &bitbucket.Project{Key: req.Key}, nil
+		},
+	}
+}
+
+// groupCalls records the group operations issued against a fake repository service
+type groupCalls struct {
+	added   []string
+	revoked []string
+}
+
+// newGroupService returns a fake repository service holding the supplied
+// bitbucket groups, recording the group operations made in calls.
+func newGroupService(existing []bitbucket.Group, calls *groupCalls) *fake.MockRepositoryService {
+	return &fake.MockRepositoryService{
+		MockGet: func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+			return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project}, nil
+		},
+		MockUpdate: func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+			return r, nil
+		},
+		MockGetGroups: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Group, error) {
+			return existing, nil
+		},
+		MockGetGroupPermission: func(_ context.Context, _ *bitbucket.Repository, name string) (string, error) {
+			for _, g := range existing {
+				if !g.Inherited && strings.EqualFold(g.Name, name) {
+					return g.Permission, nil
+				}
+			}
+			return "", bitbucket.ErrNotFound
+		},
+		MockCountOpenPullRequests: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+			return 0, nil
+		},
+		MockGetRequiredApprovals: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+			return 0, nil
+		},
+		MockGetUsers: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.UserPermission, error) {
+			return nil, nil
+		},
+		MockAddGroup: func(_ context.Context, _ *bitbucket.Repository, g *bitbucket.Group) error {
+			calls.added = append(calls.added, g.Name)
+			return nil
+		},
+		MockRevokeGroup: func(_ context.Context, _ *bitbucket.Repository, g *bitbucket.Group) error {
+			calls.revoked = append(calls.revoked, g.Name)
+			return nil
+		},
+	}
+}
+
+func TestObserveUnknownGroups(t *testing.T) {
+	details := managed.ConnectionDetails{keyProjectKey: []byte("PRJ"), keyRepositorySlug: []byte("repo")}
+	existing := []bitbucket.Group{
+		{Name: "managed", Permission: "REPO_WRITE"},
+		{Name: "manual", Permission: "REPO_READ"},
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   managed.ExternalObservation
+	}{
+		"Prune": {
+			reason: "An unknown group should be reported as drift when pruning",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"})),
+			want:   managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false, ConnectionDetails: details},
+		},
+		"Additive": {
+			reason: "An unknown group should be ignored when not pruning",
+			mg: repository(
+				withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"}),
+				withPruneUnknownGroups(false),
+			),
+			want: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: details},
+		},
+		"AdditiveMissing": {
+			reason: "A missing group should be reported as drift when not pruning",
+			mg: repository(
+				withGroups(v1alpha1.AdGroup{Name: "other", Permission: "REPO_WRITE"}),
+				withPruneUnknownGroups(false),
+			),
+			want: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false, ConnectionDetails: details},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: newGroupService(existing, &groupCalls{})}}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpdateUnknownGroups(t *testing.T) {
+	existing := []bitbucket.Group{
+		{Name: "managed", Permission: "REPO_READ"},
+		{Name: "manual", Permission: "REPO_READ"},
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   groupCalls
+	}{
+		"Prune": {
+			reason: "Groups not in the spec should be revoked by default",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"})),
+			want:   groupCalls{added: []string{"managed"}, revoked: []string{"manual"}},
+		},
+		"Additive": {
+			reason: "Groups not in the spec should be left alone when not pruning",
+			mg: repository(
+				withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"}),
+				withPruneUnknownGroups(false),
+			),
+			want: groupCalls{added: []string{"managed"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			calls := groupCalls{}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: newGroupService(existing, &calls)}}
+			if _, err := e.Update(context.Background(), tc.mg); err != nil {
+				t.Fatalf("e.Update(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, calls, cmp.AllowUnexported(groupCalls{})); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInheritedGroups(t *testing.T) {
+	existing := []bitbucket.Group{
+		{Name: "managed", Permission: "REPO_WRITE"},
+		{Name: "project-admins", Permission: "REPO_ADMIN", Inherited: true},
+		{Name: "managed-inherited", Permission: "REPO_READ", Inherited: true},
+	}
+
+	type want struct {
+		upToDate bool
+		calls    groupCalls
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *v1alpha1.Repository
+		want   want
+	}{
+		"InheritedIgnored": {
+			reason: "Inherited grants should neither be drift nor be revoked",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"})),
+			want:   want{upToDate: true},
+		},
+		"InheritedNotDirect": {
+			reason: "A group in the spec only granted through the project should still be granted on the repository",
+			mg: repository(withGroups(
+				v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"},
+				v1alpha1.AdGroup{Name: "managed-inherited", Permission: "REPO_READ"},
+			)),
+			want: want{calls: groupCalls{added: []string{"managed", "managed-inherited"}}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: newGroupService(existing, &got.calls)}}
+			o, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			got.upToDate = o.ResourceUpToDate
+			if !o.ResourceUpToDate {
+				if _, err := e.Update(context.Background(), tc.mg); err != nil {
+					t.Fatalf("e.Update(...): %v", err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}, groupCalls{})); diff != "" {
+				t.Errorf("\n%s\nreconciling groups: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNamePattern(t *testing.T) {
+	type want struct {
+		created bool
+		err     error
+	}
+	pattern := regexp.MustCompile("^svc-")
+
+	cases := map[string]struct {
+		reason  string
+		name    string
+		pattern *regexp.Regexp
+		want    want
+	}{
+		"NoPattern": {
+			reason: "Any name should be created without a pattern",
+			name:   "billing",
+			want:   want{created: true},
+		},
+		"Conforming": {
+			reason:  "A name matching the pattern should be created",
+			name:    "svc-billing",
+			pattern: pattern,
+			want:    want{created: true},
+		},
+		"NonConforming": {
+			reason:  "A name not matching the pattern should be rejected before calling bitbucket",
+			name:    "billing",
+			pattern: pattern,
+			want:    want{err: errors.Errorf(errNameMismatch, "billing", "^svc-")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockCreate = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				got.created = true
+				return r, nil
+			}
+			e := external{namePattern: tc.pattern, service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.Name = tc.name })
+
+			_, got.err = e.Create(context.Background(), cr)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreateProtection(t *testing.T) {
+	type want struct {
+		err          error
+		events       []event.Reason
+		groups       []string
+		restrictions []string
+	}
+	errBoom := errors.New("boom")
+
+	// an in memory repository whose first attempt to add the no-deletes
+	// restriction fails
+	restrictions := []bitbucket.Restriction{}
+	failed := false
+	calls := groupCalls{}
+	svc := newGroupService(nil, &calls)
+	svc.MockCreate = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+		return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project}, nil
+	}
+	svc.MockGetDefaultBranch = func(_ context.Context, _ *bitbucket.Repository) (string, error) {
+		return "refs/heads/main", nil
+	}
+	svc.MockGetRestrictions = func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Restriction, error) {
+		return restrictions, nil
+	}
+	svc.MockAddRestriction = func(_ context.Context, _ *bitbucket.Repository, r *bitbucket.Restriction) error {
+		if r.Type == bitbucket.RestrictionNoDeletes && !failed {
+			failed = true
+			return errBoom
+		}
+		restrictions = append(restrictions, *r)
+		return nil
+	}
+	types := func() []string {
+		t := []string{}
+		for _, r := range restrictions {
+			t = append(t, r.Type)
+		}
+		return t
+	}
+
+	recorder := &eventRecorder{}
+	e := external{recorder: recorder, service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+	cr := repository(
+		withGroups(v1alpha1.AdGroup{Name: "devs", Permission: "REPO_WRITE"}),
+		func(r *v1alpha1.Repository) { r.Spec.ForProvider.ProtectDefaultBranch = boolPtr(true) },
+	)
+
+	_, err := e.Create(context.Background(), cr)
+	got := want{err: err, groups: calls.added, restrictions: types()}
+	for _, ev := range recorder.events {
+		got.events = append(got.events, ev.Reason)
+	}
+	partial := want{
+		err:          errors.Wrapf(errBoom, errProtectionCreated, "repo"),
+		events:       []event.Reason{reasonProtectionFailed},
+		restrictions: []string{bitbucket.RestrictionFastForwardOnly},
+	}
+	if diff := cmp.Diff(partial, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+		t.Errorf("e.Create(...) failing to protect the default branch: -want, +got:\n%s\n", diff)
+	}
+
+	o, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if o.ResourceUpToDate || !strings.Contains(cr.Status.AtProvider.DriftReason, settingDefaultBranchProtection) {
+		t.Errorf("e.Observe(...) after failing to protect the default branch: want drift of %q, got %q", settingDefaultBranchProtection, cr.Status.AtProvider.DriftReason)
+	}
+
+	if _, err := e.Update(context.Background(), cr); err != nil {
+		t.Fatalf("e.Update(...): %v", err)
+	}
+	retried := []string{bitbucket.RestrictionFastForwardOnly, bitbucket.RestrictionNoDeletes, bitbucket.RestrictionPullRequestOnly}
+	if diff := cmp.Diff(retried, types()); diff != "" {
+		t.Errorf("e.Update(...) retrying the protection: -want restrictions, +got restrictions:\n%s\n", diff)
+	}
+}
+
+func TestLastSyncTime(t *testing.T) {
+	type want struct {
+		failed   bool
+		advanced bool
+	}
+	errBoom := errors.New("boom")
+	last := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	cases := map[string]struct {
+		reason    string
+		reconcile func(e external, mg resource.Managed) error
+		getErr    error
+		want      want
+	}{
+		"Observe": {
+			reason: "A successful observation should advance the last sync time",
+			reconcile: func(e external, mg resource.Managed) error {
+				_, err := e.Observe(context.Background(), mg)
+				return err
+			},
+			want: want{advanced: true},
+		},
+		"Update": {
+			reason: "A successful update should advance the last sync time",
+			reconcile: func(e external, mg resource.Managed) error {
+				_, err := e.Update(context.Background(), mg)
+				return err
+			},
+			want: want{advanced: true},
+		},
+		"ObserveError": {
+			reason: "A failed observation should keep the last sync time",
+			reconcile: func(e external, mg resource.Managed) error {
+				_, err := e.Observe(context.Background(), mg)
+				return err
+			},
+			getErr: errBoom,
+			want:   want{failed: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService(nil, &groupCalls{})
+			get := svc.MockGet
+			svc.MockGet = func(ctx context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+				return get(ctx, r)
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Status.AtProvider.LastSyncTime = last.DeepCopy() })
+
+			err := tc.reconcile(e, cr)
+			got := want{failed: err != nil, advanced: cr.Status.AtProvider.LastSyncTime.After(last.Time)}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nlast sync time: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectionDetails(t *testing.T) {
+	// bitbucket derives the slug from the name and upper cases project keys
+	webURL := "https://bitbucket.example.com/projects/PRJ/repos/my-repo/browse"
+	canonical := &fake.MockRepositoryService{
+		MockGet: func(_ context.Context, _ *bitbucket.Repository) (*bitbucket.Repository, error) {
+			return &bitbucket.Repository{Name: "My Repo", Slug: "my-repo", Project: "PRJ", WebURL: webURL}, nil
+		},
+		MockCreate: func(_ context.Context, _ *bitbucket.Repository) (*bitbucket.Repository, error) {
+			return &bitbucket.Repository{Name: "My Repo", Slug: "my-repo", Project: "PRJ", WebURL: webURL}, nil
+		},
+		MockGetGroups: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Group, error) {
+			return []bitbucket.Group{}, nil
+		},
+		MockCountOpenPullRequests: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+			return 0, nil
+		},
+		MockGetRequiredApprovals: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+			return 0, nil
+		},
+		MockGetUsers: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.UserPermission, error) {
+			return nil, nil
+		},
+	}
+	want := managed.ConnectionDetails{
+		keyProjectKey:     []byte("PRJ"),
+		keyRepositorySlug: []byte("my-repo"),
+		keyWebURL:         []byte(webURL),
+	}
+
+	e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: canonical}}
+	mg := repository(func(r *v1alpha1.Repository) {
+		r.Spec.ForProvider.Name = "My Repo"
+		r.Spec.ForProvider.Project = "prj"
+	})
+
+	o, err := e.Observe(context.Background(), mg)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if diff := cmp.Diff(want, o.ConnectionDetails); diff != "" {
+		t.Errorf("e.Observe(...): -want, +got:\n%s\n", diff)
+	}
+
+	c, err := e.Create(context.Background(), mg)
+	if err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	if diff := cmp.Diff(want, c.ConnectionDetails); diff != "" {
+		t.Errorf("e.Create(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestPublishRenamedConnectionDetails(t *testing.T) {
+	keys, err := config.NewConnectionKeys(map[string]string{keyRepositorySlug: "slug"}, keyProjectKey, keyRepositorySlug)
+	if err != nil {
+		t.Fatalf("config.NewConnectionKeys(...): %v", err)
+	}
+	svc := &fake.MockRepositoryService{
+		MockGet: func(_ context.Context, _ *bitbucket.Repository) (*bitbucket.Repository, error) {
+			return &bitbucket.Repository{Name: "repo", Slug: "repo", Project: "PRJ"}, nil
+		},
+		MockGetGroups: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Group, error) {
+			return []bitbucket.Group{}, nil
+		},
+		MockCountOpenPullRequests: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+			return 0, nil
+		},
+		MockGetRequiredApprovals: func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+			return 0, nil
+		},
+		MockGetUsers: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.UserPermission, error) {
+			return nil, nil
+		},
+	}
+	e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}, connectionKeys: keys}
+
+	mg := repository(func(r *v1alpha1.Repository) {
+		r.Spec.WriteConnectionSecretToReference = &xpv1.SecretReference{Namespace: "crossplane-system", Name: "repo"}
+	})
+	o, err := e.Observe(context.Background(), mg)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+
+	var published map[string][]byte
+	kube := &test.MockClient{
+		MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "repo")),
+		MockCreate: func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+			published = obj.(*corev1.Secret).Data
+			return nil
+		},
+	}
+	s, err := v1alpha1.SchemeBuilder.Build()
+	if err != nil {
+		t.Fatalf("SchemeBuilder.Build(): %v", err)
+	}
+	if _, err := managed.NewAPISecretPublisher(kube, s).PublishConnection(context.Background(), mg, o.ConnectionDetails); err != nil {
+		t.Fatalf("PublishConnection(...): %v", err)
+	}
+
+	want := map[string][]byte{keyProjectKey: []byte("PRJ"), "slug": []byte("repo")}
+	if diff := cmp.Diff(want, published); diff != "" {
+		t.Errorf("PublishConnection(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestUpdateSkipsRepositoryPut(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		mg      resource.Managed
+		wantPut bool
+	}{
+		"OnlyGroupsChanged": {
+			reason: "The repository should not be PUT when only groups changed",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"})),
+		},
+		"DescriptionChanged": {
+			reason:  "The repository should be PUT when the description changed",
+			mg:      repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.Description = "new" }),
+			wantPut: true,
+		},
+		"VisibilityChanged": {
+			reason:  "The repository should be PUT when the visibility changed",
+			mg:      repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.Public = true }),
+			wantPut: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			put := false
+			svc := newGroupService([]bitbucket.Group{{Name: "managed", Permission: "REPO_READ"}}, &groupCalls{})
+			svc.MockUpdate = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				put = true
+				return r, nil
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			if _, err := e.Update(context.Background(), tc.mg); err != nil {
+				t.Fatalf("e.Update(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.wantPut, put); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want PUT, +got PUT:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// eventRecorder records the events it is sent
+type eventRecorder struct {
+	events []event.Event
+}
+
+func (r *eventRecorder) Event(_ runtime.Object, e event.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) WithAnnotations(_ ...string) event.Recorder {
+	return r
+}
+
+func TestVisibilityChangeEvent(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   []event.Event
+	}{
+		"MadePublic": {
+			reason: "Making the repository public should record a warning event",
+			mg:     repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.Public = true }),
+			want: []event.Event{{
+				Type:    event.TypeWarning,
+				Reason:  reasonVisibilityChanged,
+				Message: "visibility of repository PRJ/repo changed from public=false to public=true",
+			}},
+		},
+		"DescriptionChanged": {
+			reason: "Updating the repository without changing its visibility should not record an event",
+			mg:     repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.Description = "new" }),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockUpdate = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project, Description: r.Description, Public: r.Public}, nil
+			}
+			recorder := &eventRecorder{}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}, recorder: recorder}
+			if _, err := e.Update(context.Background(), tc.mg); err != nil {
+				t.Fatalf("e.Update(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, recorder.events); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want events, +got events:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDriftReason(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		mg     *v1alpha1.Repository
+		want   string
+	}{
+		"UpToDate": {
+			reason: "No drift should be reported when up to date",
+			mg:     repository(withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"})),
+			want:   "",
+		},
+		"Description": {
+			reason: "A differing description should be reported",
+			mg: repository(
+				withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_WRITE"}),
+				func(r *v1alpha1.Repository) { r.Spec.ForProvider.Description = "new" },
+			),
+			want: "description",
+		},
+		"PublicAndGroups": {
+			reason: "All differing fields should be reported",
+			mg: repository(
+				withGroups(v1alpha1.AdGroup{Name: "managed", Permission: "REPO_READ"}),
+				func(r *v1alpha1.Repository) { r.Spec.ForProvider.Public = true },
+			),
+			want: "public, groups",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService([]bitbucket.Group{{Name: "managed", Permission: "REPO_WRITE"}}, &groupCalls{})
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			o, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, tc.mg.Status.AtProvider.DriftReason); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want == "", o.ResourceUpToDate); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want up to date, +got up to date:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreateConflict(t *testing.T) {
+	type want struct {
+		externalNames []string
+		err           error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		existing *bitbucket.Repository
+		mg       []*v1alpha1.Repository
+		want     want
+	}{
+		"ConcurrentCreates": {
+			reason: "Two replicas creating the same repository should both end up with the one repository",
+			mg:     []*v1alpha1.Repository{repository(), repository()},
+			want:   want{externalNames: []string{"repo", "repo"}},
+		},
+		"Mismatch": {
+			reason:   "An existing repository that differs from the spec should not be adopted",
+			existing: &bitbucket.Repository{Name: "repo", Slug: "repo", Project: "PRJ", Description: "someone else's"},
+			mg:       []*v1alpha1.Repository{repository()},
+			want: want{
+				externalNames: []string{""},
+				err:           errors.Errorf(errAdoptMismatch, "repo", "PRJ"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// an in memory bitbucket holding at most one repository
+			created := tc.existing
+			creates := 0
+			svc := &fake.MockRepositoryService{
+				MockCreate: func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+					if created != nil {
+						return nil, errors.Wrap(bitbucket.ErrConflict, "error creating repository")
+					}
+					creates++
+					created = &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project, Description: r.Description, Public: r.Public}
+					return created, nil
+				},
+				MockGet: func(_ context.Context, _ *bitbucket.Repository) (*bitbucket.Repository, error) {
+					return created, nil
+				},
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+
+			got := want{}
+			for _, cr := range tc.mg {
+				if _, err := e.Create(context.Background(), cr); err != nil {
+					got.err = err
+				}
+				got.externalNames = append(got.externalNames, meta.GetExternalName(cr))
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if tc.existing == nil && creates != 1 {
+				t.Errorf("\n%s\ne.Create(...): want 1 repository created, got %d", tc.reason, creates)
+			}
+		})
+	}
+}
+
+func TestForceSync(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		mg     *v1alpha1.Repository
+		want   bool
+	}{
+		"Forced": {
+			reason: "The force sync annotation should be removed and the removal persisted",
+			mg: repository(func(r *v1alpha1.Repository) {
+				meta.AddAnnotations(r, map[string]string{v1alpha1.AnnotationForceSync: "true"})
+			}),
+			want: true,
+		},
+		"NotForced": {
+			reason: "A repository without the annotation should not be persisted",
+			mg:     repository(),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: newGroupService(nil, &groupCalls{})}}
+			o, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, o.ResourceLateInitialized); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want late initialized, +got late initialized:\n%s\n", tc.reason, diff)
+			}
+			if forceSync(tc.mg) {
+				t.Errorf("\n%s\ne.Observe(...): want force sync annotation removed", tc.reason)
+			}
+		})
+	}
+}
+
+func TestObserveDeleted(t *testing.T) {
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+	errBoom := errors.New("boom")
+
+	cases := map[string]struct {
+		reason string
+		exists bool
+		err    error
+		want   want
+	}{
+		"Exists": {
+			reason: "A repository being deleted that still exists should be reported as existing",
+			exists: true,
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}},
+		},
+		"Gone": {
+			reason: "A repository being deleted that is gone should be reported as not existing",
+			want:   want{o: managed.ExternalObservation{ResourceUpToDate: true}},
+		},
+		"Error": {
+			reason: "An error checking the repository should be returned",
+			err:    errBoom,
+			want:   want{err: errors.Wrap(errBoom, "error checking Bitbucket repository")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// only Exists is mocked, fetching the full repository would panic
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: &fake.MockRepositoryService{
+				MockExists: func(_ context.Context, _ *bitbucket.Repository) (bool, error) {
+					return tc.exists, tc.err
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.SetDeletionTimestamp(&metav1.Time{Time: time.Now()}) })
+
+			got := want{}
+			got.o, got.err = e.Observe(context.Background(), cr)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDeleteConfirmation(t *testing.T) {
+	type want struct {
+		deleted bool
+		err     error
+	}
+	maxSize := int64(1024)
+
+	withConfirmation := func(dc *v1alpha1.DeleteConfirmation) repositoryModifier {
+		return func(r *v1alpha1.Repository) { r.Spec.ForProvider.DeleteConfirmation = dc }
+	}
+	confirmed := func(r *v1alpha1.Repository) {
+		r.SetAnnotations(map[string]string{v1alpha1.AnnotationConfirmDelete: "true"})
+	}
+
+	cases := map[string]struct {
+		reason     string
+		mg         *v1alpha1.Repository
+		size       int64
+		hasCommits bool
+		gone       bool
+		want       want
+	}{
+		"NotRequired": {
+			reason: "A repository without delete confirmation should be deleted",
+			mg:     repository(),
+			size:   1 << 30,
+			want:   want{deleted: true},
+		},
+		"Small": {
+			reason: "A repository below the size should be deleted without confirmation",
+			mg:     repository(withConfirmation(&v1alpha1.DeleteConfirmation{MaxSize: &maxSize})),
+			size:   512,
+			want:   want{deleted: true},
+		},
+		"LargeUnconfirmed": {
+			reason: "A repository above the size should not be deleted without confirmation",
+			mg:     repository(withConfirmation(&v1alpha1.DeleteConfirmation{MaxSize: &maxSize})),
+			size:   2048,
+			want:   want{err: errors.Errorf(errDeleteUnconfirmed, "repo", "is larger than 1024 bytes", v1alpha1.AnnotationConfirmDelete)},
+		},
+		"LargeConfirmed": {
+			reason: "A repository above the size should be deleted when confirmed",
+			mg:     repository(withConfirmation(&v1alpha1.DeleteConfirmation{MaxSize: &maxSize}), confirmed),
+			size:   2048,
+			want:   want{deleted: true},
+		},
+		"CommitsUnconfirmed": {
+			reason:     "A repository with commits should not be deleted without confirmation",
+			mg:         repository(withConfirmation(&v1alpha1.DeleteConfirmation{IfCommits: true})),
+			hasCommits: true,
+			want:       want{err: errors.Errorf(errDeleteUnconfirmed, "repo", "contains commits", v1alpha1.AnnotationConfirmDelete)},
+		},
+		"Empty": {
+			reason: "An empty repository should be deleted without confirmation",
+			mg:     repository(withConfirmation(&v1alpha1.DeleteConfirmation{IfCommits: true})),
+			want:   want{deleted: true},
+		},
+		"AlreadyDeleted": {
+			reason: "A repository that is already gone should not need confirmation",
+			mg:     repository(withConfirmation(&v1alpha1.DeleteConfirmation{MaxSize: &maxSize, IfCommits: true})),
+			gone:   true,
+			want:   want{deleted: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: &fake.MockRepositoryService{
+				MockGetSize: func(_ context.Context, _ *bitbucket.Repository) (int64, error) {
+					if tc.gone {
+						return 0, bitbucket.ErrNotFound
+					}
+					return tc.size, nil
+				},
+				MockHasCommits: func(_ context.Context, _ *bitbucket.Repository) (bool, error) {
+					if tc.gone {
+						return false, bitbucket.ErrNotFound
+					}
+					return tc.hasCommits, nil
+				},
+				MockDelete: func(_ context.Context, _ *bitbucket.Repository) error {
+					got.deleted = true
+					return nil
+				},
+			}}}
+
+			got.err = e.Delete(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStuckDeletion(t *testing.T) {
+	type want struct {
+		err    error
+		reason xpv1.ConditionReason
+		events []event.Reason
+		exists bool
+	}
+	errBoom := errors.New("repository has forks")
+	hour := &metav1.Duration{Duration: time.Hour}
+
+	orphanAfter := func(d *metav1.Duration) repositoryModifier {
+		return func(r *v1alpha1.Repository) { r.Spec.ForProvider.OrphanStuckDeletionAfter = d }
+	}
+	failingSince := func(ago time.Duration) repositoryModifier {
+		return func(r *v1alpha1.Repository) {
+			c := v1alpha1.DeletionBlocked()
+			c.LastTransitionTime = metav1.NewTime(time.Now().Add(-ago))
+			r.SetConditions(c)
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *v1alpha1.Repository
+		want   want
+	}{
+		"Blocking": {
+			reason: "A failed deletion should block and report the condition by default",
+			mg:     repository(failingSince(48 * time.Hour)),
+			want:   want{err: errBoom, reason: v1alpha1.ReasonDeletionFailed, exists: true},
+		},
+		"GracePeriod": {
+			reason: "A failed deletion should block until the grace period has passed",
+			mg:     repository(orphanAfter(hour)),
+			want:   want{err: errBoom, reason: v1alpha1.ReasonDeletionFailed, exists: true},
+		},
+		"Orphan": {
+			reason: "A deletion failing for longer than the grace period should orphan the repository with a warning",
+			mg:     repository(orphanAfter(hour), failingSince(2*time.Hour)),
+			want:   want{reason: v1alpha1.ReasonDeletionOrphaned, events: []event.Reason{reasonDeletionOrphaned}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			recorder := &eventRecorder{}
+			e := external{recorder: recorder, service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: &fake.MockRepositoryService{
+				MockDelete: func(_ context.Context, _ *bitbucket.Repository) error {
+					return errBoom
+				},
+				MockExists: func(_ context.Context, _ *bitbucket.Repository) (bool, error) {
+					return true, nil
+				},
+			}}}
+
+			got := want{}
+			got.err = e.Delete(context.Background(), tc.mg)
+			got.reason = tc.mg.GetCondition(v1alpha1.TypeDeletionBlocked).Reason
+			for _, ev := range recorder.events {
+				got.events = append(got.events, ev.Reason)
+			}
+
+			now := metav1.Now()
+			tc.mg.SetDeletionTimestamp(&now)
+			o, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			got.exists = o.ResourceExists
+
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nstuck deletion: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestArchived(t *testing.T) {
+	type want struct {
+		upToDate    bool
+		driftReason string
+		condition   corev1.ConditionStatus
+		added       []string
+	}
+	archived, unarchived := true, false
+
+	withArchived := func(a *bool) repositoryModifier {
+		return func(r *v1alpha1.Repository) { r.Spec.ForProvider.Archived = a }
+	}
+
+	cases := map[string]struct {
+		reason   string
+		mg       *v1alpha1.Repository
+		archived bool
+		want     want
+	}{
+		"ArchivedUnmanaged": {
+			reason:   "An archived repository should be reported up to date and not updated when the archive state is not managed",
+			mg:       repository(withGroups(v1alpha1.AdGroup{Name: "new", Permission: "REPO_READ"})),
+			archived: true,
+			want:     want{upToDate: true, condition: corev1.ConditionTrue},
+		},
+		"ArchivedWanted": {
+			reason:   "An archived repository should be reported up to date when the spec archives it",
+			mg:       repository(withArchived(&archived), withGroups(v1alpha1.AdGroup{Name: "new", Permission: "REPO_READ"})),
+			archived: true,
+			want:     want{upToDate: true, condition: corev1.ConditionTrue},
+		},
+		"Unarchive": {
+			reason:   "An archived repository should be unarchived and updated when the spec asks for it",
+			mg:       repository(withArchived(&unarchived), withGroups(v1alpha1.AdGroup{Name: "new", Permission: "REPO_READ"})),
+			archived: true,
+			want:     want{driftReason: "archived, groups", condition: corev1.ConditionUnknown, added: []string{"new"}},
+		},
+		"Archive": {
+			reason: "A repository should be archived without applying the rest of the spec",
+			mg:     repository(withArchived(&archived), withGroups(v1alpha1.AdGroup{Name: "new", Permission: "REPO_READ"})),
+			want:   want{driftReason: "archived, groups", condition: corev1.ConditionUnknown},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			calls := &groupCalls{}
+			svc := newGroupService(nil, calls)
+			svc.MockGet = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project, Archived: &tc.archived}, nil
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+
+			got := want{}
+			o, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			got.upToDate = o.ResourceUpToDate
+			got.driftReason = tc.mg.Status.AtProvider.DriftReason
+			got.condition = tc.mg.GetCondition(v1alpha1.TypeArchived).Status
+
+			if !o.ResourceUpToDate {
+				if _, err := e.Update(context.Background(), tc.mg); err != nil {
+					t.Fatalf("e.Update(...): %v", err)
+				}
+				got.added = calls.added
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...), e.Update(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectPending(t *testing.T) {
+	type want struct {
+		connected   bool
+		pending     corev1.ConditionStatus
+		reason      xpv1.ConditionReason
+		errorReason xpv1.ConditionReason
+	}
+	errAuth := errors.Wrap(bitbucket.ErrPermission, "cannot list projects")
+
+	pc := &apisv1alpha1.ProviderConfig{}
+	pc.Spec.BaseURL = "https://bitbucket.example.com"
+	pc.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+	pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "creds"},
+		Key:             "credentials",
+	}
+
+	// kube serves the ProviderConfig and credentials secret that exist
+	kube := func(pcExists, secretExists bool) client.Client {
+		return &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				if !pcExists {
+					return kerrors.NewNotFound(schema.GroupResource{Resource: "providerconfigs"}, key.Name)
+				}
+				pc.DeepCopyInto(o)
+			case *corev1.Secret:
+				if !secretExists {
+					return kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+				}
+				o.Data = map[string][]byte{"credentials": []byte("user:pass")}
+			}
+			return nil
+		}}
+	}
+
+	cases := map[string]struct {
+		reason     string
+		kube       client.Client
+		serviceErr error
+		conditions []xpv1.Condition
+		want       want
+	}{
+		"MissingProviderConfig": {
+			reason: "A missing ProviderConfig should mark the repository as pending",
+			kube:   kube(false, false),
+			want:   want{pending: corev1.ConditionTrue, reason: v1alpha1.ReasonProviderConfigMissing},
+		},
+		"MissingSecret": {
+			reason: "A missing credentials secret should mark the repository as pending",
+			kube:   kube(true, false),
+			want:   want{pending: corev1.ConditionTrue, reason: v1alpha1.ReasonCredentialsMissing},
+		},
+		"AuthFailure": {
+			reason:     "Credentials rejected by bitbucket should fail with an AuthenticationFailed condition without marking the repository as pending",
+			kube:       kube(true, true),
+			serviceErr: errAuth,
+			want:       want{pending: corev1.ConditionUnknown, errorReason: v1alpha1.ReasonAuthenticationFailed},
+		},
+		"Connected": {
+			reason: "A repository that was pending should no longer be once its credentials exist",
+			kube:   kube(true, true),
+			conditions: []xpv1.Condition{
+				v1alpha1.CredentialsPending(),
+			},
+			want: want{connected: true, pending: corev1.ConditionFalse, reason: v1alpha1.ReasonConnected},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &connector{
+				kube:  tc.kube,
+				usage: resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				newServiceFn: func(_ string, _ []byte, _ *string, _ ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+					if tc.serviceErr != nil {
+						return nil, tc.serviceErr
+					}
+					return &bitbucket.BitBucketService{}, nil
+				},
+			}
+			cr := repository(func(r *v1alpha1.Repository) {
+				r.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+				r.SetConditions(tc.conditions...)
+			})
+
+			_, err := c.Connect(context.Background(), cr)
+			if tc.serviceErr != nil && !errors.Is(err, bitbucket.ErrPermission) {
+				t.Errorf("\n%s\nc.Connect(...): want auth error, got %v", tc.reason, err)
+			}
+			pending := cr.GetCondition(v1alpha1.TypePending)
+			got := want{connected: err == nil, pending: pending.Status, reason: pending.Reason, errorReason: cr.GetCondition(v1alpha1.TypeBitbucketError).Reason}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAnonymousAccess(t *testing.T) {
+	type want struct {
+		upToDate  bool
+		drift     string
+		anonymous bool
+		events    []event.Event
+		added     []string
+	}
+	publicProject := &fake.MockProjectService{
+		MockGet: func(_ context.Context, req *bitbucket.GetProjectRequest) (*bitbucket.Project, error) {
+			return &bitbucket.Project{Key: req.Key, Public: true}, nil
+		},
+	}
+	warning := event.Event{
+		Type:    event.TypeWarning,
+		Reason:  reasonAnonymousAccess,
+		Message: "repository PRJ/repo is readable anonymously because its project is public, make the project private or set public to true",
+	}
+
+	cases := map[string]struct {
+		reason   string
+		public   bool
+		reported bool
+		groups   []v1alpha1.AdGroup
+		projects bitbucket.ProjectService
+		want     want
+	}{
+		"Private": {
+			reason:   "A private repository in a private project should not be readable anonymously",
+			projects: privateProject(),
+			want:     want{upToDate: true},
+		},
+		"PublicProject": {
+			reason:   "A private repository in a public project should be reported with a warning but not as drift",
+			projects: publicProject,
+			want:     want{upToDate: true, anonymous: true, events: []event.Event{warning}},
+		},
+		"PublicProjectReported": {
+			reason:   "Anonymous access already reported should not be reported again",
+			reported: true,
+			projects: publicProject,
+			want:     want{upToDate: true, anonymous: true},
+		},
+		"PublicProjectGroupChanged": {
+			reason:   "A private repository in a public project should still get its groups applied",
+			reported: true,
+			groups:   []v1alpha1.AdGroup{{Name: "dev", Permission: "REPO_WRITE"}},
+			projects: publicProject,
+			want:     want{drift: "groups", anonymous: true, added: []string{"dev"}},
+		},
+		"ProjectError": {
+			reason:   "An error getting the project should keep the previously reported access without failing the observation",
+			reported: true,
+			projects: &fake.MockProjectService{
+				MockGet: func(_ context.Context, _ *bitbucket.GetProjectRequest) (*bitbucket.Project, error) {
+					return nil, bitbucket.ErrPermission
+				},
+			},
+			want: want{upToDate: true, anonymous: true},
+		},
+		"PublicRepository": {
+			reason:   "A public repository should be readable anonymously whatever its project",
+			public:   true,
+			projects: publicProject,
+			want:     want{upToDate: true, anonymous: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			calls := &groupCalls{}
+			svc := newGroupService(nil, calls)
+			svc.MockGet = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project, Public: tc.public, State: bitbucket.StateAvailable}, nil
+			}
+			recorder := &eventRecorder{}
+			e := external{service: &bitbucket.BitBucketService{Projects: tc.projects, Repositories: svc}, recorder: recorder}
+			cr := repository(withGroups(tc.groups...), func(r *v1alpha1.Repository) {
+				r.Spec.ForProvider.Public = tc.public
+				r.Status.AtProvider.AnonymousAccess = tc.reported
+			})
+
+			o, err := e.Observe(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			got := want{upToDate: o.ResourceUpToDate, drift: cr.Status.AtProvider.DriftReason, anonymous: cr.Status.AtProvider.AnonymousAccess, events: recorder.events}
+			if !o.ResourceUpToDate {
+				if _, err := e.Update(context.Background(), cr); err != nil {
+					t.Fatalf("\n%s\ne.Update(...): %v", tc.reason, err)
+				}
+			}
+			got.added = calls.added
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nanonymous access: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectVerifyAccess(t *testing.T) {
+	type want struct {
+		err      error
+		verified []string
+	}
+	errForbidden := errors.Wrap(bitbucket.ErrPermission, "the credentials cannot administer repositories of project PRJ")
+
+	cases := map[string]struct {
+		reason    string
+		verify    *bool
+		accessErr error
+		want      want
+	}{
+		"Disabled": {
+			reason: "Access should not be verified unless enabled in the ProviderConfig",
+			want:   want{},
+		},
+		"Sufficient": {
+			reason: "Credentials that can administer the project should connect",
+			verify: boolPtr(true),
+			want:   want{verified: []string{"PRJ"}},
+		},
+		"Insufficient": {
+			reason:    "Credentials that cannot administer the project should fail to connect",
+			verify:    boolPtr(true),
+			accessErr: errForbidden,
+			want:      want{err: errors.Wrap(errForbidden, errVerifyAccess), verified: []string{"PRJ"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			kube := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+				switch o := obj.(type) {
+				case *apisv1alpha1.ProviderConfig:
+					o.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+					o.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "credentials"}
+					o.Spec.VerifyAccess = tc.verify
+				case *corev1.Secret:
+					o.Data = map[string][]byte{"credentials": []byte("user:pass")}
+				}
+				return nil
+			}}
+			c := &connector{
+				kube:  kube,
+				usage: resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				newServiceFn: func(_ string, _ []byte, _ *string, _ ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+					return &bitbucket.BitBucketService{Server: &fake.MockServerService{
+						MockVerifyAccess: func(_ context.Context, projectKey string) error {
+							got.verified = append(got.verified, projectKey)
+							return tc.accessErr
+						},
+					}}, nil
+				},
+			}
+			cr := repository(func(r *v1alpha1.Repository) {
+				r.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			})
+
+			_, got.err = c.Connect(context.Background(), cr)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectDefaultProject(t *testing.T) {
+	type want struct {
+		err       error
+		project   string
+		defaulted bool
+	}
+
+	cases := map[string]struct {
+		reason         string
+		project        string
+		defaultProject *string
+		want           want
+	}{
+		"DefaultApplied": {
+			reason:         "A repository without a project should use the default-project of the ProviderConfig",
+			defaultProject: strPtr("DEF"),
+			want:           want{project: "DEF", defaulted: true},
+		},
+		"ExplicitOverride": {
+			reason:         "The project of a repository should override the default-project of the ProviderConfig",
+			project:        "PRJ",
+			defaultProject: strPtr("DEF"),
+			want:           want{project: "PRJ"},
+		},
+		"ExplicitWithoutDefault": {
+			reason:  "The project of a repository should be used when the ProviderConfig has no default-project",
+			project: "PRJ",
+			want:    want{project: "PRJ"},
+		},
+		"NoProject": {
+			reason: "Connecting should fail when neither the repository nor the ProviderConfig provide a project",
+			want:   want{err: errors.Errorf(errNoProject, "default")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+				switch o := obj.(type) {
+				case *apisv1alpha1.ProviderConfig:
+					o.SetName("default")
+					o.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+					o.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "credentials"}
+					o.Spec.DefaultProject = tc.defaultProject
+				case *corev1.Secret:
+					o.Data = map[string][]byte{"credentials": []byte("user:pass")}
+				}
+				return nil
+			}}
+			c := &connector{
+				kube:  kube,
+				usage: resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				newServiceFn: func(_ string, _ []byte, _ *string, _ ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+					return &bitbucket.BitBucketService{}, nil
+				},
+			}
+			cr := repository(func(r *v1alpha1.Repository) {
+				r.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+				r.Spec.ForProvider.Project = tc.project
+			})
+
+			got := want{}
+			var ext managed.ExternalClient
+			ext, got.err = c.Connect(context.Background(), cr)
+			if ce, ok := ext.(*conditionedExternal); ok {
+				got.defaulted = ce.ExternalClient.(*external).projectDefaulted
+			}
+			if got.err == nil {
+				got.project = cr.Spec.ForProvider.Project
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectVerifyScopes(t *testing.T) {
+	type want struct {
+		err      error
+		verified []string
+	}
+	errScopes := errors.Wrap(bitbucket.ErrPermission, "the credentials or the scopes of their access token grant neither REPO_ADMIN nor PROJECT_ADMIN")
+
+	cases := map[string]struct {
+		reason    string
+		verify    *bool
+		scopesErr error
+		want      want
+	}{
+		"Disabled": {
+			reason: "Scopes should not be verified unless enabled in the ProviderConfig",
+			want:   want{},
+		},
+		"Sufficient": {
+			reason: "Credentials with sufficient scopes should connect",
+			verify: boolPtr(true),
+			want:   want{verified: []string{"PRJ/repo"}},
+		},
+		"Insufficient": {
+			reason:    "Credentials with too narrow scopes should fail to connect",
+			verify:    boolPtr(true),
+			scopesErr: errScopes,
+			want:      want{err: errors.Wrap(errScopes, errVerifyScopes), verified: []string{"PRJ/repo"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			kube := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+				switch o := obj.(type) {
+				case *apisv1alpha1.ProviderConfig:
+					o.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+					o.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "credentials"}
+					o.Spec.VerifyTokenScopes = tc.verify
+				case *corev1.Secret:
+					o.Data = map[string][]byte{"credentials": []byte("token")}
+				}
+				return nil
+			}}
+			c := &connector{
+				kube:  kube,
+				usage: resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				newServiceFn: func(_ string, _ []byte, _ *string, _ ...bitbucket.ClientOption) (*bitbucket.BitBucketService, error) {
+					return &bitbucket.BitBucketService{Server: &fake.MockServerService{
+						MockVerifyScopes: func(_ context.Context, projectKey string, repository string) error {
+							got.verified = append(got.verified, projectKey+"/"+repository)
+							return tc.scopesErr
+						},
+					}}, nil
+				},
+			}
+			cr := repository(func(r *v1alpha1.Repository) {
+				r.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			})
+
+			_, got.err = c.Connect(context.Background(), cr)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPreferredCloneURL(t *testing.T) {
+	httpURL := "https://bitbucket.example.com/scm/prj/repo.git"
+	sshURL := "ssh://git@bitbucket.example.com:7999/prj/repo.git"
+	both := map[string]string{bitbucket.CloneHTTP: httpURL, bitbucket.CloneSSH: sshURL}
+
+	cases := map[string]struct {
+		reason    string
+		protocol  string
+		cloneURLs map[string]string
+		want      managed.ConnectionDetails
+	}{
+		"DefaultHTTP": {
+			reason:    "Both clone URLs should be published with http preferred by default",
+			cloneURLs: both,
+			want: managed.ConnectionDetails{
+				keyProjectKey: []byte("PRJ"), keyRepositorySlug: []byte("repo"),
+				keyHTTPCloneURL: []byte(httpURL), keySSHCloneURL: []byte(sshURL), keyPreferredCloneURL: []byte(httpURL),
+			},
+		},
+		"PreferSSH": {
+			reason:    "The ssh clone URL should be preferred when configured",
+			protocol:  bitbucket.CloneSSH,
+			cloneURLs: both,
+			want: managed.ConnectionDetails{
+				keyProjectKey: []byte("PRJ"), keyRepositorySlug: []byte("repo"),
+				keyHTTPCloneURL: []byte(httpURL), keySSHCloneURL: []byte(sshURL), keyPreferredCloneURL: []byte(sshURL),
+			},
+		},
+		"PreferredUnavailable": {
+			reason:    "The other clone URL should be preferred when the preferred protocol is not available",
+			protocol:  bitbucket.CloneSSH,
+			cloneURLs: map[string]string{bitbucket.CloneHTTP: httpURL},
+			want: managed.ConnectionDetails{
+				keyProjectKey: []byte("PRJ"), keyRepositorySlug: []byte("repo"),
+				keyHTTPCloneURL: []byte(httpURL), keyPreferredCloneURL: []byte(httpURL),
+			},
+		},
+		"NoCloneURLs": {
+			reason: "No clone URLs should be published when the repository has none",
+			want:   managed.ConnectionDetails{keyProjectKey: []byte("PRJ"), keyRepositorySlug: []byte("repo")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{cloneProtocol: tc.protocol}
+			got := e.connectionDetails(&bitbucket.Repository{Project: "PRJ", Slug: "repo", CloneURLs: tc.cloneURLs})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ne.connectionDetails(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveRequiredApprovals(t *testing.T) {
+	type want struct {
+		required int
+		upToDate bool
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		required int
+		err      error
+		want     want
+	}{
+		"Captured": {
+			reason:   "The required approvals of the default reviewers should be reported in status",
+			required: 2,
+			want:     want{required: 2, upToDate: true},
+		},
+		"Error": {
+			reason: "An error getting the default reviewers should keep the previous required approvals without failing the observation",
+			err:    bitbucket.ErrPermission,
+			want:   want{required: 1, upToDate: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockGetRequiredApprovals = func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+				return tc.required, tc.err
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Status.AtProvider.RequiredApprovals = 1 })
+
+			o, err := e.Observe(context.Background(), cr)
+			got := want{required: cr.Status.AtProvider.RequiredApprovals, upToDate: o.ResourceUpToDate, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveOpenPullRequests(t *testing.T) {
+	type want struct {
+		open     int
+		upToDate bool
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		previous int
+		open     int
+		err      error
+		want     want
+	}{
+		"Counted": {
+			reason:   "The open pull requests should be reported in status",
+			previous: 1,
+			open:     3,
+			want:     want{open: 3, upToDate: true},
+		},
+		"Error": {
+			reason:   "An error counting the pull requests should keep the previous count without failing the observation",
+			previous: 1,
+			err:      bitbucket.ErrTooManyPages,
+			want:     want{open: 1, upToDate: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockCountOpenPullRequests = func(_ context.Context, _ *bitbucket.Repository) (int, error) {
+				return tc.open, tc.err
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Status.AtProvider.OpenPullRequests = tc.previous })
+
+			o, err := e.Observe(context.Background(), cr)
+			got := want{open: cr.Status.AtProvider.OpenPullRequests, upToDate: o.ResourceUpToDate, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveUsers(t *testing.T) {
+	type want struct {
+		users    []v1alpha1.UserPermission
+		upToDate bool
+		err      error
+	}
+	previous := []v1alpha1.UserPermission{{User: "alice", Permission: "REPO_READ"}}
+
+	cases := map[string]struct {
+		reason string
+		users  []bitbucket.UserPermission
+		err    error
+		want   want
+	}{
+		"Listed": {
+			reason: "The user permissions should be reported in status",
+			users:  []bitbucket.UserPermission{{User: "bob", Permission: "REPO_WRITE"}},
+			want:   want{users: []v1alpha1.UserPermission{{User: "bob", Permission: "REPO_WRITE"}}, upToDate: true},
+		},
+		"Error": {
+			reason: "An error listing the user permissions should keep the previous permissions without failing the observation",
+			err:    bitbucket.ErrResponseMalformed,
+			want:   want{users: previous, upToDate: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockGetUsers = func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.UserPermission, error) {
+				return tc.users, tc.err
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Status.AtProvider.Users = previous })
+
+			o, err := e.Observe(context.Background(), cr)
+			got := want{users: cr.Status.AtProvider.Users, upToDate: o.ResourceUpToDate, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveCreatedAt(t *testing.T) {
+	created := time.Date(2023, 4, 1, 12, 30, 0, 0, time.UTC)
+	createdAt := metav1.NewTime(created)
+
+	cases := map[string]struct {
+		reason  string
+		created *time.Time
+		want    *metav1.Time
+	}{
+		"Present": {
+			reason:  "The creation date reported by bitbucket should be captured in status",
+			created: &created,
+			want:    &createdAt,
+		},
+		"Absent": {
+			reason: "The creation date should be left empty when bitbucket does not report it",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockGet = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project, CreatedDate: tc.created}, nil
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository()
+
+			if _, err := e.Observe(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, cr.Status.AtProvider.CreatedAt); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestProjectMismatch(t *testing.T) {
+	type want struct {
+		exists   bool
+		upToDate bool
+		drift    string
+		looked   []string
+		moved    string
+		project  string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason    string
+		observed  string
+		existing  string
+		collision bool
+		want      want
+	}{
+		"Unchanged": {
+			reason:   "A repository in the project of the spec should be up to date",
+			observed: "PRJ",
+			existing: "PRJ",
+			want:     want{exists: true, upToDate: true, looked: []string{"PRJ"}, project: "PRJ"},
+		},
+		"ProjectChanged": {
+			reason:   "A repository still in the project it was observed in should be moved rather than created",
+			observed: "OLD",
+			existing: "OLD",
+			want:     want{exists: true, drift: "project", looked: []string{"PRJ", "OLD", "PRJ", "OLD"}, moved: "PRJ", project: "PRJ"},
+		},
+		"CaseOnly": {
+			reason:   "Project keys differing only in case should not be taken as a change of project",
+			observed: "prj",
+			existing: "PRJ",
+			want:     want{exists: true, upToDate: true, looked: []string{"PRJ"}, project: "PRJ"},
+		},
+		"Gone": {
+			reason:   "A repository in neither project should be created",
+			observed: "OLD",
+			want:     want{looked: []string{"PRJ", "OLD"}, project: "OLD"},
+		},
+		"Collision": {
+			reason:    "A repository with the same slug in the project of the spec should be a conflict while the repository is still in the project it was observed in",
+			observed:  "OLD",
+			existing:  "OLD",
+			collision: true,
+			want: want{
+				looked:  []string{"PRJ"},
+				project: "OLD",
+				err:     errors.Wrap(errors.Wrapf(bitbucket.ErrConflict, errSlugCollision, "repo", "PRJ", "OLD"), "error fetching Bitbucket repository"),
+			},
+		},
+		"NeverObserved": {
+			reason: "A repository that was never observed should only be looked up in the project of the spec",
+			want:   want{looked: []string{"PRJ"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			existing := tc.existing
+			svc := newGroupService(nil, &groupCalls{})
+			svc.MockGet = func(_ context.Context, r *bitbucket.Repository) (*bitbucket.Repository, error) {
+				got.looked = append(got.looked, r.Project)
+				if tc.collision && r.Project == "PRJ" {
+					return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: r.Project}, nil
+				}
+				if existing == "" || !strings.EqualFold(r.Project, existing) {
+					return nil, bitbucket.ErrNotFound
+				}
+				return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: existing}, nil
+			}
+			svc.MockExists = func(_ context.Context, r *bitbucket.Repository) (bool, error) {
+				return strings.EqualFold(r.Project, existing), nil
+			}
+			svc.MockMove = func(_ context.Context, r *bitbucket.Repository, project string) (*bitbucket.Repository, error) {
+				got.moved = project
+				existing = project
+				return &bitbucket.Repository{Name: r.Name, Slug: r.Name, Project: project}, nil
+			}
+			e := external{service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Status.AtProvider.Project = tc.observed })
+
+			o, err := e.Observe(context.Background(), cr)
+			got.err = err
+			got.exists, got.upToDate, got.drift = o.ResourceExists, o.ResourceUpToDate, cr.Status.AtProvider.DriftReason
+			if err == nil && o.ResourceExists && !o.ResourceUpToDate {
+				if _, err := e.Update(context.Background(), cr); err != nil {
+					t.Fatalf("\n%s\ne.Update(...): %v", tc.reason, err)
+				}
+			}
+			got.project = cr.Status.AtProvider.Project
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nproject mismatch: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
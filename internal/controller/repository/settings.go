@@ -0,0 +1,594 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+const (
+	errGetMirrors             = "cannot get repository mirror servers"
+	errGetPullRequestTemplate = "cannot get repository pull request template"
+	errGetDefaultBranch       = "cannot get repository default branch"
+	errGetRestrictions        = "cannot get repository branch restrictions"
+	errGetLFS                 = "cannot get repository git lfs state"
+	errGetHooks               = "cannot get repository hooks"
+	errGetMergeStrategy       = "cannot get repository default merge strategy"
+	errGetBranches            = "cannot get repository branches"
+	errGetRequiredBuilds      = "cannot get repository required builds"
+	errGetForkSync            = "cannot get repository fork sync"
+	errNotFork                = "forkSync is only applicable to forks, repository %s is not a fork"
+
+	// settingDefaultBranchProtection is applied first when creating a
+	// repository so it is unprotected as briefly as possible
+	settingDefaultBranchProtection = "default branch protection"
+)
+
+// A setting is a part of the repository configuration that is reconciled
+// through its own endpoint rather than the repository endpoint. A setting
+// only touches bitbucket when it is configured in the spec.
+type setting struct {
+	name     string
+	upToDate func(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error)
+	update   func(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error
+}
+
+// settings returns the settings reconciled after the repository itself
+func (c *external) settings() []setting {
+	return []setting{
+		{name: "mirroring", upToDate: c.mirroringUpToDate, update: c.updateMirroring},
+		{name: "pull request template", upToDate: c.pullRequestTemplateUpToDate, update: c.updatePullRequestTemplate},
+		{name: "default merge strategy", upToDate: c.defaultMergeStrategyUpToDate, update: c.updateDefaultMergeStrategy},
+		{name: "branches", upToDate: c.branchesUpToDate, update: c.updateBranches},
+		{name: "default branch", upToDate: c.defaultBranchUpToDate, update: c.updateDefaultBranch},
+		{name: settingDefaultBranchProtection, upToDate: c.defaultBranchProtectionUpToDate, update: c.updateDefaultBranchProtection},
+		{name: "hooks", upToDate: c.hooksUpToDate, update: c.updateHooks},
+		{name: "required builds", upToDate: c.requiredBuildsUpToDate, update: c.updateRequiredBuilds},
+		{name: "lfs", upToDate: c.lfsUpToDate, update: c.updateLFS},
+		{name: "fork sync", upToDate: c.forkSyncUpToDate, update: c.updateForkSync},
+	}
+}
+
+// mirroringUpToDate reports whether the repository is mirrored to the mirror
+// servers in the spec. Mirroring is only checked when configured in the spec so
+// servers without mirroring are left alone.
+func (c *external) mirroringUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.Mirroring == nil {
+		return true, nil
+	}
+	mirrors, err := c.service.Repositories.GetMirrorServers(ctx, repository)
+	if err != nil {
+		return false, errors.Wrap(err, errGetMirrors)
+	}
+	add, remove := diffStrings(cr.Spec.ForProvider.Mirroring.MirrorServers, mirrors)
+	return len(add) == 0 && len(remove) == 0, nil
+}
+
+// updateMirroring adds and removes mirror servers so the repository is
+// mirrored to exactly the mirror servers in the spec
+func (c *external) updateMirroring(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.Mirroring == nil {
+		return nil
+	}
+	mirrors, err := c.service.Repositories.GetMirrorServers(ctx, repository)
+	if err != nil {
+		return errors.Wrap(err, errGetMirrors)
+	}
+	add, remove := diffStrings(cr.Spec.ForProvider.Mirroring.MirrorServers, mirrors)
+	for _, id := range add {
+		log.Printf("Adding mirror server %s for repository %+v\n", id, repository)
+		if err := c.service.Repositories.AddMirrorServer(ctx, repository, id); err != nil {
+			return err
+		}
+	}
+	for _, id := range remove {
+		log.Printf("Removing mirror server %s for repository %+v\n", id, repository)
+		if err := c.service.Repositories.RemoveMirrorServer(ctx, repository, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffStrings returns the entries of want missing in have and the entries of
+// have not in want
+func diffStrings(want []string, have []string) (missing []string, extra []string) {
+	wantSet := make(map[string]bool, len(want))
+	for _, w := range want {
+		wantSet[w] = true
+	}
+	haveSet := make(map[string]bool, len(have))
+	for _, h := range have {
+		if haveSet[h] {
+			continue
+		}
+		haveSet[h] = true
+		if !wantSet[h] {
+			extra = append(extra, h)
+		}
+	}
+	for _, w := range want {
+		if !haveSet[w] {
+			missing = append(missing, w)
+			haveSet[w] = true
+		}
+	}
+	return missing, extra
+}
+
+// pullRequestTemplateUpToDate reports whether the default pull request
+// description of the repository matches the spec
+func (c *external) pullRequestTemplateUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.PullRequestTemplate == nil {
+		return true, nil
+	}
+	template, err := c.service.Repositories.GetPullRequestTemplate(ctx, repository)
+	if err != nil {
+		return false, errors.Wrap(err, errGetPullRequestTemplate)
+	}
+	return template == *cr.Spec.ForProvider.PullRequestTemplate, nil
+}
+
+// updatePullRequestTemplate sets the default pull request description of the repository
+func (c *external) updatePullRequestTemplate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.PullRequestTemplate == nil {
+		return nil
+	}
+	upToDate, err := c.pullRequestTemplateUpToDate(ctx, cr, repository)
+	if err != nil || upToDate {
+		return err
+	}
+	log.Printf("Setting pull request template for repository %+v\n", repository)
+	return c.service.Repositories.SetPullRequestTemplate(ctx, repository, *cr.Spec.ForProvider.PullRequestTemplate)
+}
+
+// defaultMergeStrategyUpToDate reports whether the default merge strategy of
+// pull requests matches the spec
+func (c *external) defaultMergeStrategyUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.DefaultMergeStrategy == nil {
+		return true, nil
+	}
+	strategy, err := c.service.Repositories.GetDefaultMergeStrategy(ctx, repository)
+	if err != nil {
+		return false, errors.Wrap(err, errGetMergeStrategy)
+	}
+	return strategy == *cr.Spec.ForProvider.DefaultMergeStrategy, nil
+}
+
+// updateDefaultMergeStrategy sets the default merge strategy of pull requests
+func (c *external) updateDefaultMergeStrategy(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.DefaultMergeStrategy == nil {
+		return nil
+	}
+	upToDate, err := c.defaultMergeStrategyUpToDate(ctx, cr, repository)
+	if err != nil || upToDate {
+		return err
+	}
+	log.Printf("Setting default merge strategy %s for repository %+v\n", *cr.Spec.ForProvider.DefaultMergeStrategy, repository)
+	return c.service.Repositories.SetDefaultMergeStrategy(ctx, repository, *cr.Spec.ForProvider.DefaultMergeStrategy)
+}
+
+// diffBranches returns the branches of the spec the repository lacks and, when
+// pruning, the branches of the repository not in the spec. The default branch
+// is never pruned.
+func (c *external) diffBranches(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) ([]v1alpha1.BranchRef, []bitbucket.Branch, error) {
+	branches, err := c.service.Repositories.GetBranches(ctx, repository)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errGetBranches)
+	}
+	existing := map[string]bool{}
+	for _, b := range branches {
+		existing[b.Name] = true
+	}
+	wanted := map[string]bool{}
+	missing := []v1alpha1.BranchRef{}
+	for _, b := range cr.Spec.ForProvider.Branches {
+		wanted[b.Name] = true
+		if !existing[b.Name] {
+			missing = append(missing, b)
+		}
+	}
+
+	unknown := []bitbucket.Branch{}
+	if p := cr.Spec.ForProvider.PruneUnknownBranches; p != nil && *p {
+		for _, b := range branches {
+			if !b.IsDefault && !wanted[b.Name] {
+				unknown = append(unknown, b)
+			}
+		}
+	}
+	return missing, unknown, nil
+}
+
+// branchesUpToDate reports whether the repository has the branches in the spec
+// and, when pruning, no others
+func (c *external) branchesUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.Branches == nil {
+		return true, nil
+	}
+	missing, unknown, err := c.diffBranches(ctx, cr, repository)
+	return len(missing) == 0 && len(unknown) == 0, err
+}
+
+// updateBranches creates the missing branches and, when pruning, deletes the
+// branches not in the spec
+func (c *external) updateBranches(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.Branches == nil {
+		return nil
+	}
+	missing, unknown, err := c.diffBranches(ctx, cr, repository)
+	if err != nil {
+		return err
+	}
+	for _, b := range missing {
+		log.Printf("Creating branch %s from %s for repository %+v\n", b.Name, b.StartPoint, repository)
+		if err := c.service.Repositories.CreateBranch(ctx, repository, b.Name, b.StartPoint); err != nil {
+			return err
+		}
+	}
+	for _, b := range unknown {
+		log.Printf("Deleting branch %s for repository %+v\n", b.Name, repository)
+		if err := c.service.Repositories.DeleteBranch(ctx, repository, b.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// branchRef returns the ref of the branch, e.g. refs/heads/main for main
+func branchRef(branch string) string {
+	if strings.HasPrefix(branch, "refs/") {
+		return branch
+	}
+	return "refs/heads/" + branch
+}
+
+// defaultBranchUpToDate reports whether the default branch of the repository
+// is the one in the spec. A repository without branches has no default branch.
+func (c *external) defaultBranchUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.DefaultBranch == nil {
+		return true, nil
+	}
+	branch, err := c.service.Repositories.GetDefaultBranch(ctx, repository)
+	if err != nil && !errors.Is(err, bitbucket.ErrNotFound) {
+		return false, errors.Wrap(err, errGetDefaultBranch)
+	}
+	return branch == branchRef(*cr.Spec.ForProvider.DefaultBranch), nil
+}
+
+// updateDefaultBranch switches the default branch to the one in the spec if
+// that branch exists. Switching only changes which branch is the default, so
+// no commits are lost. While the branch does not exist the DefaultBranchPending
+// condition is set instead of failing, the next poll tries again.
+func (c *external) updateDefaultBranch(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.DefaultBranch == nil {
+		return nil
+	}
+	upToDate, err := c.defaultBranchUpToDate(ctx, cr, repository)
+	if err != nil {
+		return err
+	}
+	if !upToDate {
+		ref := branchRef(*cr.Spec.ForProvider.DefaultBranch)
+		branches, err := c.service.Repositories.GetBranches(ctx, repository)
+		if err != nil {
+			return errors.Wrap(err, errGetBranches)
+		}
+		exists := false
+		for _, b := range branches {
+			exists = exists || b.ID == ref
+		}
+		if !exists {
+			log.Printf("Default branch %s of repository %+v does not exist yet\n", ref, repository)
+			cr.SetConditions(v1alpha1.DefaultBranchPending(*cr.Spec.ForProvider.DefaultBranch))
+			return nil
+		}
+		log.Printf("Setting default branch %s for repository %+v\n", ref, repository)
+		if err := c.service.Repositories.SetDefaultBranch(ctx, repository, ref); err != nil {
+			return err
+		}
+	}
+	if cr.GetCondition(v1alpha1.TypeDefaultBranchPending).Status == corev1.ConditionTrue {
+		cr.SetConditions(v1alpha1.DefaultBranchSet())
+	}
+	return nil
+}
+
+// defaultBranchRestrictions returns the restrictions protecting a branch: no
+// force-pushes, no deletion and changes only through pull requests
+func defaultBranchRestrictions(branch string) []bitbucket.Restriction {
+	restrictions := []bitbucket.Restriction{}
+	for _, t := range []string{bitbucket.RestrictionFastForwardOnly, bitbucket.RestrictionNoDeletes, bitbucket.RestrictionPullRequestOnly} {
+		restrictions = append(restrictions, bitbucket.Restriction{Type: t, MatcherType: bitbucket.MatcherBranch, MatcherID: branch})
+	}
+	return restrictions
+}
+
+// pendingDefaultBranchRestrictions returns the restrictions protecting the
+// default branch the repository lacks and the existing ones whose exempted
+// groups differ from the spec, carrying the ID of the restriction they
+// replace. A repository without branches has nothing to protect yet.
+func (c *external) pendingDefaultBranchRestrictions(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) ([]bitbucket.Restriction, error) {
+	if cr.Spec.ForProvider.ProtectDefaultBranch == nil || !*cr.Spec.ForProvider.ProtectDefaultBranch {
+		return nil, nil
+	}
+	branch, err := c.service.Repositories.GetDefaultBranch(ctx, repository)
+	if errors.Is(err, bitbucket.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errGetDefaultBranch)
+	}
+	existing, err := c.service.Repositories.GetRestrictions(ctx, repository)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetRestrictions)
+	}
+
+	exempt := cr.Spec.ForProvider.DefaultBranchProtectionExemptGroups
+	pending := []bitbucket.Restriction{}
+	for _, want := range defaultBranchRestrictions(branch) {
+		have, ok := findRestriction(existing, want)
+		if !ok {
+			want.Groups = exempt
+			pending = append(pending, want)
+			continue
+		}
+		if exempt != nil && !sameGroupNames(have.Groups, exempt) {
+			have.Groups = exempt
+			pending = append(pending, have)
+		}
+	}
+	return pending, nil
+}
+
+// findRestriction returns the restriction of the same type and matcher as
+// want
+func findRestriction(existing []bitbucket.Restriction, want bitbucket.Restriction) (bitbucket.Restriction, bool) {
+	for _, have := range existing {
+		if have.Type == want.Type && have.MatcherType == want.MatcherType && have.MatcherID == want.MatcherID {
+			return have, true
+		}
+	}
+	return bitbucket.Restriction{}, false
+}
+
+// hasRestriction reports whether a restriction of the same type and matcher
+// as want exists
+func hasRestriction(existing []bitbucket.Restriction, want bitbucket.Restriction) bool {
+	_, ok := findRestriction(existing, want)
+	return ok
+}
+
+// sameGroupNames reports whether a and b name the same groups, ignoring case,
+// order and duplicates
+func sameGroupNames(a []string, b []string) bool {
+	lower := func(names []string) []string {
+		lowered := make([]string, 0, len(names))
+		for _, n := range names {
+			lowered = append(lowered, strings.ToLower(n))
+		}
+		return lowered
+	}
+	missing, extra := diffStrings(lower(a), lower(b))
+	return len(missing) == 0 && len(extra) == 0
+}
+
+// defaultBranchProtectionUpToDate reports whether the default branch has the
+// restrictions protecting it with the exempted groups of the spec
+func (c *external) defaultBranchProtectionUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	pending, err := c.pendingDefaultBranchRestrictions(ctx, cr, repository)
+	return len(pending) == 0, err
+}
+
+// updateDefaultBranchProtection adds the restrictions protecting the default
+// branch it lacks and updates the exempted groups of the existing ones
+func (c *external) updateDefaultBranchProtection(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	pending, err := c.pendingDefaultBranchRestrictions(ctx, cr, repository)
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		if pending[i].ID == 0 {
+			log.Printf("Adding %s restriction on %s for repository %+v\n", pending[i].Type, pending[i].MatcherID, repository)
+		} else {
+			log.Printf("Updating groups exempted from %s restriction on %s for repository %+v\n", pending[i].Type, pending[i].MatcherID, repository)
+		}
+		if err := c.service.Repositories.AddRestriction(ctx, repository, &pending[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hooksUpToDate reports whether exactly the hooks in the spec are enabled
+func (c *external) hooksUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.EnabledHooks == nil {
+		return true, nil
+	}
+	hooks, err := c.service.Repositories.GetEnabledHooks(ctx, repository)
+	if err != nil {
+		return false, errors.Wrap(err, errGetHooks)
+	}
+	enable, disable := diffStrings(cr.Spec.ForProvider.EnabledHooks, hooks)
+	return len(enable) == 0 && len(disable) == 0, nil
+}
+
+// updateHooks enables and disables hooks so exactly the hooks in the spec are enabled
+func (c *external) updateHooks(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.EnabledHooks == nil {
+		return nil
+	}
+	hooks, err := c.service.Repositories.GetEnabledHooks(ctx, repository)
+	if err != nil {
+		return errors.Wrap(err, errGetHooks)
+	}
+	enable, disable := diffStrings(cr.Spec.ForProvider.EnabledHooks, hooks)
+	for _, key := range enable {
+		log.Printf("Enabling hook %s for repository %+v\n", key, repository)
+		if err := c.service.Repositories.EnableHook(ctx, repository, key); err != nil {
+			return err
+		}
+	}
+	for _, key := range disable {
+		log.Printf("Disabling hook %s for repository %+v\n", key, repository)
+		if err := c.service.Repositories.DisableHook(ctx, repository, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lfsUpToDate reports whether git LFS is enabled for the repository as in the spec
+func (c *external) lfsUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.LFSEnabled == nil {
+		return true, nil
+	}
+	enabled, err := c.service.Repositories.GetLFSEnabled(ctx, repository)
+	if err != nil {
+		return false, errors.Wrap(err, errGetLFS)
+	}
+	return enabled == *cr.Spec.ForProvider.LFSEnabled, nil
+}
+
+// updateLFS enables or disables git LFS for the repository
+func (c *external) updateLFS(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.LFSEnabled == nil {
+		return nil
+	}
+	upToDate, err := c.lfsUpToDate(ctx, cr, repository)
+	if err != nil || upToDate {
+		return err
+	}
+	log.Printf("Setting lfs enabled to %t for repository %+v\n", *cr.Spec.ForProvider.LFSEnabled, repository)
+	return c.service.Repositories.SetLFSEnabled(ctx, repository, *cr.Spec.ForProvider.LFSEnabled)
+}
+
+// forkSyncUpToDate reports whether synchronizing the fork with its upstream
+// repository is enabled as in the spec. Configuring it on a repository that
+// is not a fork is an error.
+func (c *external) forkSyncUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.ForkSync == nil {
+		return true, nil
+	}
+	if !repository.IsFork() {
+		return false, errors.Errorf(errNotFork, repository.Name)
+	}
+	enabled, err := c.service.Repositories.GetForkSyncEnabled(ctx, repository)
+	if err != nil {
+		return false, errors.Wrap(err, errGetForkSync)
+	}
+	return enabled == cr.Spec.ForProvider.ForkSync.Enabled, nil
+}
+
+// updateForkSync enables or disables synchronizing the fork with its upstream repository
+func (c *external) updateForkSync(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.ForkSync == nil {
+		return nil
+	}
+	upToDate, err := c.forkSyncUpToDate(ctx, cr, repository)
+	if err != nil || upToDate {
+		return err
+	}
+	log.Printf("Setting fork sync enabled to %t for repository %+v\n", cr.Spec.ForProvider.ForkSync.Enabled, repository)
+	return c.service.Repositories.SetForkSyncEnabled(ctx, repository, cr.Spec.ForProvider.ForkSync.Enabled)
+}
+
+// diffRequiredBuilds returns the required builds of the spec the repository
+// lacks, the existing required builds whose build keys differ from the spec
+// and the required builds of branches not in the spec
+func (c *external) diffRequiredBuilds(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (add, update, remove []bitbucket.RequiredBuild, err error) {
+	existing, err := c.service.Repositories.GetRequiredBuilds(ctx, repository)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, errGetRequiredBuilds)
+	}
+	byBranch := make(map[string]bitbucket.RequiredBuild, len(existing))
+	for _, b := range existing {
+		if b.MatcherType == bitbucket.MatcherBranch {
+			byBranch[b.MatcherID] = b
+		}
+	}
+
+	wanted := make(map[string]bool, len(cr.Spec.ForProvider.RequiredBuilds))
+	for _, w := range cr.Spec.ForProvider.RequiredBuilds {
+		wanted[w.Branch] = true
+		have, ok := byBranch[w.Branch]
+		if !ok {
+			add = append(add, bitbucket.RequiredBuild{BuildKeys: w.BuildKeys, MatcherType: bitbucket.MatcherBranch, MatcherID: w.Branch})
+			continue
+		}
+		missing, extra := diffStrings(w.BuildKeys, have.BuildKeys)
+		if len(missing) > 0 || len(extra) > 0 {
+			have.BuildKeys = w.BuildKeys
+			update = append(update, have)
+		}
+	}
+	for _, b := range existing {
+		if b.MatcherType != bitbucket.MatcherBranch || !wanted[b.MatcherID] {
+			remove = append(remove, b)
+		}
+	}
+	return add, update, remove, nil
+}
+
+// requiredBuildsUpToDate reports whether exactly the required builds in the
+// spec are configured
+func (c *external) requiredBuildsUpToDate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) (bool, error) {
+	if cr.Spec.ForProvider.RequiredBuilds == nil {
+		return true, nil
+	}
+	add, update, remove, err := c.diffRequiredBuilds(ctx, cr, repository)
+	return len(add) == 0 && len(update) == 0 && len(remove) == 0, err
+}
+
+// updateRequiredBuilds adds, updates and removes required builds so exactly
+// the required builds in the spec are configured
+func (c *external) updateRequiredBuilds(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) error {
+	if cr.Spec.ForProvider.RequiredBuilds == nil {
+		return nil
+	}
+	add, update, remove, err := c.diffRequiredBuilds(ctx, cr, repository)
+	if err != nil {
+		return err
+	}
+	for i := range add {
+		log.Printf("Adding required builds %v of %s for repository %+v\n", add[i].BuildKeys, add[i].MatcherID, repository)
+		if err := c.service.Repositories.AddRequiredBuild(ctx, repository, &add[i]); err != nil {
+			return err
+		}
+	}
+	for i := range update {
+		log.Printf("Updating required builds %v of %s for repository %+v\n", update[i].BuildKeys, update[i].MatcherID, repository)
+		if err := c.service.Repositories.UpdateRequiredBuild(ctx, repository, &update[i]); err != nil {
+			return err
+		}
+	}
+	for _, b := range remove {
+		log.Printf("Removing required builds %v of %s for repository %+v\n", b.BuildKeys, b.MatcherID, repository)
+		if err := c.service.Repositories.DeleteRequiredBuild(ctx, repository, b.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,900 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket/fake"
+)
+
+func TestMirroring(t *testing.T) {
+	type want struct {
+		upToDate bool
+		added    []string
+		removed  []string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason    string
+		mirroring *v1alpha1.Mirroring
+		existing  []string
+		getErr    error
+		want      want
+	}{
+		"NotConfigured": {
+			reason:   "Mirroring should not be checked when not in the spec",
+			getErr:   bitbucket.ErrUnsupported,
+			want:     want{upToDate: true},
+			existing: nil,
+		},
+		"UpToDate": {
+			reason:    "The mirror servers in the spec should be up to date",
+			mirroring: &v1alpha1.Mirroring{MirrorServers: []string{"M1"}},
+			existing:  []string{"M1"},
+			want:      want{upToDate: true},
+		},
+		"Enable": {
+			reason:    "Missing mirror servers should be added and unknown ones removed",
+			mirroring: &v1alpha1.Mirroring{MirrorServers: []string{"M1", "M2"}},
+			existing:  []string{"M2", "M3"},
+			want:      want{added: []string{"M1"}, removed: []string{"M3"}},
+		},
+		"Unsupported": {
+			reason:    "Requesting mirroring on a server without mirroring should return an error",
+			mirroring: &v1alpha1.Mirroring{MirrorServers: []string{"M1"}},
+			getErr:    bitbucket.ErrUnsupported,
+			want:      want{err: errors.Wrap(bitbucket.ErrUnsupported, errGetMirrors)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetMirrorServers: func(_ context.Context, _ *bitbucket.Repository) ([]string, error) {
+					return tc.existing, tc.getErr
+				},
+				MockAddMirrorServer: func(_ context.Context, _ *bitbucket.Repository, id string) error {
+					got.added = append(got.added, id)
+					return nil
+				},
+				MockRemoveMirrorServer: func(_ context.Context, _ *bitbucket.Repository, id string) error {
+					got.removed = append(got.removed, id)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.Mirroring = tc.mirroring })
+
+			got.upToDate, got.err = e.mirroringUpToDate(context.Background(), cr, &bitbucket.Repository{ID: 1})
+			if got.err == nil && !got.upToDate {
+				got.err = e.updateMirroring(context.Background(), cr, &bitbucket.Repository{ID: 1})
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nmirroring: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPullRequestTemplate(t *testing.T) {
+	type want struct {
+		upToDate bool
+		set      []string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		template *string
+		existing string
+		getErr   error
+		want     want
+	}{
+		"NotConfigured": {
+			reason: "The template should not be checked when not in the spec",
+			getErr: bitbucket.ErrUnsupported,
+			want:   want{upToDate: true},
+		},
+		"UpToDate": {
+			reason:   "A matching template should be up to date",
+			template: strPtr("## Summary"),
+			existing: "## Summary",
+			want:     want{upToDate: true},
+		},
+		"Drift": {
+			reason:   "A different template should be set",
+			template: strPtr("## Summary"),
+			existing: "old",
+			want:     want{set: []string{"## Summary"}},
+		},
+		"Cleared": {
+			reason:   "An empty template in the spec should clear the template",
+			template: strPtr(""),
+			existing: "old",
+			want:     want{set: []string{""}},
+		},
+		"Unsupported": {
+			reason:   "Requesting a template on a server without templates should return an error",
+			template: strPtr("## Summary"),
+			getErr:   bitbucket.ErrUnsupported,
+			want:     want{err: errors.Wrap(bitbucket.ErrUnsupported, errGetPullRequestTemplate)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetPullRequestTemplate: func(_ context.Context, _ *bitbucket.Repository) (string, error) {
+					return tc.existing, tc.getErr
+				},
+				MockSetPullRequestTemplate: func(_ context.Context, _ *bitbucket.Repository, template string) error {
+					got.set = append(got.set, template)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.PullRequestTemplate = tc.template })
+
+			got.upToDate, got.err = e.pullRequestTemplateUpToDate(context.Background(), cr, &bitbucket.Repository{})
+			if got.err == nil && !got.upToDate {
+				got.err = e.updatePullRequestTemplate(context.Background(), cr, &bitbucket.Repository{})
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\npull request template: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultMergeStrategy(t *testing.T) {
+	type want struct {
+		upToDate bool
+		set      []string
+		err      error
+	}
+	errBoom := errors.New("boom")
+
+	cases := map[string]struct {
+		reason   string
+		strategy *string
+		existing string
+		getErr   error
+		want     want
+	}{
+		"NotConfigured": {
+			reason: "The default merge strategy should not be checked when not in the spec",
+			getErr: errBoom,
+			want:   want{upToDate: true},
+		},
+		"UpToDate": {
+			reason:   "A matching default merge strategy should be up to date",
+			strategy: strPtr("squash"),
+			existing: "squash",
+			want:     want{upToDate: true},
+		},
+		"Drift": {
+			reason:   "A different default merge strategy should be set",
+			strategy: strPtr("squash"),
+			existing: "no-ff",
+			want:     want{set: []string{"squash"}},
+		},
+		"GetError": {
+			reason:   "An error getting the default merge strategy should be returned",
+			strategy: strPtr("squash"),
+			getErr:   errBoom,
+			want:     want{err: errors.Wrap(errBoom, errGetMergeStrategy)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetDefaultMergeStrategy: func(_ context.Context, _ *bitbucket.Repository) (string, error) {
+					return tc.existing, tc.getErr
+				},
+				MockSetDefaultMergeStrategy: func(_ context.Context, _ *bitbucket.Repository, strategy string) error {
+					got.set = append(got.set, strategy)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.DefaultMergeStrategy = tc.strategy })
+
+			got.upToDate, got.err = e.defaultMergeStrategyUpToDate(context.Background(), cr, &bitbucket.Repository{})
+			if got.err == nil && !got.upToDate {
+				got.err = e.updateDefaultMergeStrategy(context.Background(), cr, &bitbucket.Repository{})
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ndefault merge strategy: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestProtectDefaultBranch(t *testing.T) {
+	type want struct {
+		upToDate bool
+		added    []bitbucket.Restriction
+		err      error
+	}
+
+	main := "refs/heads/main"
+	restriction := func(t string) bitbucket.Restriction {
+		return bitbucket.Restriction{Type: t, MatcherType: bitbucket.MatcherBranch, MatcherID: main}
+	}
+	exempted := func(id int, t string, groups ...string) bitbucket.Restriction {
+		r := restriction(t)
+		r.ID = id
+		r.Groups = groups
+		return r
+	}
+
+	cases := map[string]struct {
+		reason    string
+		protect   *bool
+		exempt    []string
+		branch    string
+		branchErr error
+		existing  []bitbucket.Restriction
+		want      want
+	}{
+		"NotConfigured": {
+			reason:    "The default branch should not be checked when not in the spec",
+			branchErr: errors.New("unexpected call"),
+			want:      want{upToDate: true},
+		},
+		"Disabled": {
+			reason:    "The default branch should not be checked when protection is off",
+			protect:   boolPtr(false),
+			branchErr: errors.New("unexpected call"),
+			want:      want{upToDate: true},
+		},
+		"Preset": {
+			reason:  "All restrictions of the preset should be added to an unprotected default branch",
+			protect: boolPtr(true),
+			branch:  main,
+			existing: []bitbucket.Restriction{
+				{ID: 1, Type: bitbucket.RestrictionReadOnly, MatcherType: bitbucket.MatcherPattern, MatcherID: "release/*"},
+			},
+			want: want{added: []bitbucket.Restriction{
+				restriction(bitbucket.RestrictionFastForwardOnly),
+				restriction(bitbucket.RestrictionNoDeletes),
+				restriction(bitbucket.RestrictionPullRequestOnly),
+			}},
+		},
+		"Partial": {
+			reason:   "Only the restrictions of the preset the default branch lacks should be added",
+			protect:  boolPtr(true),
+			branch:   main,
+			existing: []bitbucket.Restriction{restriction(bitbucket.RestrictionNoDeletes)},
+			want: want{added: []bitbucket.Restriction{
+				restriction(bitbucket.RestrictionFastForwardOnly),
+				restriction(bitbucket.RestrictionPullRequestOnly),
+			}},
+		},
+		"Protected": {
+			reason:  "A default branch with all restrictions of the preset should be up to date",
+			protect: boolPtr(true),
+			branch:  main,
+			existing: []bitbucket.Restriction{
+				restriction(bitbucket.RestrictionPullRequestOnly),
+				restriction(bitbucket.RestrictionFastForwardOnly),
+				restriction(bitbucket.RestrictionNoDeletes),
+			},
+			want: want{upToDate: true},
+		},
+		"ExemptGroupsAdded": {
+			reason:   "Added restrictions should exempt the groups of the spec",
+			protect:  boolPtr(true),
+			exempt:   []string{"release-managers"},
+			branch:   main,
+			existing: []bitbucket.Restriction{exempted(1, bitbucket.RestrictionNoDeletes, "release-managers")},
+			want: want{added: []bitbucket.Restriction{
+				exempted(0, bitbucket.RestrictionFastForwardOnly, "release-managers"),
+				exempted(0, bitbucket.RestrictionPullRequestOnly, "release-managers"),
+			}},
+		},
+		"ExemptGroupsDrift": {
+			reason:  "Existing restrictions exempting other groups should be replaced by their ID with the groups of the spec",
+			protect: boolPtr(true),
+			exempt:  []string{"release-managers", "admins"},
+			branch:  main,
+			existing: []bitbucket.Restriction{
+				exempted(1, bitbucket.RestrictionFastForwardOnly, "admins", "release-managers"),
+				exempted(2, bitbucket.RestrictionNoDeletes, "admins"),
+				exempted(3, bitbucket.RestrictionPullRequestOnly),
+			},
+			want: want{added: []bitbucket.Restriction{
+				exempted(2, bitbucket.RestrictionNoDeletes, "release-managers", "admins"),
+				exempted(3, bitbucket.RestrictionPullRequestOnly, "release-managers", "admins"),
+			}},
+		},
+		"ExemptGroupsNone": {
+			reason:   "An empty list should remove the exempted groups",
+			protect:  boolPtr(true),
+			exempt:   []string{},
+			branch:   main,
+			existing: []bitbucket.Restriction{exempted(1, bitbucket.RestrictionFastForwardOnly, "admins"), exempted(2, bitbucket.RestrictionNoDeletes), exempted(3, bitbucket.RestrictionPullRequestOnly)},
+			want: want{added: []bitbucket.Restriction{
+				{ID: 1, Type: bitbucket.RestrictionFastForwardOnly, MatcherType: bitbucket.MatcherBranch, MatcherID: main, Groups: []string{}},
+			}},
+		},
+		"ExemptGroupsUpToDate": {
+			reason:  "Exempted groups should be compared ignoring case and order",
+			protect: boolPtr(true),
+			exempt:  []string{"Release-Managers", "admins"},
+			branch:  main,
+			existing: []bitbucket.Restriction{
+				exempted(1, bitbucket.RestrictionFastForwardOnly, "admins", "release-managers"),
+				exempted(2, bitbucket.RestrictionNoDeletes, "release-managers", "ADMINS"),
+				exempted(3, bitbucket.RestrictionPullRequestOnly, "release-managers", "admins"),
+			},
+			want: want{upToDate: true},
+		},
+		"ExemptGroupsUnmanaged": {
+			reason:  "Exempted groups should be left alone when not in the spec",
+			protect: boolPtr(true),
+			branch:  main,
+			existing: []bitbucket.Restriction{
+				exempted(1, bitbucket.RestrictionFastForwardOnly, "admins"),
+				exempted(2, bitbucket.RestrictionNoDeletes, "admins"),
+				exempted(3, bitbucket.RestrictionPullRequestOnly, "admins"),
+			},
+			want: want{upToDate: true},
+		},
+		"Empty": {
+			reason:    "A repository without a default branch has nothing to protect yet",
+			protect:   boolPtr(true),
+			branchErr: bitbucket.ErrNotFound,
+			want:      want{upToDate: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetDefaultBranch: func(_ context.Context, _ *bitbucket.Repository) (string, error) {
+					return tc.branch, tc.branchErr
+				},
+				MockGetRestrictions: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Restriction, error) {
+					return tc.existing, nil
+				},
+				MockAddRestriction: func(_ context.Context, _ *bitbucket.Repository, r *bitbucket.Restriction) error {
+					got.added = append(got.added, *r)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) {
+				r.Spec.ForProvider.ProtectDefaultBranch = tc.protect
+				r.Spec.ForProvider.DefaultBranchProtectionExemptGroups = tc.exempt
+			})
+
+			got.upToDate, got.err = e.defaultBranchProtectionUpToDate(context.Background(), cr, &bitbucket.Repository{Name: "repo", Project: "PRJ"})
+			if got.err == nil && !got.upToDate {
+				got.err = e.updateDefaultBranchProtection(context.Background(), cr, &bitbucket.Repository{Name: "repo", Project: "PRJ"})
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ndefault branch protection: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestLFS(t *testing.T) {
+	type want struct {
+		upToDate bool
+		set      []bool
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		lfs     *bool
+		enabled bool
+		setErr  error
+		want    want
+	}{
+		"NotConfigured": {
+			reason:  "LFS should not be changed when not in the spec",
+			enabled: true,
+			want:    want{upToDate: true},
+		},
+		"UpToDate": {
+			reason:  "Enabled LFS should be up to date when enabled in the spec",
+			lfs:     boolPtr(true),
+			enabled: true,
+			want:    want{upToDate: true},
+		},
+		"Enable": {
+			reason: "Disabled LFS should be enabled when enabled in the spec",
+			lfs:    boolPtr(true),
+			want:   want{set: []bool{true}},
+		},
+		"Disable": {
+			reason:  "Enabled LFS should be disabled when disabled in the spec",
+			lfs:     boolPtr(false),
+			enabled: true,
+			want:    want{set: []bool{false}},
+		},
+		"Unsupported": {
+			reason: "Enabling LFS on a server without LFS should return an error",
+			lfs:    boolPtr(true),
+			setErr: bitbucket.ErrUnsupported,
+			want:   want{set: []bool{true}, err: bitbucket.ErrUnsupported},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetLFSEnabled: func(_ context.Context, _ *bitbucket.Repository) (bool, error) {
+					return tc.enabled, nil
+				},
+				MockSetLFSEnabled: func(_ context.Context, _ *bitbucket.Repository, enabled bool) error {
+					got.set = append(got.set, enabled)
+					return tc.setErr
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.LFSEnabled = tc.lfs })
+
+			got.upToDate, got.err = e.lfsUpToDate(context.Background(), cr, &bitbucket.Repository{})
+			if got.err == nil && !got.upToDate {
+				got.err = e.updateLFS(context.Background(), cr, &bitbucket.Repository{})
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nlfs: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestForkSync(t *testing.T) {
+	type want struct {
+		upToDate bool
+		set      []bool
+		err      error
+	}
+	fork := &bitbucket.Repository{Name: "repo", Project: "PRJ", Origin: "UPSTREAM/repo"}
+
+	cases := map[string]struct {
+		reason     string
+		forkSync   *v1alpha1.ForkSync
+		repository *bitbucket.Repository
+		enabled    bool
+		want       want
+	}{
+		"NotConfigured": {
+			reason:     "Fork sync should not be changed when not in the spec",
+			repository: fork,
+			want:       want{upToDate: true},
+		},
+		"UpToDate": {
+			reason:     "Enabled fork sync should be up to date when enabled in the spec",
+			forkSync:   &v1alpha1.ForkSync{Enabled: true},
+			repository: fork,
+			enabled:    true,
+			want:       want{upToDate: true},
+		},
+		"Enable": {
+			reason:     "Fork sync should be enabled on a fork when enabled in the spec",
+			forkSync:   &v1alpha1.ForkSync{Enabled: true},
+			repository: fork,
+			want:       want{set: []bool{true}},
+		},
+		"Disable": {
+			reason:     "Fork sync should be disabled on a fork when disabled in the spec",
+			forkSync:   &v1alpha1.ForkSync{Enabled: false},
+			repository: fork,
+			enabled:    true,
+			want:       want{set: []bool{false}},
+		},
+		"NotFork": {
+			reason:     "Configuring fork sync on a repository that is not a fork should be an error",
+			forkSync:   &v1alpha1.ForkSync{Enabled: true},
+			repository: &bitbucket.Repository{Name: "repo", Project: "PRJ"},
+			want:       want{err: errors.Errorf(errNotFork, "repo")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetForkSyncEnabled: func(_ context.Context, _ *bitbucket.Repository) (bool, error) {
+					return tc.enabled, nil
+				},
+				MockSetForkSyncEnabled: func(_ context.Context, _ *bitbucket.Repository, enabled bool) error {
+					got.set = append(got.set, enabled)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.ForkSync = tc.forkSync })
+
+			got.upToDate, got.err = e.forkSyncUpToDate(context.Background(), cr, tc.repository)
+			if got.err == nil && !got.upToDate {
+				got.err = e.updateForkSync(context.Background(), cr, tc.repository)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nfork sync: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestHooks(t *testing.T) {
+	type want struct {
+		upToDate bool
+		enabled  []string
+		disabled []string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		hooks    []string
+		existing []string
+		want     want
+	}{
+		"NotConfigured": {
+			reason:   "Hooks should not be changed when not in the spec",
+			existing: []string{"force-push"},
+			want:     want{upToDate: true},
+		},
+		"UpToDate": {
+			reason:   "The enabled hooks in the spec should be up to date",
+			hooks:    []string{"force-push", "secret-scan"},
+			existing: []string{"secret-scan", "force-push"},
+			want:     want{upToDate: true},
+		},
+		"Diff": {
+			reason:   "Missing hooks should be enabled and hooks not in the spec disabled",
+			hooks:    []string{"force-push", "secret-scan"},
+			existing: []string{"secret-scan", "jira"},
+			want:     want{enabled: []string{"force-push"}, disabled: []string{"jira"}},
+		},
+		"DisableAll": {
+			reason:   "An empty list should disable all hooks",
+			hooks:    []string{},
+			existing: []string{"force-push"},
+			want:     want{disabled: []string{"force-push"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetEnabledHooks: func(_ context.Context, _ *bitbucket.Repository) ([]string, error) {
+					return tc.existing, nil
+				},
+				MockEnableHook: func(_ context.Context, _ *bitbucket.Repository, key string) error {
+					got.enabled = append(got.enabled, key)
+					return nil
+				},
+				MockDisableHook: func(_ context.Context, _ *bitbucket.Repository, key string) error {
+					got.disabled = append(got.disabled, key)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.EnabledHooks = tc.hooks })
+
+			upToDate, err := e.hooksUpToDate(context.Background(), cr, &bitbucket.Repository{})
+			if err == nil && !upToDate {
+				err = e.updateHooks(context.Background(), cr, &bitbucket.Repository{})
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nhooks: %v", tc.reason, err)
+			}
+			got.upToDate = upToDate
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nhooks: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestBranches(t *testing.T) {
+	type want struct {
+		upToDate bool
+		created  []string
+		deleted  []string
+	}
+
+	develop := v1alpha1.BranchRef{Name: "develop", StartPoint: "main"}
+	existing := []bitbucket.Branch{
+		{ID: "refs/heads/main", Name: "main", IsDefault: true},
+		{ID: "refs/heads/feature", Name: "feature"},
+	}
+
+	cases := map[string]struct {
+		reason   string
+		branches []v1alpha1.BranchRef
+		prune    *bool
+		existing []bitbucket.Branch
+		want     want
+	}{
+		"NotConfigured": {
+			reason:   "Branches should not be changed when not in the spec",
+			existing: existing,
+			want:     want{upToDate: true},
+		},
+		"Create": {
+			reason:   "A missing branch should be created from its start point",
+			branches: []v1alpha1.BranchRef{develop},
+			existing: existing,
+			want:     want{created: []string{"develop from main"}},
+		},
+		"Idempotent": {
+			reason:   "An existing branch should not be created again",
+			branches: []v1alpha1.BranchRef{develop},
+			existing: append([]bitbucket.Branch{{ID: "refs/heads/develop", Name: "develop"}}, existing...),
+			want:     want{upToDate: true},
+		},
+		"Prune": {
+			reason:   "Branches not in the spec should only be deleted when pruning, never the default branch",
+			branches: []v1alpha1.BranchRef{develop},
+			prune:    boolPtr(true),
+			existing: existing,
+			want:     want{created: []string{"develop from main"}, deleted: []string{"refs/heads/feature"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetBranches: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Branch, error) {
+					return tc.existing, nil
+				},
+				MockCreateBranch: func(_ context.Context, _ *bitbucket.Repository, name string, startPoint string) error {
+					got.created = append(got.created, name+" from "+startPoint)
+					return nil
+				},
+				MockDeleteBranch: func(_ context.Context, _ *bitbucket.Repository, ref string) error {
+					got.deleted = append(got.deleted, ref)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) {
+				r.Spec.ForProvider.Branches = tc.branches
+				r.Spec.ForProvider.PruneUnknownBranches = tc.prune
+			})
+
+			upToDate, err := e.branchesUpToDate(context.Background(), cr, &bitbucket.Repository{})
+			if err == nil && !upToDate {
+				err = e.updateBranches(context.Background(), cr, &bitbucket.Repository{})
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nbranches: %v", tc.reason, err)
+			}
+			got.upToDate = upToDate
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nbranches: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	type want struct {
+		upToDate bool
+		set      []string
+		pending  corev1.ConditionStatus
+	}
+
+	existing := []bitbucket.Branch{
+		{ID: "refs/heads/master", Name: "master", IsDefault: true},
+		{ID: "refs/heads/main", Name: "main"},
+	}
+
+	cases := map[string]struct {
+		reason   string
+		branch   *string
+		current  string
+		existing []bitbucket.Branch
+		pending  bool
+		want     want
+	}{
+		"NotConfigured": {
+			reason:   "The default branch should not be changed when not in the spec",
+			current:  "refs/heads/master",
+			existing: existing,
+			want:     want{upToDate: true, pending: corev1.ConditionUnknown},
+		},
+		"BranchExists": {
+			reason:   "The default branch should be switched to an existing branch",
+			branch:   strPtr("main"),
+			current:  "refs/heads/master",
+			existing: existing,
+			want:     want{set: []string{"refs/heads/main"}, pending: corev1.ConditionUnknown},
+		},
+		"BranchMissing": {
+			reason:   "A missing branch should set the pending condition rather than fail",
+			branch:   strPtr("develop"),
+			current:  "refs/heads/master",
+			existing: existing,
+			want:     want{pending: corev1.ConditionTrue},
+		},
+		"EmptyRepository": {
+			reason: "A repository without branches should wait for the branch to be pushed",
+			branch: strPtr("main"),
+			want:   want{pending: corev1.ConditionTrue},
+		},
+		"BranchCreated": {
+			reason:   "The pending condition should be cleared once the default branch is switched",
+			branch:   strPtr("main"),
+			current:  "refs/heads/master",
+			existing: existing,
+			pending:  true,
+			want:     want{set: []string{"refs/heads/main"}, pending: corev1.ConditionFalse},
+		},
+		"UpToDate": {
+			reason:   "The default branch should not be switched when it is the one in the spec",
+			branch:   strPtr("refs/heads/main"),
+			current:  "refs/heads/main",
+			existing: existing,
+			want:     want{upToDate: true, pending: corev1.ConditionUnknown},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetDefaultBranch: func(_ context.Context, _ *bitbucket.Repository) (string, error) {
+					if tc.current == "" {
+						return "", bitbucket.ErrNotFound
+					}
+					return tc.current, nil
+				},
+				MockGetBranches: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.Branch, error) {
+					return tc.existing, nil
+				},
+				MockSetDefaultBranch: func(_ context.Context, _ *bitbucket.Repository, ref string) error {
+					got.set = append(got.set, ref)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) {
+				r.Spec.ForProvider.DefaultBranch = tc.branch
+				if tc.pending {
+					r.SetConditions(v1alpha1.DefaultBranchPending("main"))
+				}
+			})
+
+			upToDate, err := e.defaultBranchUpToDate(context.Background(), cr, &bitbucket.Repository{})
+			if err == nil && !upToDate {
+				err = e.updateDefaultBranch(context.Background(), cr, &bitbucket.Repository{})
+			}
+			if err != nil {
+				t.Fatalf("\n%s\ndefault branch: %v", tc.reason, err)
+			}
+			got.upToDate = upToDate
+			got.pending = cr.GetCondition(v1alpha1.TypeDefaultBranchPending).Status
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ndefault branch: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRequiredBuilds(t *testing.T) {
+	type want struct {
+		upToDate bool
+		added    []bitbucket.RequiredBuild
+		updated  []bitbucket.RequiredBuild
+		removed  []int
+	}
+
+	onMain := bitbucket.RequiredBuild{ID: 1, BuildKeys: []string{"ci", "lint"}, MatcherType: bitbucket.MatcherBranch, MatcherID: "refs/heads/main"}
+	onRelease := bitbucket.RequiredBuild{ID: 2, BuildKeys: []string{"ci"}, MatcherType: bitbucket.MatcherPattern, MatcherID: "release/*"}
+
+	cases := map[string]struct {
+		reason   string
+		builds   []v1alpha1.RequiredBuild
+		existing []bitbucket.RequiredBuild
+		want     want
+	}{
+		"NotConfigured": {
+			reason:   "Required builds should not be changed when not in the spec",
+			existing: []bitbucket.RequiredBuild{onMain},
+			want:     want{upToDate: true},
+		},
+		"UpToDate": {
+			reason:   "Build keys should be compared regardless of their order",
+			builds:   []v1alpha1.RequiredBuild{{Branch: "refs/heads/main", BuildKeys: []string{"lint", "ci"}}},
+			existing: []bitbucket.RequiredBuild{onMain},
+			want:     want{upToDate: true},
+		},
+		"Add": {
+			reason: "Required builds of a branch without any should be added",
+			builds: []v1alpha1.RequiredBuild{{Branch: "refs/heads/main", BuildKeys: []string{"ci"}}},
+			want: want{added: []bitbucket.RequiredBuild{
+				{BuildKeys: []string{"ci"}, MatcherType: bitbucket.MatcherBranch, MatcherID: "refs/heads/main"},
+			}},
+		},
+		"UpdateKeys": {
+			reason:   "Required builds with other build keys than the spec should be updated in place",
+			builds:   []v1alpha1.RequiredBuild{{Branch: "refs/heads/main", BuildKeys: []string{"ci", "security-scan"}}},
+			existing: []bitbucket.RequiredBuild{onMain},
+			want: want{updated: []bitbucket.RequiredBuild{
+				{ID: 1, BuildKeys: []string{"ci", "security-scan"}, MatcherType: bitbucket.MatcherBranch, MatcherID: "refs/heads/main"},
+			}},
+		},
+		"Remove": {
+			reason:   "Required builds of branches not in the spec should be removed",
+			builds:   []v1alpha1.RequiredBuild{{Branch: "refs/heads/main", BuildKeys: []string{"ci", "lint"}}},
+			existing: []bitbucket.RequiredBuild{onMain, onRelease},
+			want:     want{removed: []int{2}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			e := external{service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetRequiredBuilds: func(_ context.Context, _ *bitbucket.Repository) ([]bitbucket.RequiredBuild, error) {
+					return tc.existing, nil
+				},
+				MockAddRequiredBuild: func(_ context.Context, _ *bitbucket.Repository, b *bitbucket.RequiredBuild) error {
+					got.added = append(got.added, *b)
+					return nil
+				},
+				MockUpdateRequiredBuild: func(_ context.Context, _ *bitbucket.Repository, b *bitbucket.RequiredBuild) error {
+					got.updated = append(got.updated, *b)
+					return nil
+				},
+				MockDeleteRequiredBuild: func(_ context.Context, _ *bitbucket.Repository, id int) error {
+					got.removed = append(got.removed, id)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.RequiredBuilds = tc.builds })
+
+			upToDate, err := e.requiredBuildsUpToDate(context.Background(), cr, &bitbucket.Repository{})
+			if err == nil && !upToDate {
+				err = e.updateRequiredBuilds(context.Background(), cr, &bitbucket.Repository{})
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nrequired builds: %v", tc.reason, err)
+			}
+			got.upToDate = upToDate
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nrequired builds: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
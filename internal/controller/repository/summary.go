@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"log"
+	"strings"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+// An updateSummary describes the changes Update applied to a repository
+type updateSummary struct {
+	// MovedFrom is the project the repository was moved from, if it was moved
+	MovedFrom string
+	// Fields are the fields of the repository itself that were updated
+	Fields []string
+	// GroupsGranted are the groups granted a permission they did not have,
+	// as name=permission
+	GroupsGranted []string
+	// GroupsRevoked are the groups whose permission was revoked
+	GroupsRevoked []string
+	// Settings are the settings that were updated
+	Settings []string
+}
+
+// empty reports whether nothing was changed
+func (s updateSummary) empty() bool {
+	return s.MovedFrom == "" && len(s.Fields) == 0 && len(s.GroupsGranted) == 0 && len(s.GroupsRevoked) == 0 && len(s.Settings) == 0
+}
+
+// String lists the changes, e.g. "fields: description; groups revoked: devs"
+func (s updateSummary) String() string {
+	parts := []string{}
+	if s.MovedFrom != "" {
+		parts = append(parts, "moved from: "+s.MovedFrom)
+	}
+	for _, p := range []struct {
+		name   string
+		values []string
+	}{
+		{name: "fields", values: s.Fields},
+		{name: "groups granted", values: s.GroupsGranted},
+		{name: "groups revoked", values: s.GroupsRevoked},
+		{name: "settings", values: s.Settings},
+	} {
+		if len(p.values) > 0 {
+			parts = append(parts, p.name+": "+strings.Join(p.values, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// changedFields returns the fields of the repository itself that differ from
+// the desired repository
+func changedFields(cr *v1alpha1.Repository, repository *bitbucket.Repository, desired *bitbucket.Repository) []string {
+	fields := []string{}
+	if !descriptionEqual(repository.Description, desired.Description) {
+		fields = append(fields, "description")
+	}
+	if repository.Public != desired.Public {
+		fields = append(fields, "public")
+	}
+	if !archivedUpToDate(cr, repository) {
+		fields = append(fields, "archived")
+	}
+	return fields
+}
+
+// grantedGroups returns the groups of the spec that do not have their
+// permission among the existing groups yet, as name=permission
+func grantedGroups(cr *v1alpha1.Repository, existing []bitbucket.Group) []string {
+	has := map[string]bool{}
+	for _, g := range existing {
+		has[groupKey(g.Name, g.Permission)] = true
+	}
+	var granted []string
+	for _, g := range cr.Spec.ForProvider.Groups {
+		key := groupKey(g.Name, g.Permission)
+		if !has[key] {
+			has[key] = true
+			granted = append(granted, g.Name+"="+g.Permission)
+		}
+	}
+	return granted
+}
+
+// logUpdate logs the changes Update applied, also when it failed part way
+func logUpdate(cr *v1alpha1.Repository, summary updateSummary, err error) {
+	if err != nil {
+		if !summary.empty() {
+			log.Printf("Partially updated repository %s: %s\n", cr.Name, summary)
+		}
+		return
+	}
+	log.Printf("Updated repository %s: %s\n", cr.Name, summary)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+func TestUpdateSummary(t *testing.T) {
+	type want struct {
+		summary updateSummary
+		text    string
+		lfs     []bool
+	}
+	admins := v1alpha1.AdGroup{Name: "admins", Permission: "REPO_ADMIN"}
+
+	cases := map[string]struct {
+		reason   string
+		existing []bitbucket.Group
+		lfs      bool
+		mods     []repositoryModifier
+		want     want
+	}{
+		"NoChanges": {
+			reason:   "A repository matching the spec should be reported unchanged",
+			existing: []bitbucket.Group{{Name: "admins", Permission: "REPO_ADMIN"}},
+			mods:     []repositoryModifier{withGroups(admins)},
+			want:     want{text: "no changes"},
+		},
+		"Fields": {
+			reason: "Updated fields of the repository should be reported",
+			mods:   []repositoryModifier{func(r *v1alpha1.Repository) { r.Spec.ForProvider.Description = "new"; r.Spec.ForProvider.Public = true }},
+			want: want{
+				summary: updateSummary{Fields: []string{"description", "public"}},
+				text:    "fields: description, public",
+			},
+		},
+		"Groups": {
+			reason:   "Granted and revoked groups should be reported, groups already granted should not",
+			existing: []bitbucket.Group{{Name: "ADMINS", Permission: "REPO_ADMIN"}, {Name: "devs", Permission: "REPO_READ"}, {Name: "old", Permission: "REPO_READ"}},
+			mods:     []repositoryModifier{withGroups(admins, v1alpha1.AdGroup{Name: "devs", Permission: "REPO_WRITE"})},
+			want: want{
+				summary: updateSummary{GroupsGranted: []string{"devs=REPO_WRITE"}, GroupsRevoked: []string{"old"}},
+				text:    "groups granted: devs=REPO_WRITE; groups revoked: old",
+			},
+		},
+		"KeptGroups": {
+			reason:   "Groups not revoked should not be reported",
+			existing: []bitbucket.Group{{Name: "old", Permission: "REPO_READ"}},
+			mods:     []repositoryModifier{withGroups(admins), withPruneUnknownGroups(false)},
+			want: want{
+				summary: updateSummary{GroupsGranted: []string{"admins=REPO_ADMIN"}},
+				text:    "groups granted: admins=REPO_ADMIN",
+			},
+		},
+		"DriftedSetting": {
+			reason: "A drifted setting should be updated and reported",
+			mods:   []repositoryModifier{func(r *v1alpha1.Repository) { r.Spec.ForProvider.LFSEnabled = boolPtr(true) }},
+			want: want{
+				summary: updateSummary{Settings: []string{"lfs"}},
+				text:    "settings: lfs",
+				lfs:     []bool{true},
+			},
+		},
+		"UpToDateSetting": {
+			reason: "A setting that is up to date should neither be updated nor reported",
+			lfs:    true,
+			mods:   []repositoryModifier{func(r *v1alpha1.Repository) { r.Spec.ForProvider.LFSEnabled = boolPtr(true) }},
+			want:   want{text: "no changes"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			svc := newGroupService(tc.existing, &groupCalls{})
+			svc.MockGetLFSEnabled = func(_ context.Context, _ *bitbucket.Repository) (bool, error) {
+				return tc.lfs, nil
+			}
+			svc.MockSetLFSEnabled = func(_ context.Context, _ *bitbucket.Repository, enabled bool) error {
+				got.lfs = append(got.lfs, enabled)
+				return nil
+			}
+			e := external{groupConcurrency: 1, service: &bitbucket.BitBucketService{Projects: privateProject(), Repositories: svc}}
+			cr := repository(tc.mods...)
+
+			_, summary, err := e.update(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.update(...): %v", tc.reason, err)
+			}
+			got.summary, got.text = summary, summary.String()
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.update(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
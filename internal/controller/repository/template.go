@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+const (
+	errTemplateCopy = "cannot copy %s from template repository %s/%s"
+
+	// reasonTemplateCopyFailed is the reason of the event recorded when a
+	// setting could not be copied from the template repository
+	reasonTemplateCopyFailed event.Reason = "TemplateCopyFailed"
+)
+
+// A templateCopy copies one category of settings from the template repository
+type templateCopy struct {
+	name string
+	copy func(ctx context.Context, template, repository *bitbucket.Repository) error
+}
+
+func (c *external) templateCopies() []templateCopy {
+	return []templateCopy{
+		{name: "branch restrictions", copy: c.copyRestrictions},
+		{name: "hooks", copy: c.copyHooks},
+		{name: "pull request settings", copy: c.copyPullRequestSettings},
+	}
+}
+
+// copyTemplate copies the settings of the template repository of the spec to
+// the created repository. The copy is best effort, a category that cannot be
+// copied is reported as a warning event and does not fail the creation.
+// Default reviewers are not copied as they are not supported.
+func (c *external) copyTemplate(ctx context.Context, cr *v1alpha1.Repository, repository *bitbucket.Repository) {
+	ref := cr.Spec.ForProvider.TemplateFrom
+	if ref == nil {
+		return
+	}
+	template := &bitbucket.Repository{Project: ref.Project, Name: ref.Slug}
+	for _, t := range c.templateCopies() {
+		if err := t.copy(ctx, template, repository); err != nil {
+			err = errors.Wrapf(err, errTemplateCopy, t.name, ref.Project, ref.Slug)
+			log.Println(err)
+			if c.recorder != nil {
+				c.recorder.Event(cr, event.Warning(reasonTemplateCopyFailed, err))
+			}
+		}
+	}
+}
+
+// copyRestrictions adds the branch restrictions of the template the
+// repository does not have yet
+func (c *external) copyRestrictions(ctx context.Context, template, repository *bitbucket.Repository) error {
+	restrictions, err := c.service.Repositories.GetRestrictions(ctx, template)
+	if err != nil {
+		return err
+	}
+	existing, err := c.service.Repositories.GetRestrictions(ctx, repository)
+	if err != nil {
+		return err
+	}
+	for _, r := range restrictions {
+		if hasRestriction(existing, r) {
+			continue
+		}
+		r := bitbucket.Restriction{Type: r.Type, MatcherType: r.MatcherType, MatcherID: r.MatcherID, Groups: r.Groups}
+		log.Printf("Copying restriction %+v to repository %+v\n", r, repository)
+		if err := c.service.Repositories.AddRestriction(ctx, repository, &r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyHooks enables the hooks enabled in the template
+func (c *external) copyHooks(ctx context.Context, template, repository *bitbucket.Repository) error {
+	hooks, err := c.service.Repositories.GetEnabledHooks(ctx, template)
+	if err != nil {
+		return err
+	}
+	for _, key := range hooks {
+		log.Printf("Copying hook %s to repository %+v\n", key, repository)
+		if err := c.service.Repositories.EnableHook(ctx, repository, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPullRequestSettings copies the pull request template and the default
+// merge strategy of the template
+func (c *external) copyPullRequestSettings(ctx context.Context, template, repository *bitbucket.Repository) error {
+	description, err := c.service.Repositories.GetPullRequestTemplate(ctx, template)
+	if err != nil {
+		return err
+	}
+	if description != "" {
+		if err := c.service.Repositories.SetPullRequestTemplate(ctx, repository, description); err != nil {
+			return err
+		}
+	}
+	strategy, err := c.service.Repositories.GetDefaultMergeStrategy(ctx, template)
+	if err != nil {
+		return err
+	}
+	if strategy != "" {
+		return c.service.Repositories.SetDefaultMergeStrategy(ctx, repository, strategy)
+	}
+	return nil
+}
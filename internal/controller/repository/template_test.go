@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket/fake"
+)
+
+func TestCopyTemplate(t *testing.T) {
+	type want struct {
+		copied []string
+		events []string
+	}
+	errBoom := errors.New("boom")
+	protected := bitbucket.Restriction{Type: bitbucket.RestrictionNoDeletes, MatcherType: bitbucket.MatcherBranch, MatcherID: "refs/heads/main"}
+	pullRequestOnly := bitbucket.Restriction{Type: bitbucket.RestrictionPullRequestOnly, MatcherType: bitbucket.MatcherBranch, MatcherID: "refs/heads/main"}
+	templateRef := &v1alpha1.RepositoryRef{Project: "TPL", Slug: "template"}
+
+	cases := map[string]struct {
+		reason   string
+		template *v1alpha1.RepositoryRef
+		failing  string
+		want     want
+	}{
+		"NoTemplate": {
+			reason: "Nothing should be copied without a template",
+			want:   want{},
+		},
+		"CopyAll": {
+			reason:   "Restrictions the repository lacks, hooks and pull request settings should be copied",
+			template: templateRef,
+			want: want{copied: []string{
+				"restriction " + pullRequestOnly.Type,
+				"hook force-push-hook",
+				"pull request template",
+				"merge strategy squash",
+			}},
+		},
+		"RestrictionsFail": {
+			reason:   "A category that cannot be copied should be reported without stopping the others",
+			template: templateRef,
+			failing:  "restrictions",
+			want: want{
+				copied: []string{"hook force-push-hook", "pull request template", "merge strategy squash"},
+				events: []string{"cannot copy branch restrictions from template repository TPL/template: boom"},
+			},
+		},
+		"HooksFail": {
+			reason:   "A failure to read the template hooks should be reported as a hooks error",
+			template: templateRef,
+			failing:  "hooks",
+			want: want{
+				copied: []string{"restriction " + pullRequestOnly.Type, "pull request template", "merge strategy squash"},
+				events: []string{"cannot copy hooks from template repository TPL/template: boom"},
+			},
+		},
+		"PullRequestSettingsFail": {
+			reason:   "A failure to read the template pull request settings should be reported as such",
+			template: templateRef,
+			failing:  "pull request settings",
+			want: want{
+				copied: []string{"restriction " + pullRequestOnly.Type, "hook force-push-hook"},
+				events: []string{"cannot copy pull request settings from template repository TPL/template: boom"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			fail := func(category string) error {
+				if tc.failing == category {
+					return errBoom
+				}
+				return nil
+			}
+			isTemplate := func(r *bitbucket.Repository) bool {
+				return r.Project == "TPL" && r.Name == "template"
+			}
+			recorder := &eventRecorder{}
+			e := external{recorder: recorder, service: &bitbucket.BitBucketService{Repositories: &fake.MockRepositoryService{
+				MockGetRestrictions: func(_ context.Context, r *bitbucket.Repository) ([]bitbucket.Restriction, error) {
+					if isTemplate(r) {
+						return []bitbucket.Restriction{protected, pullRequestOnly}, fail("restrictions")
+					}
+					return []bitbucket.Restriction{protected}, nil
+				},
+				MockAddRestriction: func(_ context.Context, _ *bitbucket.Repository, r *bitbucket.Restriction) error {
+					got.copied = append(got.copied, "restriction "+r.Type)
+					return nil
+				},
+				MockGetEnabledHooks: func(_ context.Context, _ *bitbucket.Repository) ([]string, error) {
+					return []string{"force-push-hook"}, fail("hooks")
+				},
+				MockEnableHook: func(_ context.Context, _ *bitbucket.Repository, key string) error {
+					got.copied = append(got.copied, "hook "+key)
+					return nil
+				},
+				MockGetPullRequestTemplate: func(_ context.Context, _ *bitbucket.Repository) (string, error) {
+					return "## Description", fail("pull request settings")
+				},
+				MockSetPullRequestTemplate: func(_ context.Context, _ *bitbucket.Repository, _ string) error {
+					got.copied = append(got.copied, "pull request template")
+					return nil
+				},
+				MockGetDefaultMergeStrategy: func(_ context.Context, _ *bitbucket.Repository) (string, error) {
+					return "squash", nil
+				},
+				MockSetDefaultMergeStrategy: func(_ context.Context, _ *bitbucket.Repository, strategy string) error {
+					got.copied = append(got.copied, "merge strategy "+strategy)
+					return nil
+				},
+			}}}
+			cr := repository(func(r *v1alpha1.Repository) { r.Spec.ForProvider.TemplateFrom = tc.template })
+
+			e.copyTemplate(context.Background(), cr, &bitbucket.Repository{Project: "PRJ", Name: "repo"})
+			for _, ev := range recorder.events {
+				if ev.Reason != reasonTemplateCopyFailed || ev.Type != event.TypeWarning {
+					t.Errorf("\n%s\ncopyTemplate(...): unexpected event %+v", tc.reason, ev)
+				}
+				got.events = append(got.events, ev.Message)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ncopyTemplate(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
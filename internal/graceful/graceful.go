@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package graceful lets in-flight operations against Bitbucket complete when
+// the provider is shutting down.
+package graceful
+
+import (
+	"context"
+	"time"
+)
+
+type periodKey struct{}
+
+// WithPeriod returns a copy of ctx carrying the grace period given to
+// operations started from it to complete once ctx is cancelled.
+func WithPeriod(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, periodKey{}, d)
+}
+
+// Period returns the grace period carried by ctx, or zero if there is none.
+func Period(ctx context.Context) time.Duration {
+	d, _ := ctx.Value(periodKey{}).(time.Duration)
+	return d
+}
+
+// Context returns a context which outlives the cancellation of ctx by the
+// grace period carried by ctx. It keeps the values and deadline of ctx, so
+// reconcile timeouts still apply. Without a grace period the returned context
+// is cancelled together with ctx.
+func Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	d := Period(ctx)
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	base, cancelDeadline := context.Context(detached{parent: ctx}), context.CancelFunc(func() {})
+	if deadline, ok := ctx.Deadline(); ok {
+		base, cancelDeadline = context.WithDeadline(base, deadline)
+	}
+	gctx, cancel := context.WithCancel(base)
+
+	go func() {
+		select {
+		case <-gctx.Done():
+			return
+		case <-ctx.Done():
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-gctx.Done():
+		case <-t.C:
+			cancel()
+		}
+	}()
+
+	return gctx, func() {
+		cancel()
+		cancelDeadline()
+	}
+}
+
+// detached is a context carrying the values of its parent but never its
+// cancellation.
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+func (d detached) Value(key any) any         { return d.parent.Value(key) }
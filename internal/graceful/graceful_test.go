@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graceful
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type valueKey struct{}
+
+func TestContext(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		period   time.Duration
+		deadline time.Duration
+		wait     time.Duration
+		want     bool
+	}{
+		"NoPeriod": {
+			reason: "Without a grace period the context should be cancelled with its parent",
+			wait:   10 * time.Millisecond,
+			want:   true,
+		},
+		"WithinPeriod": {
+			reason: "The context should outlive its parent within the grace period",
+			period: time.Minute,
+			wait:   10 * time.Millisecond,
+			want:   false,
+		},
+		"PeriodElapsed": {
+			reason: "The context should be cancelled once the grace period has elapsed",
+			period: 10 * time.Millisecond,
+			wait:   100 * time.Millisecond,
+			want:   true,
+		},
+		"Deadline": {
+			reason:   "The deadline of the parent should still apply",
+			period:   time.Minute,
+			deadline: 10 * time.Millisecond,
+			wait:     100 * time.Millisecond,
+			want:     true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			parent, cancelParent := context.WithCancel(WithPeriod(context.WithValue(context.Background(), valueKey{}, "value"), tc.period))
+			defer cancelParent()
+			if tc.deadline > 0 {
+				parent, cancelParent = context.WithTimeout(parent, tc.deadline)
+				defer cancelParent()
+			}
+
+			ctx, cancel := Context(parent)
+			defer cancel()
+			if tc.deadline == 0 {
+				cancelParent()
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(tc.wait):
+			}
+			if diff := cmp.Diff(tc.want, ctx.Err() != nil); diff != "" {
+				t.Errorf("\n%s\nContext(...): -want cancelled, +got cancelled:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff("value", ctx.Value(valueKey{})); diff != "" {
+				t.Errorf("\n%s\nContext(...): -want value, +got value:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInFlightRequest(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		period time.Duration
+		want   bool
+	}{
+		"Drained": {
+			reason: "An in-flight request should complete when shutdown starts within the grace period",
+			period: time.Minute,
+			want:   true,
+		},
+		"Aborted": {
+			reason: "An in-flight request should be aborted on shutdown without a grace period",
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			started := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				close(started)
+				time.Sleep(50 * time.Millisecond)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer srv.Close()
+
+			shutdown, stop := context.WithCancel(WithPeriod(context.Background(), tc.period))
+			defer stop()
+			ctx, cancel := Context(shutdown)
+			defer cancel()
+
+			go func() {
+				<-started
+				stop()
+			}()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := srv.Client().Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if diff := cmp.Diff(tc.want, err == nil); diff != "" {
+				t.Errorf("\n%s\nDo(...): -want completed, +got completed:\n%s\n%v", tc.reason, diff, err)
+			}
+		})
+	}
+}
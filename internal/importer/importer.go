@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package importer generates managed resource manifests for existing
+// Bitbucket resources, so they can be adopted by crossplane.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/MrVinkel/provider-bitbucketserver/apis/repository/v1alpha1"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+)
+
+// WriteRepositories writes a Repository manifest for every repository in the
+// project to w. The manifests carry the external name and the current state
+// of the repositories, including their groups, so adopting them changes
+// nothing in bitbucket. They use the Orphan deletion policy so deleting a
+// manifest by mistake does not delete the repository. A project without
+// repositories results in a comment saying so rather than an empty document.
+func WriteRepositories(ctx context.Context, svc bitbucket.RepositoryService, project string, providerConfig string, w io.Writer) error {
+	repositories, err := svc.List(ctx, project)
+	if err != nil {
+		return err
+	}
+	if len(repositories) == 0 {
+		_, err := fmt.Fprintf(w, "# project %s has no repositories\n", project)
+		return err
+	}
+
+	for i := range repositories {
+		cr, err := repository(ctx, svc, &repositories[i], providerConfig)
+		if err != nil {
+			return err
+		}
+		out, err := manifest(cr)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repository returns the Repository managed resource of an existing repository
+func repository(ctx context.Context, svc bitbucket.RepositoryService, r *bitbucket.Repository, providerConfig string) (*v1alpha1.Repository, error) {
+	groups, err := svc.GetGroups(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := &v1alpha1.Repository{}
+	cr.SetGroupVersionKind(v1alpha1.RepositoryGroupVersionKind)
+	cr.SetName(resourceName(r.Project, r.Slug))
+	meta.SetExternalName(cr, r.Name)
+	cr.Spec.DeletionPolicy = xpv1.DeletionOrphan
+	cr.Spec.ProviderConfigReference = &xpv1.Reference{Name: providerConfig}
+	cr.Spec.ForProvider = v1alpha1.RepositoryParameters{
+		Name:        r.Name,
+		Project:     r.Project,
+		Public:      r.Public,
+		Description: r.Description,
+	}
+	for _, g := range groups {
+		// grants inherited from the project are not managed on the repository
+		if g.Inherited {
+			continue
+		}
+		cr.Spec.ForProvider.Groups = append(cr.Spec.ForProvider.Groups, v1alpha1.AdGroup{Name: g.Name, Permission: g.Permission})
+	}
+	return cr, nil
+}
+
+// resourceName returns a kubernetes object name for a repository
+func resourceName(project string, slug string) string {
+	return strings.ReplaceAll(strings.ToLower(project+"-"+slug), "_", "-")
+}
+
+// manifest returns the YAML of the managed resource without the fields only
+// set by the api server or the provider
+func manifest(cr *v1alpha1.Repository) ([]byte, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cr)
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj, "spec", "initProvider")
+	unstructured.RemoveNestedField(obj, "status")
+	return yaml.Marshal(obj)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket"
+	"github.com/MrVinkel/provider-bitbucketserver/internal/bitbucket/fake"
+)
+
+func TestWriteRepositories(t *testing.T) {
+	svc := &fake.MockRepositoryService{
+		MockList: func(_ context.Context, project string) ([]bitbucket.Repository, error) {
+			return []bitbucket.Repository{
+				{Name: "api", Slug: "api", Project: project, Description: "the api"},
+				{Name: "web_ui", Slug: "web_ui", Project: project, Public: true},
+			}, nil
+		},
+		MockGetGroups: func(_ context.Context, r *bitbucket.Repository) ([]bitbucket.Group, error) {
+			// grants inherited from the project are not imported
+			inherited := bitbucket.Group{Name: "admins", Permission: "REPO_ADMIN", Inherited: true}
+			if r.Name == "api" {
+				return []bitbucket.Group{{Name: "developers", Permission: "REPO_WRITE"}, inherited}, nil
+			}
+			return []bitbucket.Group{inherited}, nil
+		},
+	}
+
+	want := `---
+apiVersion: repository.bitbucketserver.crossplane.io/v1alpha1
+kind: Repository
+metadata:
+  annotations:
+    crossplane.io/external-name: api
+  name: prj-api
+spec:
+  deletionPolicy: Orphan
+  forProvider:
+    description: the api
+    groups:
+    - name: developers
+      permission: REPO_WRITE
+    name: api
+    project: PRJ
+    public: false
+  providerConfigRef:
+    name: default
+---
+apiVersion: repository.bitbucketserver.crossplane.io/v1alpha1
+kind: Repository
+metadata:
+  annotations:
+    crossplane.io/external-name: web_ui
+  name: prj-web-ui
+spec:
+  deletionPolicy: Orphan
+  forProvider:
+    name: web_ui
+    project: PRJ
+    public: true
+  providerConfigRef:
+    name: default
+`
+
+	out := &bytes.Buffer{}
+	if err := WriteRepositories(context.Background(), svc, "PRJ", "default", out); err != nil {
+		t.Fatalf("WriteRepositories(...): %v", err)
+	}
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("WriteRepositories(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestWriteRepositoriesEmptyProject(t *testing.T) {
+	svc := &fake.MockRepositoryService{
+		MockList: func(_ context.Context, _ string) ([]bitbucket.Repository, error) {
+			return []bitbucket.Repository{}, nil
+		},
+	}
+
+	out := &bytes.Buffer{}
+	if err := WriteRepositories(context.Background(), svc, "PRJ", "default", out); err != nil {
+		t.Fatalf("WriteRepositories(...): %v", err)
+	}
+	if diff := cmp.Diff("# project PRJ has no repositories\n", out.String()); diff != "" {
+		t.Errorf("WriteRepositories(...) of an empty project: -want, +got:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jitter spreads out the polling of managed resources so resources
+// created together do not all reconcile against Bitbucket at the same time.
+package jitter
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fractionKey struct{}
+
+// WithFraction returns a copy of ctx carrying the fraction of the poll
+// interval requeues of reconciles started from it are jittered by.
+func WithFraction(ctx context.Context, f float64) context.Context {
+	return context.WithValue(ctx, fractionKey{}, f)
+}
+
+// Fraction returns the jitter fraction carried by ctx, or zero if there is none.
+func Fraction(ctx context.Context) float64 {
+	f, _ := ctx.Value(fractionKey{}).(float64)
+	return f
+}
+
+// Duration returns d moved randomly by up to fraction of d in either
+// direction. The fraction is clamped to [0, 1].
+func Duration(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	return d + time.Duration((rand.Float64()*2-1)*fraction*float64(d)) //nolint:gosec // jitter does not need a secure source
+}
+
+// A Reconciler jitters the requeue interval of the results of the wrapped
+// reconciler by the fraction carried in the reconcile context.
+type Reconciler struct {
+	inner reconcile.Reconciler
+}
+
+// NewReconciler wraps r with jitter.
+func NewReconciler(r reconcile.Reconciler) *Reconciler {
+	return &Reconciler{inner: r}
+}
+
+// Reconcile the supplied request, jittering when it is requeued.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	res, err := r.inner.Reconcile(ctx, req)
+	res.RequeueAfter = Duration(res.RequeueAfter, Fraction(ctx))
+	return res, err
+}
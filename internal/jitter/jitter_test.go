@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jitter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconciler(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		fraction float64
+		after    time.Duration
+		min      time.Duration
+		max      time.Duration
+		varies   bool
+	}{
+		"Jittered": {
+			reason:   "Successive requeues should vary within the jitter band",
+			fraction: 0.2,
+			after:    time.Minute,
+			min:      48 * time.Second,
+			max:      72 * time.Second,
+			varies:   true,
+		},
+		"NoFraction": {
+			reason: "Without a fraction the requeue interval should be unchanged",
+			after:  time.Minute,
+			min:    time.Minute,
+			max:    time.Minute,
+		},
+		"NoRequeue": {
+			reason:   "A result that is not requeued after an interval should be unchanged",
+			fraction: 0.2,
+		},
+		"Clamped": {
+			reason:   "A fraction above one should never requeue in the past",
+			fraction: 5,
+			after:    time.Minute,
+			min:      0,
+			max:      2 * time.Minute,
+			varies:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewReconciler(reconcile.Func(func(_ context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{RequeueAfter: tc.after}, nil
+			}))
+			ctx := WithFraction(context.Background(), tc.fraction)
+
+			seen := map[time.Duration]bool{}
+			for i := 0; i < 100; i++ {
+				res, err := r.Reconcile(ctx, reconcile.Request{})
+				if err != nil {
+					t.Fatalf("r.Reconcile(...): %v", err)
+				}
+				if res.RequeueAfter < tc.min || res.RequeueAfter > tc.max {
+					t.Fatalf("\n%s\nr.Reconcile(...): requeue after %s outside of [%s, %s]", tc.reason, res.RequeueAfter, tc.min, tc.max)
+				}
+				seen[res.RequeueAfter] = true
+			}
+			if got := len(seen) > 1; got != tc.varies {
+				t.Errorf("\n%s\nr.Reconcile(...): want varying requeues %t, got %d distinct", tc.reason, tc.varies, len(seen))
+			}
+		})
+	}
+}